@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/config"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+)
+
+const (
+	backupUsage  = "usage: server backup FILE\n\nwrites a consistent snapshot of the SQLite database to FILE, which must not exist\n"
+	restoreUsage = "usage: server restore FILE\n\nreplaces the SQLite database with the snapshot in FILE; stop the server first\n" +
+		"(or use POST /api/admin/restore while it runs)\n"
+)
+
+// runBackup implements the "backup" subcommand.
+func runBackup(cfg config.Config, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(backupUsage)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if err := initSQLiteForCommand(ctx, cfg); err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.Backup(ctx, args[0]); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(out, "backed up %s to %s\n", cfg.DBPath, args[0])
+	return nil
+}
+
+// runRestore implements the "restore" subcommand.
+func runRestore(cfg config.Config, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(restoreUsage)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if err := initSQLiteForCommand(ctx, cfg); err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	res, err := db.Restore(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(out, "restored %s from %s\n", cfg.DBPath, args[0])
+	if res.SnapshotVersion != res.SchemaVersion {
+		_, _ = fmt.Fprintf(out, "migrated the snapshot from schema version %d to %d\n", res.SnapshotVersion, res.SchemaVersion)
+	}
+	_, _ = fmt.Fprintf(out, "the previous database is kept as %s\n", cfg.DBPath+db.PreRestoreSuffix)
+	return nil
+}
+
+func initSQLiteForCommand(ctx context.Context, cfg config.Config) error {
+	if cfg.DBDriver != config.DBDriverSQLite {
+		return fmt.Errorf("backup and restore require DB_DRIVER=%s", config.DBDriverSQLite)
+	}
+	if err := db.InitSQLite(ctx, cfg.DBPath); err != nil {
+		return fmt.Errorf("init database: %w", err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/config"
@@ -16,6 +17,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	if len(os.Args) > 1 {
+		commands := map[string]func(config.Config, []string, io.Writer) error{
+			"migrate": runMigrate,
+			"backup":  runBackup,
+			"restore": runRestore,
+		}
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(cfg, os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	log.Init(cfg.LogLevel)
 	log.Info("app starting",
 		"env", cfg.Env,
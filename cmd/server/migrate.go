@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/config"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+)
+
+const migrateUsage = `usage: server migrate <command> [-to VERSION]
+
+commands:
+  up      apply pending migrations (up to -to, default: latest)
+  down    roll back migrations (down to -to, default: the previous version)
+  status  list migrations and whether they are applied
+`
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(cfg config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", migrateUsage)
+	}
+	cmd := args[0]
+	if cfg.DBDriver == config.DBDriverMemory {
+		return fmt.Errorf("migrations do not apply to the %s driver", config.DBDriverMemory)
+	}
+
+	fs := flag.NewFlagSet("migrate "+cmd, flag.ContinueOnError)
+	fs.SetOutput(out)
+	to := fs.Int("to", -1, "target schema version")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := db.Init(ctx, db.Dialect(cfg.DBDriver), cfg.DBPath, cfg.DBDSN); err != nil {
+		return fmt.Errorf("init database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+	conn, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "up":
+		target := *to
+		if target < 0 {
+			target = 0
+		}
+		applied, err := db.MigrateUp(ctx, conn, target)
+		for _, m := range applied {
+			_, _ = fmt.Fprintf(out, "applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			_, _ = fmt.Fprintln(out, "no pending migrations")
+		}
+		return nil
+	case "down":
+		target := *to
+		if target < 0 {
+			current, err := db.SchemaVersion(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if target, err = previousVersion(current); err != nil {
+				return err
+			}
+		}
+		rolledBack, err := db.MigrateDown(ctx, conn, target)
+		for _, m := range rolledBack {
+			_, _ = fmt.Fprintf(out, "rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			_, _ = fmt.Fprintln(out, "nothing to roll back")
+		}
+		return nil
+	case "status":
+		statuses, err := db.MigrationStatuses(ctx, conn)
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			_, _ = fmt.Fprintf(out, "%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", cmd, migrateUsage)
+	}
+}
+
+// previousVersion returns the embedded migration version just below current (0 if none).
+func previousVersion(current int) (int, error) {
+	migrations, err := db.Migrations()
+	if err != nil {
+		return 0, err
+	}
+	prev := 0
+	for _, m := range migrations {
+		if m.Version < current {
+			prev = m.Version
+		}
+	}
+	return prev, nil
+}
@@ -4,17 +4,63 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packio"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packpolicy"
 	"github.com/joho/godotenv"
 )
 
+// minAdminTokenLen is the minimum length of ADMIN_TOKEN, the same as for API keys.
+const minAdminTokenLen = 16
+
+// Supported DB_DRIVER values.
+const (
+	DBDriverSQLite   = "sqlite"
+	DBDriverPostgres = "postgres"
+	// DBDriverMemory keeps all data in memory; nothing is persisted.
+	DBDriverMemory = "memory"
+)
+
 type Config struct {
 	Env      string
 	LogLevel string
 	HTTPPort string
-	DBPath   string
+	DBDriver string
+	// DBPath is the SQLite database file; DBDSN the PostgreSQL connection string.
+	DBPath string
+	DBDSN  string
+
+	// RequireIfMatch makes If-Match mandatory on pack size updates and deletes.
+	RequireIfMatch bool
+
+	// PackCacheTTL is how long pack size listings are cached; 0 disables the cache.
+	PackCacheTTL time.Duration
+
+	// IdempotencyKeyTTL is how long Idempotency-Key headers and their responses are remembered.
+	IdempotencyKeyTTL time.Duration
+
+	// DefaultPackSizes are the sizes a pack set reset restores, from DEFAULT_PACK_SIZES or
+	// DEFAULT_PACK_SIZES_FILE. Empty means the built-in defaults.
+	DefaultPackSizes []int
+
+	// PackSizePolicy restricts the pack sizes that can be stored, from MIN_PACK_SIZE,
+	// MAX_PACK_SIZE, MAX_PACK_SIZES_PER_SET, PACK_SIZE_MULTIPLES and FORBIDDEN_PACK_SIZES.
+	PackSizePolicy packpolicy.Policy
+
+	// SeedFile is an optional JSON or YAML file of pack sets applied on startup.
+	SeedFile string
+
+	// TenantsFile is an optional JSON or YAML file listing the tenants, their API keys and
+	// default pack sizes. Without it any tenant named in the X-Tenant header is accepted.
+	TenantsFile string
+
+	// AdminToken is the bearer token of the admin endpoints (backup and restore); empty turns
+	// them off.
+	AdminToken string
 }
 
 // Load config from .env if it exists. If it doesn't, fall back to real env vars.
@@ -31,8 +77,44 @@ func Load() (Config, error) {
 		Env:      getEnv("APP_ENV", "dev"),
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 		HTTPPort: getEnv("HTTP_PORT", "8080"),
+		DBDriver: strings.ToLower(strings.TrimSpace(getEnv("DB_DRIVER", DBDriverSQLite))),
 		DBPath:   getEnv("DB_PATH", "./data/app.db"),
+		DBDSN:    getEnv("DB_DSN", ""),
+		SeedFile: strings.TrimSpace(getEnv("SEED_FILE", "")),
+
+		TenantsFile: strings.TrimSpace(getEnv("TENANTS_FILE", "")),
+		AdminToken:  strings.TrimSpace(getEnv("ADMIN_TOKEN", "")),
+	}
+
+	requireIfMatch, err := getEnvBool("REQUIRE_IF_MATCH", false)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.RequireIfMatch = requireIfMatch
+
+	packCacheTTL, err := getEnvDuration("PACK_CACHE_TTL", 5*time.Second)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.PackCacheTTL = packCacheTTL
+
+	idempotencyKeyTTL, err := getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.IdempotencyKeyTTL = idempotencyKeyTTL
+
+	defaultPackSizes, err := loadDefaultPackSizes()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.DefaultPackSizes = defaultPackSizes
+
+	packSizePolicy, err := loadPackSizePolicy()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
 	}
+	cfg.PackSizePolicy = packSizePolicy
 
 	if err := validate(cfg); err != nil {
 		return Config{}, fmt.Errorf("invalid config: %w", err)
@@ -55,8 +137,35 @@ func validate(cfg Config) error {
 	} else if err := validateHTTPPort(cfg.HTTPPort); err != nil {
 		errs = append(errs, "HTTP_PORT is invalid: "+err.Error())
 	}
-	if strings.TrimSpace(cfg.DBPath) == "" {
-		errs = append(errs, "DB_PATH is required")
+	switch cfg.DBDriver {
+	case DBDriverSQLite:
+		if strings.TrimSpace(cfg.DBPath) == "" {
+			errs = append(errs, "DB_PATH is required")
+		}
+	case DBDriverPostgres:
+		if strings.TrimSpace(cfg.DBDSN) == "" {
+			errs = append(errs, "DB_DSN is required when DB_DRIVER is postgres")
+		}
+	case DBDriverMemory:
+	default:
+		errs = append(errs, fmt.Sprintf("DB_DRIVER must be %s, %s or %s, provided: %s",
+			DBDriverSQLite, DBDriverPostgres, DBDriverMemory, cfg.DBDriver))
+	}
+	if cfg.AdminToken != "" && len(cfg.AdminToken) < minAdminTokenLen {
+		errs = append(errs, fmt.Sprintf("ADMIN_TOKEN must be at least %d characters", minAdminTokenLen))
+	}
+	if cfg.IdempotencyKeyTTL <= 0 {
+		errs = append(errs, "IDEMPOTENCY_KEY_TTL must be > 0")
+	}
+	if err := cfg.PackSizePolicy.Validate(); err != nil {
+		errs = append(errs, "pack size policy is invalid: "+err.Error())
+	}
+	if cfg.DefaultPackSizes != nil {
+		if err := validatePackSizes(cfg.DefaultPackSizes); err != nil {
+			errs = append(errs, "default pack sizes are invalid: "+err.Error())
+		} else if err := cfg.PackSizePolicy.CheckSizes(cfg.DefaultPackSizes, len(cfg.DefaultPackSizes)); err != nil {
+			errs = append(errs, "default pack sizes violate the pack size policy: "+err.Error())
+		}
 	}
 	if len(errs) > 0 {
 		return errors.New(strings.Join(errs, "; "))
@@ -65,6 +174,102 @@ func validate(cfg Config) error {
 	return nil
 }
 
+func validatePackSizes(sizes []int) error {
+	if len(sizes) == 0 {
+		return errors.New("at least one size is required")
+	}
+	seen := make(map[int]bool, len(sizes))
+	for _, s := range sizes {
+		if s <= 0 {
+			return fmt.Errorf("size must be > 0, provided: %d", s)
+		}
+		if seen[s] {
+			return fmt.Errorf("duplicate size %d", s)
+		}
+		seen[s] = true
+	}
+	return nil
+}
+
+// loadDefaultPackSizes reads DEFAULT_PACK_SIZES (a comma-separated list) or
+// DEFAULT_PACK_SIZES_FILE (a CSV, JSON or YAML file in the pack size export format). It returns
+// nil when neither is set.
+func loadDefaultPackSizes() ([]int, error) {
+	list := strings.TrimSpace(getEnv("DEFAULT_PACK_SIZES", ""))
+	path := strings.TrimSpace(getEnv("DEFAULT_PACK_SIZES_FILE", ""))
+	switch {
+	case list != "" && path != "":
+		return nil, errors.New("set either DEFAULT_PACK_SIZES or DEFAULT_PACK_SIZES_FILE, not both")
+	case list != "":
+		return parsePackSizesList(list)
+	case path != "":
+		return readPackSizesFile(path)
+	default:
+		return nil, nil
+	}
+}
+
+func parsePackSizesList(list string) ([]int, error) {
+	parts := strings.Split(list, ",")
+	sizes := make([]int, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("DEFAULT_PACK_SIZES must be a comma-separated list of integers, provided: %s", list)
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes, nil
+}
+
+func readPackSizesFile(path string) ([]int, error) {
+	format := packio.ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+	if format == "" {
+		return nil, fmt.Errorf("DEFAULT_PACK_SIZES_FILE must be a .csv, .json or .yaml file, provided: %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DEFAULT_PACK_SIZES_FILE: %w", err)
+	}
+	sizes, err := packio.Decode(data, format)
+	if err != nil {
+		var invalid *packio.InvalidError
+		if errors.As(err, &invalid) && len(invalid.Rows) > 0 {
+			r := invalid.Rows[0]
+			return nil, fmt.Errorf("DEFAULT_PACK_SIZES_FILE row %d: %s", r.Row, r.Message)
+		}
+		return nil, fmt.Errorf("DEFAULT_PACK_SIZES_FILE: %w", err)
+	}
+	return sizes, nil
+}
+
+func loadPackSizePolicy() (packpolicy.Policy, error) {
+	var (
+		p   packpolicy.Policy
+		err error
+	)
+	if p.MinSize, err = getEnvInt("MIN_PACK_SIZE", 0); err != nil {
+		return p, err
+	}
+	if p.MaxSize, err = getEnvInt("MAX_PACK_SIZE", 0); err != nil {
+		return p, err
+	}
+	if p.MaxSizesPerSet, err = getEnvInt("MAX_PACK_SIZES_PER_SET", 0); err != nil {
+		return p, err
+	}
+	if p.Multiples, err = getEnvIntList("PACK_SIZE_MULTIPLES"); err != nil {
+		return p, err
+	}
+	if p.Forbidden, err = getEnvIntList("FORBIDDEN_PACK_SIZES"); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
 func validateHTTPPort(port string) error {
 	p, err := strconv.Atoi(strings.TrimSpace(port))
 	if err != nil {
@@ -82,3 +287,60 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvBool(key string, fallback bool) (bool, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(v) == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean, provided: %s", key, v)
+	}
+	return b, nil
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(v) == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration (e.g. 5s), provided: %s", key, v)
+	}
+	return d, nil
+}
+
+func getEnvInt(key string, fallback int) (int, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(v) == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, provided: %s", key, v)
+	}
+	return n, nil
+}
+
+// getEnvIntList reads a comma-separated list of integers; it returns nil when key is not set.
+func getEnvIntList(key string) ([]int, error) {
+	v := strings.TrimSpace(getEnv(key, ""))
+	if v == "" {
+		return nil, nil
+	}
+	var out []int
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a comma-separated list of integers, provided: %s", key, v)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
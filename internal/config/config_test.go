@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packpolicy"
+)
+
+func TestLoadDefaultPackSizes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		list    string
+		file    string
+		want    []int
+		wantErr string
+	}{
+		{name: "not configured", want: nil},
+		{name: "list", list: "500, 250,1000", want: []int{500, 250, 1000}},
+		{name: "json file", file: writeFile("sizes.json", `{"packs":[{"size":12},{"size":24}]}`), want: []int{12, 24}},
+		{name: "yaml file", file: writeFile("sizes.yml", "packs:\n  - size: 6\n"), want: []int{6}},
+		{name: "csv file", file: writeFile("sizes.csv", "size\n10\n20\n"), want: []int{10, 20}},
+		{name: "not a number", list: "250,abc", wantErr: "comma-separated list of integers"},
+		{name: "negative", list: "250,-1", wantErr: "default pack sizes are invalid: size must be > 0"},
+		{name: "duplicate", list: "250,250", wantErr: "default pack sizes are invalid: duplicate size 250"},
+		{name: "empty list", list: ",", wantErr: "at least one size is required"},
+		{name: "both", list: "250", file: "sizes.json", wantErr: "not both"},
+		{name: "unknown extension", file: writeFile("sizes.txt", "250"), wantErr: "must be a .csv, .json or .yaml file"},
+		{name: "missing file", file: filepath.Join(dir, "missing.json"), wantErr: "failed to read DEFAULT_PACK_SIZES_FILE"},
+		{name: "invalid row", file: writeFile("bad.json", `{"packs":[{"size":0}]}`), wantErr: "row 1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", "test")
+			t.Setenv("DB_DRIVER", DBDriverMemory)
+			t.Setenv("DEFAULT_PACK_SIZES", tc.list)
+			t.Setenv("DEFAULT_PACK_SIZES_FILE", tc.file)
+
+			cfg, err := Load()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.DefaultPackSizes, tc.want) {
+				t.Fatalf("default pack sizes = %v, want %v", cfg.DefaultPackSizes, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadPackSizePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    packpolicy.Policy
+		wantErr string
+	}{
+		{name: "not configured"},
+		{
+			name: "all limits",
+			env: map[string]string{
+				"MIN_PACK_SIZE": "10", "MAX_PACK_SIZE": "10000", "MAX_PACK_SIZES_PER_SET": "8",
+				"PACK_SIZE_MULTIPLES": "50, 125", "FORBIDDEN_PACK_SIZES": "666",
+			},
+			want: packpolicy.Policy{MinSize: 10, MaxSize: 10000, MaxSizesPerSet: 8, Multiples: []int{50, 125}, Forbidden: []int{666}},
+		},
+		{name: "not a number", env: map[string]string{"MIN_PACK_SIZE": "ten"}, wantErr: "MIN_PACK_SIZE must be an integer"},
+		{name: "bad list", env: map[string]string{"PACK_SIZE_MULTIPLES": "5,x"}, wantErr: "PACK_SIZE_MULTIPLES must be a comma-separated list"},
+		{name: "min above max", env: map[string]string{"MIN_PACK_SIZE": "500", "MAX_PACK_SIZE": "100"}, wantErr: "minimum size 500 is greater than maximum size 100"},
+		{name: "zero multiple", env: map[string]string{"PACK_SIZE_MULTIPLES": "0"}, wantErr: "multiples must be > 0"},
+		{
+			name:    "defaults break the policy",
+			env:     map[string]string{"DEFAULT_PACK_SIZES": "1,250", "MIN_PACK_SIZE": "10"},
+			wantErr: "default pack sizes violate the pack size policy: sizes: size 1 must be at least 10",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", "test")
+			t.Setenv("DB_DRIVER", DBDriverMemory)
+			for _, key := range []string{"DEFAULT_PACK_SIZES", "DEFAULT_PACK_SIZES_FILE", "MIN_PACK_SIZE", "MAX_PACK_SIZE",
+				"MAX_PACK_SIZES_PER_SET", "PACK_SIZE_MULTIPLES", "FORBIDDEN_PACK_SIZES"} {
+				t.Setenv(key, tc.env[key])
+			}
+
+			cfg, err := Load()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.PackSizePolicy, tc.want) {
+				t.Fatalf("policy = %+v, want %+v", cfg.PackSizePolicy, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadAdminToken(t *testing.T) {
+	t.Setenv("APP_ENV", "test")
+	t.Setenv("DB_DRIVER", DBDriverMemory)
+
+	t.Setenv("ADMIN_TOKEN", " admin-token-0123456789 ")
+	cfg, err := Load()
+	if err != nil || cfg.AdminToken != "admin-token-0123456789" {
+		t.Fatalf("admin token = %q, %v", cfg.AdminToken, err)
+	}
+
+	t.Setenv("ADMIN_TOKEN", "short")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "ADMIN_TOKEN must be at least 16 characters") {
+		t.Fatalf("expected a length error, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package configfile decodes the JSON and YAML files that configure a deployment, such as the seed
+// and tenants files.
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// FormatFromPath returns the format of a file by its extension, or "" if unsupported.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return ""
+	}
+}
+
+// Decode decodes data in the "json" or "yaml" format into v. Unknown fields are rejected so that
+// typos do not go unnoticed.
+func Decode(data []byte, format string, v any) error {
+	switch format {
+	case FormatJSON:
+	case FormatYAML:
+		// YAML is converted to JSON so that both formats share the json field names.
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("invalid yaml: %w", err)
+		}
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("invalid yaml: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	// ErrNotSQLite is returned by Backup and Restore unless the global connection is SQLite.
+	ErrNotSQLite = errors.New("backup and restore require the sqlite driver")
+	// ErrInvalidSnapshot means a restore was attempted from a file that is not a usable backup.
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+)
+
+// PreRestoreSuffix is appended to the database file name to keep the database that a restore
+// replaced, until the next restore.
+const PreRestoreSuffix = ".pre-restore"
+
+// restoreMu serializes restores, which share the staging file.
+var restoreMu sync.Mutex
+
+// RestoreResult describes a completed restore.
+type RestoreResult struct {
+	// SnapshotVersion is the schema version of the snapshot; it was migrated to SchemaVersion.
+	SnapshotVersion int
+	SchemaVersion   int
+}
+
+// Backup writes a consistent copy of the global SQLite database to destPath, which must not
+// exist. It runs online: writers wait for the copy to finish, readers are not blocked.
+func Backup(ctx context.Context, destPath string) error {
+	// The read lock keeps a restore from swapping the connection during the copy.
+	mu.RLock()
+	defer mu.RUnlock()
+	if dbConn == nil || dialect != DialectSQLite {
+		return ErrNotSQLite
+	}
+
+	if _, err := dbConn.ExecContext(ctx, `VACUUM INTO ?`, destPath); err != nil {
+		return fmt.Errorf("backup sqlite: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the global SQLite database with the snapshot at snapshotPath, which is left
+// unchanged. The snapshot must pass an integrity check and carry migrations known to this build;
+// an older snapshot is migrated to the latest schema before it is installed.
+//
+// The swap happens under the connection lock: queries that are running finish against the old
+// database, later ones use the restored one. The replaced database is kept next to it with
+// PreRestoreSuffix.
+func Restore(ctx context.Context, snapshotPath string) (*RestoreResult, error) {
+	restoreMu.Lock()
+	defer restoreMu.Unlock()
+
+	mu.RLock()
+	path, ok := sqlitePath, dbConn != nil && dialect == DialectSQLite
+	mu.RUnlock()
+	if !ok {
+		return nil, ErrNotSQLite
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	// The snapshot is prepared next to the database so that installing it is a rename.
+	staged := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".restore")
+	removeSQLiteFiles(staged)
+	defer removeSQLiteFiles(staged)
+	if err := copyFile(snapshotPath, staged); err != nil {
+		return nil, err
+	}
+	res, err := prepareSnapshot(ctx, staged, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if dbConn == nil || sqlitePath != path {
+		return nil, fmt.Errorf("database connection changed during restore")
+	}
+
+	// Closing the last connection checkpoints the WAL into the database file.
+	if err := dbConn.Close(); err != nil {
+		return nil, fmt.Errorf("close sqlite: %w", err)
+	}
+	dbConn = nil
+
+	previous := path + PreRestoreSuffix
+	removeSQLiteFiles(previous)
+	if err := os.Rename(path, previous); err != nil {
+		return nil, reopenAfterFailedRestore(ctx, path, fmt.Errorf("keep previous database: %w", err))
+	}
+	removeSQLiteFiles(path)
+	if err := os.Rename(staged, path); err != nil {
+		_ = os.Rename(previous, path)
+		return nil, reopenAfterFailedRestore(ctx, path, fmt.Errorf("install snapshot: %w", err))
+	}
+
+	conn, err := openSQLite(ctx, path)
+	if err != nil {
+		_ = os.Rename(previous, path)
+		return nil, reopenAfterFailedRestore(ctx, path, err)
+	}
+	dbConn = conn
+	return res, nil
+}
+
+// prepareSnapshot validates the snapshot at path and migrates it to the latest schema.
+func prepareSnapshot(ctx context.Context, path string, migrations []Migration) (*RestoreResult, error) {
+	conn, err := openSQLite(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	// Closing checkpoints the migrated snapshot into a single file.
+	defer func() { _ = conn.Close() }()
+
+	var check string
+	if err := conn.QueryRowContext(ctx, `PRAGMA quick_check`).Scan(&check); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	if check != "ok" {
+		return nil, fmt.Errorf("%w: integrity check failed: %s", ErrInvalidSnapshot, check)
+	}
+
+	version, err := snapshotVersion(ctx, conn, migrations)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrateUp(ctx, conn, migrations, 0); err != nil {
+		return nil, fmt.Errorf("migrate snapshot: %w", err)
+	}
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	return &RestoreResult{SnapshotVersion: version, SchemaVersion: latest}, nil
+}
+
+// snapshotVersion returns the schema version of a snapshot after checking that its applied
+// migrations are known to this build.
+func snapshotVersion(ctx context.Context, conn *sql.DB, migrations []Migration) (int, error) {
+	ok, err := hasTable(ctx, conn, "schema_migrations")
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w: no schema version", ErrInvalidSnapshot)
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	if err := verifyApplied(migrations, applied); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+
+	version := 0
+	for v := range applied {
+		version = max(version, v)
+	}
+	if version == 0 {
+		return 0, fmt.Errorf("%w: no schema version", ErrInvalidSnapshot)
+	}
+	return version, nil
+}
+
+// reopenAfterFailedRestore reopens the database at path after a failed swap; mu must be held.
+func reopenAfterFailedRestore(ctx context.Context, path string, cause error) error {
+	conn, err := openSQLite(ctx, path)
+	if err != nil {
+		sqlitePath = ""
+		return fmt.Errorf("restore: %w (reopening the database failed: %v)", cause, err)
+	}
+	dbConn = conn
+	return fmt.Errorf("restore: %w", cause)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("create staged snapshot: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("copy snapshot: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("copy snapshot: %w", err)
+	}
+	return nil
+}
+
+// removeSQLiteFiles removes a database file together with its WAL and shared-memory files.
+func removeSQLiteFiles(path string) {
+	for _, p := range []string{path, path + "-wal", path + "-shm"} {
+		_ = os.Remove(p)
+	}
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// initBackupTestDB opens the global connection on a migrated SQLite file with one widget row.
+func initBackupTestDB(t *testing.T) string {
+	t.Helper()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "app.db")
+	if err := InitSQLite(ctx, path); err != nil {
+		t.Fatalf("init sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = Close() })
+
+	conn, _ := DB()
+	if _, err := Migrate(ctx, conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	mustExec(t, `INSERT INTO pack_sizes(pack_set, size) VALUES('default', 250)`)
+	return path
+}
+
+func mustExec(t *testing.T, query string) {
+	t.Helper()
+
+	conn, err := DB()
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), query); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+func packSizeCount(t *testing.T) int {
+	t.Helper()
+
+	conn, err := DB()
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	var n int
+	if err := conn.QueryRowContext(context.Background(), `SELECT COUNT(*) FROM pack_sizes`).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	return n
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	path := initBackupTestDB(t)
+
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	if err := Backup(ctx, backup); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if err := Backup(ctx, backup); err == nil {
+		t.Fatalf("expected backup to refuse an existing file")
+	}
+
+	mustExec(t, `INSERT INTO pack_sizes(pack_set, size) VALUES('default', 500)`)
+	if n := packSizeCount(t); n != 2 {
+		t.Fatalf("count before restore = %d", n)
+	}
+
+	latest, _ := LatestSchemaVersion()
+	res, err := Restore(ctx, backup)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if res.SnapshotVersion != latest || res.SchemaVersion != latest {
+		t.Fatalf("result = %+v, want version %d", res, latest)
+	}
+	if n := packSizeCount(t); n != 1 {
+		t.Fatalf("count after restore = %d, want 1", n)
+	}
+
+	// The replaced database is kept, and the snapshot itself is not modified.
+	if _, err := os.Stat(path + PreRestoreSuffix); err != nil {
+		t.Fatalf("previous database: %v", err)
+	}
+	if _, err := os.Stat(backup + "-wal"); !os.IsNotExist(err) {
+		t.Fatalf("expected the snapshot to be left alone, got %v", err)
+	}
+}
+
+func TestRestoreMigratesOlderSnapshots(t *testing.T) {
+	ctx := context.Background()
+	initBackupTestDB(t)
+
+	// Make a snapshot at schema version 1.
+	snapshot := filepath.Join(t.TempDir(), "v1.db")
+	conn, err := openSQLite(ctx, snapshot)
+	if err != nil {
+		t.Fatalf("open snapshot: %v", err)
+	}
+	if _, err := MigrateUp(ctx, conn, 1); err != nil {
+		t.Fatalf("migrate snapshot: %v", err)
+	}
+	_ = conn.Close()
+
+	res, err := Restore(ctx, snapshot)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	latest, _ := LatestSchemaVersion()
+	if res.SnapshotVersion != 1 || res.SchemaVersion != latest {
+		t.Fatalf("result = %+v", res)
+	}
+	conn, _ = DB()
+	if v, _ := SchemaVersion(ctx, conn); v != latest {
+		t.Fatalf("schema version after restore = %d, want %d", v, latest)
+	}
+}
+
+func TestRestoreRejectsInvalidSnapshots(t *testing.T) {
+	ctx := context.Background()
+	initBackupTestDB(t)
+	dir := t.TempDir()
+
+	notADatabase := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(notADatabase, []byte("definitely not sqlite, but long enough to look like a header"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	noSchema := filepath.Join(dir, "empty.db")
+	conn, err := openSQLite(ctx, noSchema)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = conn.ExecContext(ctx, `CREATE TABLE other (id INTEGER)`)
+	_ = conn.Close()
+
+	newer := filepath.Join(dir, "newer.db")
+	if err := Backup(ctx, newer); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	conn, err = openSQLite(ctx, newer)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = conn.ExecContext(ctx, `INSERT INTO schema_migrations(version, name, checksum, applied_at)
+	VALUES(9999, 'future', 'x', '2030-01-01T00:00:00Z')`)
+	_ = conn.Close()
+
+	for name, path := range map[string]string{"not a database": notADatabase, "no schema": noSchema, "newer": newer} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Restore(ctx, path); !errors.Is(err, ErrInvalidSnapshot) {
+				t.Fatalf("expected ErrInvalidSnapshot, got %v", err)
+			}
+			// The live database is untouched and still usable.
+			if n := packSizeCount(t); n != 1 {
+				t.Fatalf("count = %d, want 1", n)
+			}
+		})
+	}
+}
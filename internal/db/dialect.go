@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect identifies the SQL flavour spoken by the global connection.
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+// Init opens the global connection for the given dialect: the SQLite file at sqlitePath or the
+// PostgreSQL server at dsn.
+func Init(ctx context.Context, d Dialect, sqlitePath, dsn string) error {
+	switch d {
+	case DialectSQLite:
+		return InitSQLite(ctx, sqlitePath)
+	case DialectPostgres:
+		return InitPostgres(ctx, dsn)
+	default:
+		return fmt.Errorf("unsupported database dialect %q", d)
+	}
+}
+
+// CurrentDialect returns the dialect of the global connection (SQLite until another driver is opened).
+func CurrentDialect() Dialect {
+	mu.RLock()
+	defer mu.RUnlock()
+	return dialect
+}
+
+// Rebind rewrites the ? placeholders in query for the current dialect, so repositories can keep
+// a single copy of each statement.
+func Rebind(query string) string {
+	return rebind(CurrentDialect(), query)
+}
+
+func rebind(d Dialect, query string) string {
+	if d != DialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var (
+		b      strings.Builder
+		n      int
+		quoted bool
+	)
+	b.Grow(len(query) + 8)
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
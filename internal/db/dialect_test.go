@@ -0,0 +1,45 @@
+package db
+
+import (
+	"path"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		in      string
+		want    string
+	}{
+		{DialectSQLite, `SELECT 1 FROM t WHERE a = ? AND b = ?`, `SELECT 1 FROM t WHERE a = ? AND b = ?`},
+		{DialectPostgres, `SELECT 1 FROM t WHERE a = ? AND b = ?`, `SELECT 1 FROM t WHERE a = $1 AND b = $2`},
+		{DialectPostgres, `SELECT 1 FROM t WHERE a = '?' AND b = ?`, `SELECT 1 FROM t WHERE a = '?' AND b = $1`},
+		{DialectPostgres, `SELECT 1`, `SELECT 1`},
+	}
+	for _, tc := range cases {
+		if got := rebind(tc.dialect, tc.in); got != tc.want {
+			t.Errorf("rebind(%s, %q) = %q, want %q", tc.dialect, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestEmbeddedMigrationsMatchAcrossDialects(t *testing.T) {
+	sqlite, err := loadMigrations(embeddedMigrations, path.Join("migrations", string(DialectSQLite)))
+	if err != nil {
+		t.Fatalf("load sqlite migrations: %v", err)
+	}
+	postgres, err := loadMigrations(embeddedMigrations, path.Join("migrations", string(DialectPostgres)))
+	if err != nil {
+		t.Fatalf("load postgres migrations: %v", err)
+	}
+
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("sqlite has %d migrations, postgres %d", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("migration %d: sqlite %d_%s, postgres %d_%s", i,
+				sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
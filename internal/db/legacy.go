@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// adoptLegacySchema prepares a database created before migrations existed, when tables were
+// created on demand, so that the idempotent baseline migration can be applied on top of it.
+// It does nothing for a new database.
+func adoptLegacySchema(ctx context.Context, conn *sql.DB) error {
+	exists, err := hasTable(ctx, conn, "pack_sizes")
+	if err != nil || !exists {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction at adoptLegacySchema: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	cols, err := tableColumns(ctx, tx, "pack_sizes")
+	if err != nil {
+		return err
+	}
+
+	var stmts []string
+	if !cols["pack_set"] {
+		// Before pack sets, size was globally UNIQUE; rebuild the table and move the rows
+		// into the default pack set.
+		stmts = append(stmts,
+			`ALTER TABLE pack_sizes RENAME TO pack_sizes_legacy`,
+			`CREATE TABLE pack_sizes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			pack_set TEXT NOT NULL DEFAULT 'default',
+			size INTEGER NOT NULL
+			)`,
+			`INSERT INTO pack_sizes(id, pack_set, size) SELECT id, 'default', size FROM pack_sizes_legacy`,
+			`DROP TABLE pack_sizes_legacy`,
+		)
+	}
+	if !cols["deleted_at"] {
+		stmts = append(stmts, `ALTER TABLE pack_sizes ADD COLUMN deleted_at TEXT`)
+	}
+	if !cols["version"] {
+		stmts = append(stmts, `ALTER TABLE pack_sizes ADD COLUMN version INTEGER NOT NULL DEFAULT 1`)
+	}
+	// Replaced by the partial index on live rows.
+	stmts = append(stmts, `DROP INDEX IF EXISTS ux_pack_sizes_set_size`)
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("adopt legacy pack_sizes table: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction at adoptLegacySchema: %w", err)
+	}
+	return nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func hasTable(ctx context.Context, q queryer, table string) (bool, error) {
+	rows, err := q.QueryContext(ctx, `SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?`, table)
+	if err != nil {
+		return false, fmt.Errorf("look up %s table: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+	return rows.Next(), rows.Err()
+}
+
+func tableColumns(ctx context.Context, q queryer, table string) (map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, fmt.Errorf("read %s columns: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan %s column: %w", table, err)
+		}
+		out[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate %s columns: %w", table, err)
+	}
+	return out, nil
+}
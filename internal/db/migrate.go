@@ -0,0 +1,378 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var embeddedMigrations embed.FS
+
+var (
+	// ErrChecksumMismatch means an applied migration was edited after it ran.
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrUnknownMigration means the database was migrated by a newer build.
+	ErrUnknownMigration = errors.New("database has migrations unknown to this build")
+	ErrNoDownMigration  = errors.New("migration has no down script")
+)
+
+// Migration is a single versioned schema change, loaded from a pair of files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of Up; it is stored when the migration is applied.
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migrations returns the embedded migrations for the current dialect, ordered by version.
+func Migrations() ([]Migration, error) {
+	return loadMigrations(embeddedMigrations, path.Join("migrations", string(CurrentDialect())))
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", e.Name())
+		}
+		b, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(b)
+		} else {
+			mig.Down = string(b)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Migrate applies all pending embedded migrations to conn.
+func Migrate(ctx context.Context, conn *sql.DB) ([]Migration, error) {
+	return MigrateUp(ctx, conn, 0)
+}
+
+// MigrateUp applies pending embedded migrations up to and including target (0 means latest).
+// It refuses to run when an applied migration was modified or is unknown to this build.
+func MigrateUp(ctx context.Context, conn *sql.DB, target int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return migrateUp(ctx, conn, migrations, target)
+}
+
+// MigrateDown rolls back applied embedded migrations, newest first, until the schema is at
+// version target (0 rolls back everything).
+func MigrateDown(ctx context.Context, conn *sql.DB, target int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return migrateDown(ctx, conn, migrations, target)
+}
+
+// MigrationStatuses lists the embedded migrations and whether they have been applied. The list is
+// also returned along with ErrChecksumMismatch or ErrUnknownMigration so that it can be inspected.
+func MigrationStatuses(ctx context.Context, conn *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return migrationStatuses(ctx, conn, migrations)
+}
+
+// SchemaVersion returns the highest applied migration version (0 for an empty database).
+func SchemaVersion(ctx context.Context, conn *sql.DB) (int, error) {
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return 0, err
+	}
+	var v int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return v, nil
+}
+
+// LatestSchemaVersion returns the version of the newest embedded migration.
+func LatestSchemaVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *sql.DB) error {
+	_, err := conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+	);`)
+	if err != nil {
+		return fmt.Errorf("ensure schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.DB) (map[int]appliedMigration, error) {
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make(map[int]appliedMigration)
+	for rows.Next() {
+		var (
+			version   int
+			a         appliedMigration
+			appliedAt string
+		)
+		if err := rows.Scan(&version, &a.checksum, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan applied migration: %w", err)
+		}
+		if a.appliedAt, err = time.Parse(time.RFC3339Nano, appliedAt); err != nil {
+			return nil, fmt.Errorf("parse migration %d applied_at: %w", version, err)
+		}
+		out[version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate applied migrations: %w", err)
+	}
+	return out, nil
+}
+
+// verifyApplied checks that every applied migration is known and unchanged.
+func verifyApplied(migrations []Migration, applied map[int]appliedMigration) error {
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	for version, a := range applied {
+		m, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+		if m.Checksum != a.checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+func migrateUp(ctx context.Context, conn *sql.DB, migrations []Migration, target int) ([]Migration, error) {
+	unlock, err := lockMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyApplied(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	// Only SQLite databases predate migrations.
+	if len(applied) == 0 && CurrentDialect() == DialectSQLite {
+		if err := adoptLegacySchema(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if target > 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := runMigration(ctx, conn, m.Up, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, Rebind(`
+			INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES(?, ?, ?, ?)`),
+				m.Version, m.Name, m.Checksum, time.Now().UTC().Format(time.RFC3339Nano))
+			return err
+		}); err != nil {
+			return done, fmt.Errorf("apply migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func migrateDown(ctx context.Context, conn *sql.DB, migrations []Migration, target int) ([]Migration, error) {
+	unlock, err := lockMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyApplied(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("%w: %d_%s", ErrNoDownMigration, m.Version, m.Name)
+		}
+		if err := runMigration(ctx, conn, m.Down, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, Rebind(`DELETE FROM schema_migrations WHERE version = ?`), m.Version)
+			return err
+		}); err != nil {
+			return done, fmt.Errorf("roll back migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// lockMigrations keeps other processes sharing a PostgreSQL database, e.g. replicas starting
+// together, from migrating at the same time; the applied migrations must be read after it. The
+// session-level lock is held on a connection of its own until the returned function is called.
+// SQLite is only ever migrated by a single process and needs no lock.
+func lockMigrations(ctx context.Context, conn *sql.DB) (func(), error) {
+	if CurrentDialect() != DialectPostgres {
+		return func() {}, nil
+	}
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lock migrations: %w", err)
+	}
+	if _, err := c.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext('schema_migrations'))`); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("lock migrations: %w", err)
+	}
+	return func() {
+		if _, err := c.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext('schema_migrations'))`); err != nil {
+			// Closing the session is the only other way to give the lock up.
+			_ = c.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		_ = c.Close()
+	}, nil
+}
+
+// runMigration executes script and the bookkeeping in record in a single transaction.
+func runMigration(ctx context.Context, conn *sql.DB, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction at runMigration: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction at runMigration: %w", err)
+	}
+	return nil
+}
+
+func migrationStatuses(ctx context.Context, conn *sql.DB, migrations []Migration) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Migration: m}
+		if a, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.appliedAt
+		}
+		out = append(out, s)
+	}
+	return out, verifyApplied(migrations, applied)
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite", sqliteDSN(filepath.Join(t.TempDir(), "test.db")))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func testMigrations(t *testing.T, files map[string]string) []Migration {
+	t.Helper()
+
+	fsys := fstest.MapFS{}
+	for name, body := range files {
+		fsys["m/"+name] = &fstest.MapFile{Data: []byte(body)}
+	}
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	return migrations
+}
+
+func tableExists(t *testing.T, conn *sql.DB, table string) bool {
+	t.Helper()
+
+	ok, err := hasTable(context.Background(), conn, table)
+	if err != nil {
+		t.Fatalf("has table: %v", err)
+	}
+	return ok
+}
+
+var twoMigrations = map[string]string{
+	"0001_widgets.up.sql":   `CREATE TABLE widgets (id INTEGER PRIMARY KEY);`,
+	"0001_widgets.down.sql": `DROP TABLE widgets;`,
+	"0002_gadgets.up.sql":   `CREATE TABLE gadgets (id INTEGER PRIMARY KEY); CREATE INDEX ix_gadgets ON gadgets(id);`,
+	"0002_gadgets.down.sql": `DROP TABLE gadgets;`,
+}
+
+func TestEmbeddedMigrationsApply(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+
+	applied, err := Migrate(ctx, conn)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(applied) == 0 {
+		t.Fatalf("expected migrations to be applied")
+	}
+	for _, table := range []string{"pack_sizes", "pack_set_versions", "audit_log", "products"} {
+		if !tableExists(t, conn, table) {
+			t.Fatalf("expected table %s to exist", table)
+		}
+	}
+
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		t.Fatalf("latest version: %v", err)
+	}
+	if v, err := SchemaVersion(ctx, conn); err != nil || v != latest {
+		t.Fatalf("expected schema version %d, got %d err=%v", latest, v, err)
+	}
+
+	applied, err = Migrate(ctx, conn)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("expected second run to be a no-op, got %v err=%v", applied, err)
+	}
+
+	if _, err := MigrateDown(ctx, conn, 0); err != nil {
+		t.Fatalf("migrate down: %v", err)
+	}
+	if tableExists(t, conn, "pack_sizes") {
+		t.Fatalf("expected pack_sizes to be dropped")
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+	migrations := testMigrations(t, twoMigrations)
+
+	applied, err := migrateUp(ctx, conn, migrations, 1)
+	if err != nil || len(applied) != 1 || applied[0].Version != 1 {
+		t.Fatalf("expected only migration 1, got %v err=%v", applied, err)
+	}
+	if tableExists(t, conn, "gadgets") {
+		t.Fatalf("migration 2 must not be applied yet")
+	}
+
+	statuses, err := migrationStatuses(ctx, conn, migrations)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if !statuses[0].Applied || statuses[0].AppliedAt.IsZero() || statuses[1].Applied {
+		t.Fatalf("unexpected statuses %+v", statuses)
+	}
+
+	if applied, err = migrateUp(ctx, conn, migrations, 0); err != nil || len(applied) != 1 || applied[0].Version != 2 {
+		t.Fatalf("expected migration 2, got %v err=%v", applied, err)
+	}
+
+	rolledBack, err := migrateDown(ctx, conn, migrations, 0)
+	if err != nil || len(rolledBack) != 2 || rolledBack[0].Version != 2 || rolledBack[1].Version != 1 {
+		t.Fatalf("expected 2 then 1 rolled back, got %v err=%v", rolledBack, err)
+	}
+	if tableExists(t, conn, "widgets") || tableExists(t, conn, "gadgets") {
+		t.Fatalf("expected tables to be dropped")
+	}
+	if v, err := SchemaVersion(ctx, conn); err != nil || v != 0 {
+		t.Fatalf("expected schema version 0, got %d err=%v", v, err)
+	}
+}
+
+func TestMigrateDetectsChangedAndUnknownMigrations(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+
+	if _, err := migrateUp(ctx, conn, testMigrations(t, twoMigrations), 0); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	changed := map[string]string{}
+	for k, v := range twoMigrations {
+		changed[k] = v
+	}
+	changed["0001_widgets.up.sql"] = `CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT);`
+	if _, err := migrateUp(ctx, conn, testMigrations(t, changed), 0); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	older := map[string]string{
+		"0001_widgets.up.sql":   twoMigrations["0001_widgets.up.sql"],
+		"0001_widgets.down.sql": twoMigrations["0001_widgets.down.sql"],
+	}
+	if _, err := migrateUp(ctx, conn, testMigrations(t, older), 0); !errors.Is(err, ErrUnknownMigration) {
+		t.Fatalf("expected ErrUnknownMigration, got %v", err)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+	migrations := testMigrations(t, map[string]string{
+		"0001_broken.up.sql": `CREATE TABLE half (id INTEGER PRIMARY KEY); INSERT INTO missing VALUES (1);`,
+	})
+
+	if _, err := migrateUp(ctx, conn, migrations, 0); err == nil {
+		t.Fatalf("expected error")
+	}
+	if tableExists(t, conn, "half") {
+		t.Fatalf("expected partial migration to be rolled back")
+	}
+	if v, err := SchemaVersion(ctx, conn); err != nil || v != 0 {
+		t.Fatalf("expected schema version 0, got %d err=%v", v, err)
+	}
+
+	if _, err := migrateDown(ctx, conn, testMigrations(t, twoMigrations), 0); err != nil {
+		t.Fatalf("rolling back nothing should succeed: %v", err)
+	}
+}
+
+func TestLoadMigrationsRejectsInvalidFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"bad name":   {"m/widgets.sql": {Data: []byte("SELECT 1")}},
+		"missing up": {"m/0001_widgets.down.sql": {Data: []byte("SELECT 1")}},
+		"name clash": {"m/0001_a.up.sql": {Data: []byte("SELECT 1")}, "m/0001_b.down.sql": {Data: []byte("SELECT 1")}},
+	}
+	for name, fsys := range cases {
+		if _, err := loadMigrations(fsys, "m"); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestMigrateAdoptsLegacySchema(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+
+	// Schema created by builds that predate pack sets and migrations.
+	if _, err := conn.ExecContext(ctx, `
+	CREATE TABLE pack_sizes (id INTEGER PRIMARY KEY AUTOINCREMENT, size INTEGER NOT NULL UNIQUE);
+	INSERT INTO pack_sizes(size) VALUES (250), (500);`); err != nil {
+		t.Fatalf("create legacy schema: %v", err)
+	}
+
+	if _, err := Migrate(ctx, conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT id, pack_set, size, version FROM pack_sizes WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var got []string
+	for rows.Next() {
+		var (
+			id, size, version int
+			set               string
+		)
+		if err := rows.Scan(&id, &set, &size, &version); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		if set != "default" || version != 1 {
+			t.Fatalf("unexpected row id=%d set=%s version=%d", id, set, version)
+		}
+		got = append(got, set)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 adopted rows, got %d", len(got))
+	}
+
+	// The same size may now exist in another pack set, but not twice in the same one.
+	if _, err := conn.ExecContext(ctx, `INSERT INTO pack_sizes(pack_set, size) VALUES ('retail', 250)`); err != nil {
+		t.Fatalf("insert into other set: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, `INSERT INTO pack_sizes(pack_set, size) VALUES ('default', 250)`); err == nil {
+		t.Fatalf("expected unique violation")
+	}
+}
@@ -0,0 +1,45 @@
+-- PostgreSQL counterpart of the SQLite baseline. Timestamps stay TEXT (RFC 3339, UTC) so that
+-- both backends store and compare them the same way.
+
+CREATE TABLE IF NOT EXISTS pack_sizes (
+	id BIGSERIAL PRIMARY KEY,
+	pack_set TEXT NOT NULL DEFAULT 'default',
+	size INTEGER NOT NULL,
+	deleted_at TEXT,
+	version BIGINT NOT NULL DEFAULT 1
+);
+
+-- Sizes only need to be unique among live rows; soft-deleted rows may repeat them.
+CREATE UNIQUE INDEX IF NOT EXISTS ux_pack_sizes_set_size_live ON pack_sizes(pack_set, size)
+WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS pack_set_versions (
+	id BIGSERIAL PRIMARY KEY,
+	pack_set TEXT NOT NULL,
+	version BIGINT NOT NULL,
+	created_at TEXT NOT NULL,
+	packs TEXT NOT NULL,
+	UNIQUE(pack_set, version)
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	request_id TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL,
+	pack_set TEXT NOT NULL,
+	pack_size_id BIGINT,
+	before_value TEXT,
+	after_value TEXT
+);
+
+CREATE INDEX IF NOT EXISTS ix_audit_log_created_at ON audit_log(created_at);
+
+CREATE TABLE IF NOT EXISTS products (
+	id BIGSERIAL PRIMARY KEY,
+	sku TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	pack_set TEXT NOT NULL DEFAULT 'default'
+);
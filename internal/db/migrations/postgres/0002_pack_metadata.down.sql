@@ -0,0 +1,7 @@
+ALTER TABLE pack_sizes DROP COLUMN weight_grams;
+ALTER TABLE pack_sizes DROP COLUMN height_mm;
+ALTER TABLE pack_sizes DROP COLUMN width_mm;
+ALTER TABLE pack_sizes DROP COLUMN length_mm;
+ALTER TABLE pack_sizes DROP COLUMN gtin;
+ALTER TABLE pack_sizes DROP COLUMN supplier_sku;
+ALTER TABLE pack_sizes DROP COLUMN label;
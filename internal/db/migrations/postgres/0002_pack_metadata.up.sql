@@ -0,0 +1,10 @@
+-- Optional metadata describing the physical pack behind a size. Empty strings and zeros mean
+-- "not set"; dimensions are in millimetres and weight in grams.
+
+ALTER TABLE pack_sizes ADD COLUMN label TEXT NOT NULL DEFAULT '';
+ALTER TABLE pack_sizes ADD COLUMN supplier_sku TEXT NOT NULL DEFAULT '';
+ALTER TABLE pack_sizes ADD COLUMN gtin TEXT NOT NULL DEFAULT '';
+ALTER TABLE pack_sizes ADD COLUMN length_mm INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE pack_sizes ADD COLUMN width_mm INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE pack_sizes ADD COLUMN height_mm INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE pack_sizes ADD COLUMN weight_grams INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,21 @@
+-- Only the default tenant's data fits the single-tenant schema; other tenants are dropped.
+
+DELETE FROM products WHERE tenant <> 'default';
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_tenant_sku_key;
+ALTER TABLE products DROP COLUMN tenant;
+ALTER TABLE products ADD CONSTRAINT products_sku_key UNIQUE (sku);
+
+DELETE FROM pack_set_versions WHERE tenant <> 'default';
+ALTER TABLE pack_set_versions DROP CONSTRAINT IF EXISTS pack_set_versions_tenant_pack_set_version_key;
+ALTER TABLE pack_set_versions DROP COLUMN tenant;
+ALTER TABLE pack_set_versions ADD CONSTRAINT pack_set_versions_pack_set_version_key UNIQUE (pack_set, version);
+
+DELETE FROM audit_log WHERE tenant <> 'default';
+DROP INDEX IF EXISTS ix_audit_log_tenant;
+ALTER TABLE audit_log DROP COLUMN tenant;
+
+DELETE FROM pack_sizes WHERE tenant <> 'default';
+DROP INDEX IF EXISTS ux_pack_sizes_tenant_set_size_live;
+ALTER TABLE pack_sizes DROP COLUMN tenant;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_pack_sizes_set_size_live ON pack_sizes(pack_set, size)
+WHERE deleted_at IS NULL;
@@ -0,0 +1,19 @@
+-- Every row belongs to a tenant; existing data is assigned to the default tenant. Pack sizes,
+-- pack set versions and product SKUs only need to be unique within a tenant.
+
+ALTER TABLE pack_sizes ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+DROP INDEX IF EXISTS ux_pack_sizes_set_size_live;
+CREATE UNIQUE INDEX ux_pack_sizes_tenant_set_size_live ON pack_sizes(tenant, pack_set, size)
+WHERE deleted_at IS NULL;
+
+ALTER TABLE audit_log ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+CREATE INDEX ix_audit_log_tenant ON audit_log(tenant, id);
+
+ALTER TABLE pack_set_versions ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE pack_set_versions DROP CONSTRAINT IF EXISTS pack_set_versions_pack_set_version_key;
+ALTER TABLE pack_set_versions ADD CONSTRAINT pack_set_versions_tenant_pack_set_version_key
+UNIQUE (tenant, pack_set, version);
+
+ALTER TABLE products ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sku_key;
+ALTER TABLE products ADD CONSTRAINT products_tenant_sku_key UNIQUE (tenant, sku);
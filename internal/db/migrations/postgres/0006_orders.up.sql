@@ -0,0 +1,21 @@
+-- Orders and their frozen allocation. packs holds the allocation as JSON, calculated once when the
+-- order is created (calculation_id points at the calculation history entry).
+
+CREATE TABLE orders (
+	id BIGSERIAL PRIMARY KEY,
+	tenant TEXT NOT NULL DEFAULT 'default',
+	reference TEXT NOT NULL DEFAULT '',
+	pack_set TEXT NOT NULL,
+	sku TEXT NOT NULL DEFAULT '',
+	quantity INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	packs TEXT NOT NULL,
+	shipped INTEGER NOT NULL,
+	overage INTEGER NOT NULL,
+	calculation_id BIGINT NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+
+CREATE INDEX ix_orders_tenant_status ON orders(tenant, status);
+CREATE INDEX ix_orders_tenant_reference ON orders(tenant, reference);
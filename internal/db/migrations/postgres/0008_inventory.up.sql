@@ -0,0 +1,30 @@
+-- Packs on hand per pack size. Every change to on_hand goes through inventory_ledger in the same
+-- transaction, so a size's ledger entries always add up to its on_hand (balance is on_hand after
+-- the entry). order_id is set for deductions and releases caused by an order.
+
+CREATE TABLE inventory_stock (
+	tenant TEXT NOT NULL DEFAULT 'default',
+	pack_set TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	on_hand INTEGER NOT NULL CHECK (on_hand >= 0),
+	updated_at TEXT NOT NULL,
+	PRIMARY KEY (tenant, pack_set, size)
+);
+
+CREATE TABLE inventory_ledger (
+	id BIGSERIAL PRIMARY KEY,
+	tenant TEXT NOT NULL DEFAULT 'default',
+	created_at TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	request_id TEXT NOT NULL DEFAULT '',
+	pack_set TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	kind TEXT NOT NULL,
+	quantity INTEGER NOT NULL,
+	balance INTEGER NOT NULL,
+	order_id BIGINT,
+	note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX ix_inventory_ledger_tenant_created_at ON inventory_ledger(tenant, created_at);
+CREATE INDEX ix_inventory_ledger_tenant_order ON inventory_ledger(tenant, order_id);
@@ -0,0 +1,4 @@
+-- The ETag header of a stored response, replayed along with it so that a retried request can
+-- make its next conditional update with the same version.
+
+ALTER TABLE idempotency_keys ADD COLUMN etag TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS pack_set_versions;
+DROP TABLE IF EXISTS pack_sizes;
@@ -0,0 +1,38 @@
+-- Only the default tenant's data fits the single-tenant schema; other tenants are dropped.
+
+DELETE FROM products WHERE tenant <> 'default';
+CREATE TABLE products_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	sku TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	pack_set TEXT NOT NULL DEFAULT 'default'
+);
+INSERT INTO products_old(id, sku, name, description, pack_set)
+SELECT id, sku, name, description, pack_set FROM products;
+DROP TABLE products;
+ALTER TABLE products_old RENAME TO products;
+
+DELETE FROM pack_set_versions WHERE tenant <> 'default';
+CREATE TABLE pack_set_versions_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	pack_set TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	created_at TEXT NOT NULL,
+	packs TEXT NOT NULL,
+	UNIQUE(pack_set, version)
+);
+INSERT INTO pack_set_versions_old(id, pack_set, version, created_at, packs)
+SELECT id, pack_set, version, created_at, packs FROM pack_set_versions;
+DROP TABLE pack_set_versions;
+ALTER TABLE pack_set_versions_old RENAME TO pack_set_versions;
+
+DELETE FROM audit_log WHERE tenant <> 'default';
+DROP INDEX IF EXISTS ix_audit_log_tenant;
+ALTER TABLE audit_log DROP COLUMN tenant;
+
+DELETE FROM pack_sizes WHERE tenant <> 'default';
+DROP INDEX IF EXISTS ux_pack_sizes_tenant_set_size_live;
+ALTER TABLE pack_sizes DROP COLUMN tenant;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_pack_sizes_set_size_live ON pack_sizes(pack_set, size)
+WHERE deleted_at IS NULL;
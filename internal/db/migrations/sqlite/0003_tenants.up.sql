@@ -0,0 +1,39 @@
+-- Every row belongs to a tenant; existing data is assigned to the default tenant. Pack sizes,
+-- pack set versions and product SKUs only need to be unique within a tenant.
+
+ALTER TABLE pack_sizes ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+DROP INDEX IF EXISTS ux_pack_sizes_set_size_live;
+CREATE UNIQUE INDEX ux_pack_sizes_tenant_set_size_live ON pack_sizes(tenant, pack_set, size)
+WHERE deleted_at IS NULL;
+
+ALTER TABLE audit_log ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+CREATE INDEX ix_audit_log_tenant ON audit_log(tenant, id);
+
+-- SQLite cannot change a UNIQUE table constraint, so these tables are rebuilt.
+CREATE TABLE pack_set_versions_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tenant TEXT NOT NULL DEFAULT 'default',
+	pack_set TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	created_at TEXT NOT NULL,
+	packs TEXT NOT NULL,
+	UNIQUE(tenant, pack_set, version)
+);
+INSERT INTO pack_set_versions_new(id, pack_set, version, created_at, packs)
+SELECT id, pack_set, version, created_at, packs FROM pack_set_versions;
+DROP TABLE pack_set_versions;
+ALTER TABLE pack_set_versions_new RENAME TO pack_set_versions;
+
+CREATE TABLE products_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tenant TEXT NOT NULL DEFAULT 'default',
+	sku TEXT NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	pack_set TEXT NOT NULL DEFAULT 'default',
+	UNIQUE(tenant, sku)
+);
+INSERT INTO products_new(id, sku, name, description, pack_set)
+SELECT id, sku, name, description, pack_set FROM products;
+DROP TABLE products;
+ALTER TABLE products_new RENAME TO products;
@@ -0,0 +1,2 @@
+ALTER TABLE pack_sizes DROP COLUMN effective_to;
+ALTER TABLE pack_sizes DROP COLUMN effective_from;
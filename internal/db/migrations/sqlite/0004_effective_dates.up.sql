@@ -0,0 +1,5 @@
+-- Optional period in which a pack size is used by calculations: from effective_from (inclusive)
+-- until effective_to (exclusive). NULL leaves that end of the period open.
+
+ALTER TABLE pack_sizes ADD COLUMN effective_from TEXT;
+ALTER TABLE pack_sizes ADD COLUMN effective_to TEXT;
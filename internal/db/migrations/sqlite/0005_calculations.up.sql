@@ -0,0 +1,23 @@
+-- Every successful calculation, so that results can be looked up later, e.g. by order reference.
+-- request, packs and pack_sizes (the normalized pack set) hold JSON; duration_us is how long the
+-- calculation itself took.
+
+CREATE TABLE calculations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tenant TEXT NOT NULL DEFAULT 'default',
+	created_at TEXT NOT NULL,
+	request_id TEXT NOT NULL DEFAULT '',
+	reference TEXT NOT NULL DEFAULT '',
+	pack_set TEXT NOT NULL,
+	pack_set_version INTEGER NOT NULL DEFAULT 0,
+	quantity INTEGER NOT NULL,
+	request TEXT NOT NULL,
+	packs TEXT NOT NULL,
+	pack_sizes TEXT NOT NULL,
+	shipped INTEGER NOT NULL,
+	overage INTEGER NOT NULL,
+	duration_us INTEGER NOT NULL
+);
+
+CREATE INDEX ix_calculations_tenant_created_at ON calculations(tenant, created_at);
+CREATE INDEX ix_calculations_tenant_reference ON calculations(tenant, reference);
@@ -0,0 +1,16 @@
+-- Responses of requests sent with an Idempotency-Key header, so that retries can be replayed.
+-- fingerprint identifies the request the key was first used with; status is 0 while that request
+-- is still being processed.
+
+CREATE TABLE idempotency_keys (
+	tenant TEXT NOT NULL DEFAULT 'default',
+	idempotency_key TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	status INTEGER NOT NULL DEFAULT 0,
+	content_type TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	PRIMARY KEY (tenant, idempotency_key)
+);
+
+CREATE INDEX ix_idempotency_keys_created_at ON idempotency_keys(created_at);
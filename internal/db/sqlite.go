@@ -13,8 +13,11 @@ import (
 )
 
 var (
-	mu     sync.RWMutex
-	dbConn *sql.DB
+	mu      sync.RWMutex
+	dbConn  *sql.DB
+	dialect = DialectSQLite
+	// sqlitePath is the file behind dbConn when the dialect is SQLite.
+	sqlitePath string
 )
 
 // InitSQLite opens a global SQLite connection.
@@ -30,10 +33,28 @@ func InitSQLite(ctx context.Context, dbPath string) error {
 		return fmt.Errorf("create db dir: %w", err)
 	}
 
-	dsn := sqliteDSN(dbPath)
-	conn, err := sql.Open("sqlite", dsn)
+	conn, err := openSQLite(ctx, dbPath)
 	if err != nil {
-		return fmt.Errorf("open sqlite: %w", err)
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if dbConn != nil {
+		_ = conn.Close()
+		return nil
+	}
+	dbConn = conn
+	dialect = DialectSQLite
+	sqlitePath = dbPath
+	return nil
+}
+
+// openSQLite opens and pings the SQLite file at dbPath.
+func openSQLite(ctx context.Context, dbPath string) (*sql.DB, error) {
+	conn, err := sql.Open("sqlite", sqliteDSN(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
 
 	conn.SetMaxOpenConns(1)
@@ -44,30 +65,23 @@ func InitSQLite(ctx context.Context, dbPath string) error {
 	defer cancel()
 	if err := conn.PingContext(pingCtx); err != nil {
 		_ = conn.Close()
-		return fmt.Errorf("ping sqlite: %w", err)
+		return nil, fmt.Errorf("ping sqlite: %w", err)
 	}
-
-	mu.Lock()
-	defer mu.Unlock()
-	if dbConn != nil {
-		_ = conn.Close()
-		return nil
-	}
-	dbConn = conn
-	return nil
+	return conn, nil
 }
 
-// DB returns the global SQLite connection.
+// DB returns the global database connection.
 func DB() (*sql.DB, error) {
 	mu.RLock()
 	defer mu.RUnlock()
 	if dbConn == nil {
-		return nil, fmt.Errorf("sqlite not initialized")
+		return nil, fmt.Errorf("database not initialized")
 	}
 	return dbConn, nil
 }
 
-func CloseSQLite() error {
+// Close closes the global database connection, whichever driver opened it.
+func Close() error {
 	mu.Lock()
 	defer mu.Unlock()
 	if dbConn == nil {
@@ -75,6 +89,8 @@ func CloseSQLite() error {
 	}
 	err := dbConn.Close()
 	dbConn = nil
+	dialect = DialectSQLite
+	sqlitePath = ""
 	return err
 }
 
@@ -0,0 +1,45 @@
+// Package gtin validates GS1 Global Trade Item Numbers: GTIN-8, GTIN-12 (UPC-A), GTIN-13 (EAN-13)
+// and GTIN-14.
+package gtin
+
+import "errors"
+
+var (
+	ErrInvalidLength     = errors.New("gtin must have 8, 12, 13 or 14 digits")
+	ErrInvalidCharacter  = errors.New("gtin must contain digits only")
+	ErrInvalidCheckDigit = errors.New("gtin check digit is invalid")
+)
+
+// Validate reports whether code is a well-formed GTIN with a correct check digit.
+func Validate(code string) error {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return ErrInvalidLength
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return ErrInvalidCharacter
+		}
+	}
+
+	last := len(code) - 1
+	if CheckDigit(code[:last]) != code[last] {
+		return ErrInvalidCheckDigit
+	}
+	return nil
+}
+
+// CheckDigit computes the GS1 check digit for payload, the GTIN without its last digit. Digits are
+// weighted 3, 1, 3, ... starting from the right. payload must contain digits only.
+func CheckDigit(payload string) byte {
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		d := int(payload[i] - '0')
+		if (len(payload)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,40 @@
+package gtin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		code string
+		want error
+	}{
+		{"96385074", nil},       // GTIN-8
+		{"036000291452", nil},   // UPC-A
+		{"4006381333931", nil},  // EAN-13
+		{"5901234123457", nil},  // EAN-13
+		{"10012345678902", nil}, // GTIN-14
+		{"4006381333932", ErrInvalidCheckDigit},
+		{"036000291453", ErrInvalidCheckDigit},
+		{"400638133393", ErrInvalidCheckDigit},
+		{"12345", ErrInvalidLength},
+		{"", ErrInvalidLength},
+		{"400638133393X", ErrInvalidCharacter},
+		{"4006 81333931", ErrInvalidCharacter},
+	}
+	for _, tc := range cases {
+		if err := Validate(tc.code); !errors.Is(err, tc.want) {
+			t.Errorf("Validate(%q) = %v, want %v", tc.code, err, tc.want)
+		}
+	}
+}
+
+func TestCheckDigit(t *testing.T) {
+	if got := CheckDigit("400638133393"); got != '1' {
+		t.Fatalf("CheckDigit = %c, want 1", got)
+	}
+	if got := CheckDigit("0000000"); got != '0' {
+		t.Fatalf("CheckDigit = %c, want 0", got)
+	}
+}
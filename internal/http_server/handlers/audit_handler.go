@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+const maxAuditLimit = 1000
+
+var auditActions = map[string]bool{
+	models.AuditActionCreate:  true,
+	models.AuditActionUpdate:  true,
+	models.AuditActionDelete:  true,
+	models.AuditActionReset:   true,
+	models.AuditActionRestore: true,
+	models.AuditActionReplace: true,
+	models.AuditActionImport:  true,
+}
+
+// ListAuditHandler lists audit entries, newest first. Supported query parameters:
+// pack_set, action, actor, request_id, from and to (RFC 3339, to is exclusive) and limit.
+func ListAuditHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.AuditFilter{
+		PackSet:   q.Get("pack_set"),
+		Action:    q.Get("action"),
+		Actor:     q.Get("actor"),
+		RequestID: q.Get("request_id"),
+	}
+
+	if filter.Action != "" && !auditActions[filter.Action] {
+		response.WriteError(w, http.StatusBadRequest, "invalid action")
+		return
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, p.name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*p.dst = t
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxAuditLimit {
+			response.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := repository.Audit().List(r.Context(), filter)
+	if err != nil {
+		log.Error("error listing audit entries", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.ListAuditResponse{Entries: entries})
+}
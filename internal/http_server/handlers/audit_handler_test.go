@@ -0,0 +1,172 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+type fakeAuditRepo struct {
+	listFn func(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+func (f *fakeAuditRepo) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	return f.listFn(ctx, filter)
+}
+
+func TestListAuditHandler(t *testing.T) {
+	orig := repository.Audit()
+	t.Cleanup(func() {
+		repository.SetAuditRepository(orig)
+	})
+
+	var gotFilter models.AuditFilter
+	fake := &fakeAuditRepo{
+		listFn: func(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+			_ = ctx
+			gotFilter = filter
+			id := int64(6)
+			return []models.AuditEntry{{
+				ID:         1,
+				CreatedAt:  time.Date(2026, 10, 13, 9, 30, 0, 0, time.UTC),
+				Actor:      "alice",
+				RequestID:  "host/abc-000001",
+				Action:     models.AuditActionDelete,
+				PackSet:    "default",
+				PackSizeID: &id,
+				Before:     json.RawMessage(`{"id":6,"size":1000}`),
+				After:      json.RawMessage(`null`),
+			}}, nil
+		},
+	}
+	repository.SetAuditRepository(fake)
+
+	h := http_server.NewHTTPHandler()
+
+	t.Run("list with filters", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/audit?pack_set=default&action=delete&actor=alice&from=2026-10-13T00:00:00Z&to=2026-10-14T00:00:00Z&limit=10", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"entries":[{"id":1,"created_at":"2026-10-13T09:30:00Z","actor":"alice","request_id":"host/abc-000001",
+			"action":"delete","pack_set":"default","pack_size_id":6,"before":{"id":6,"size":1000},"after":null}]}}`)
+
+		want := models.AuditFilter{
+			PackSet: "default",
+			Action:  models.AuditActionDelete,
+			Actor:   "alice",
+			From:    time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC),
+			To:      time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+			Limit:   10,
+		}
+		if gotFilter != want {
+			t.Fatalf("unexpected filter; got=%+v expected=%+v", gotFilter, want)
+		}
+	})
+
+	t.Run("invalid params -> 400", func(t *testing.T) {
+		cases := []struct {
+			query string
+			want  string
+		}{
+			{"action=explode", "invalid action"},
+			{"from=yesterday", "from must be an RFC 3339 timestamp"},
+			{"to=2026-13-01", "to must be an RFC 3339 timestamp"},
+			{"limit=0", "limit must be between 1 and 1000"},
+			{"limit=5000", "limit must be between 1 and 1000"},
+		}
+		for _, c := range cases {
+			rr := doJSON(t, h, http.MethodGet, "/api/audit?"+c.query, nil)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d body=%s", c.query, rr.Code, rr.Body.String())
+			}
+			mustJSONEqual(t, rr, `{"error":{"message":"`+c.want+`"}}`)
+		}
+	})
+
+	t.Run("internal error -> 500", func(t *testing.T) {
+		fake.listFn = func(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+			_, _ = ctx, filter
+			return nil, errors.New("db down")
+		}
+		rr := doJSON(t, h, http.MethodGet, "/api/audit", nil)
+		if rr.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"`+constants.InternalServerErrorMsg+`"}}`)
+	})
+}
+
+func TestRequestInfoReachesRepository(t *testing.T) {
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+
+	var gotActor, gotRequestID string
+	repository.SetPackSizesRepository(&fakePackSizesRepo{
+		createFn: func(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+			_ = set
+			gotActor = requestinfo.Actor(ctx)
+			gotRequestID = requestinfo.RequestID(ctx)
+			return &models.PackSize{ID: 1, Size: size}, nil
+		},
+	})
+
+	h := http_server.NewHTTPHandler()
+
+	t.Run("actor header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/packs/", jsonBody(t, models.CreatePackSizeRequest{Size: 250}))
+		req.Header.Set("X-Actor", "alice")
+		req.Header.Set("X-Request-Id", "req-42")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if gotActor != "alice" || gotRequestID != "req-42" {
+			t.Fatalf("unexpected request info; actor=%q request_id=%q", gotActor, gotRequestID)
+		}
+	})
+
+	t.Run("basic auth fallback", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/packs/", jsonBody(t, models.CreatePackSizeRequest{Size: 250}))
+		req.SetBasicAuth("bob", "secret")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if gotActor != "bob" || gotRequestID == "" {
+			t.Fatalf("unexpected request info; actor=%q request_id=%q", gotActor, gotRequestID)
+		}
+	})
+
+	t.Run("long actor is cut between characters", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/packs/", jsonBody(t, models.CreatePackSizeRequest{Size: 250}))
+		req.Header.Set("X-Actor", "a"+strings.Repeat("é", 200))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if want := "a" + strings.Repeat("é", 199); gotActor != want {
+			t.Fatalf("actor = %q, want %q", gotActor, want)
+		}
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 250})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if gotActor != requestinfo.AnonymousActor {
+			t.Fatalf("expected anonymous actor, got %q", gotActor)
+		}
+	})
+}
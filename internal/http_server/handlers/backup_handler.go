@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+const (
+	maxRestoreBytes = 256 << 20
+
+	sqliteContentType = "application/vnd.sqlite3"
+)
+
+// BackupHandler downloads a consistent snapshot of the SQLite database.
+func BackupHandler(w http.ResponseWriter, r *http.Request) {
+	dir, err := os.MkdirTemp("", "packs-backup-")
+	if err != nil {
+		log.Error("error creating backup dir", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "backup.db")
+	if err := db.Backup(r.Context(), path); err != nil {
+		if errors.Is(err, db.ErrNotSQLite) {
+			response.WriteError(w, http.StatusNotImplemented, "backup is only supported with the sqlite driver")
+			return
+		}
+		log.Error("error backing up database", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Error("error opening backup", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		log.Error("error opening backup", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	name := fmt.Sprintf("packs-%s.db", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", sqliteContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, f); err != nil {
+		log.Error("error sending backup", "err", err)
+	}
+}
+
+// RestoreHandler replaces the SQLite database with the snapshot in the request body.
+func RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := os.CreateTemp("", "packs-restore-*.db")
+	if err != nil {
+		log.Error("error creating restore file", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = io.Copy(f, http.MaxBytesReader(w, r.Body, maxRestoreBytes))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.WriteError(w, http.StatusRequestEntityTooLarge, "snapshot is too large")
+			return
+		}
+		response.WriteError(w, http.StatusBadRequest, "error reading request body")
+		return
+	}
+
+	res, err := db.Restore(r.Context(), f.Name())
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotSQLite):
+			response.WriteError(w, http.StatusNotImplemented, "restore is only supported with the sqlite driver")
+		case errors.Is(err, db.ErrInvalidSnapshot):
+			response.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Error("error restoring database", "err", err)
+			response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		}
+		return
+	}
+
+	// Cached listings describe the database that was just replaced.
+	if c, ok := repository.PackSizes().(*repository.CachedPackSizesRepository); ok {
+		c.Invalidate("")
+	}
+	log.Info("database restored", "actor", requestinfo.Actor(r.Context()),
+		"snapshot_version", res.SnapshotVersion, "schema_version", res.SchemaVersion)
+
+	response.WriteSuccess(w, http.StatusOK, models.RestoreResponse{
+		SnapshotVersion: res.SnapshotVersion,
+		SchemaVersion:   res.SchemaVersion,
+	})
+}
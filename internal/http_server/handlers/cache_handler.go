@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+// CacheStatsHandler reports the hit/miss counters of the pack size listing cache.
+func CacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	var stats models.CacheStats
+	if c, ok := repository.PackSizes().(*repository.CachedPackSizesRepository); ok {
+		stats = c.Stats()
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.CacheStatsResponse{PackSizes: stats})
+}
@@ -0,0 +1,55 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+func TestCacheStatsHandler(t *testing.T) {
+	useMemoryRepositories(t)
+	orig := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(orig)
+	})
+
+	fake := &fakePackSizesRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
+			return []models.PackSize{{ID: 1, Size: 250}}, nil
+		},
+	}
+	h := http_server.NewHTTPHandler()
+
+	t.Run("disabled", func(t *testing.T) {
+		repository.SetPackSizesRepository(fake)
+
+		rr := doJSON(t, h, http.MethodGet, "/api/cache/stats", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"pack_sizes":{"enabled":false,"hits":0,"misses":0,"entries":0,"ttl_seconds":0}}}`)
+	})
+
+	t.Run("counts calculate lookups", func(t *testing.T) {
+		repository.SetPackSizesRepository(repository.NewCachedPackSizesRepository(fake, 5*time.Second))
+
+		for i := 0; i < 3; i++ {
+			rr := doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1})
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+			}
+		}
+
+		rr := doJSON(t, h, http.MethodGet, "/api/cache/stats", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"pack_sizes":{"enabled":true,"hits":2,"misses":1,"entries":1,"ttl_seconds":5}}}`)
+	})
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
@@ -18,6 +21,220 @@ func CalculateHandler(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
+
+	calc, ok := calculate(w, r, req)
+	if !ok {
+		return
+	}
+	calc, err := repository.Calculations().Record(r.Context(), *calc)
+	if err != nil {
+		log.Error("error storing calculation", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.CalculateResponse{Packs: calc.Packs, Version: calc.Version, CalculationID: calc.ID})
+}
+
+// calculate validates req and calculates the allocation. The returned calculation is not stored
+// yet; the caller records it in the calculation history. On failure it writes the error response
+// and returns false.
+func calculate(w http.ResponseWriter, r *http.Request, req models.CalculateRequest) (*models.Calculation, bool) {
+	if req.Quantity <= 0 {
+		response.WriteError(w, http.StatusBadRequest, "quantity must be > 0")
+		return nil, false
+	}
+	if req.Quantity > 50_000_000 {
+		response.WriteError(w, http.StatusBadRequest, "quantity too large")
+		return nil, false
+	}
+	if len(req.Reference) > maxReferenceLen {
+		response.WriteError(w, http.StatusBadRequest, "reference too long")
+		return nil, false
+	}
+	set, ok := calculationPackSet(w, r, req.PackSet, req.SKU)
+	if !ok {
+		return nil, false
+	}
+
+	if req.AsOf != "" && req.Version != 0 {
+		response.WriteError(w, http.StatusBadRequest, "specify either as_of or version, not both")
+		return nil, false
+	}
+	if req.InStockOnly && (req.AsOf != "" || req.Version != 0) {
+		response.WriteError(w, http.StatusBadRequest, "in_stock_only cannot be combined with as_of or version")
+		return nil, false
+	}
+	now := time.Now()
+	at := now
+	if req.AsOf != "" {
+		t, err := time.Parse(time.RFC3339, req.AsOf)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "as_of must be an RFC 3339 timestamp")
+			return nil, false
+		}
+		at = t
+	}
+
+	var (
+		packs   []models.PackSize
+		version int64
+		err     error
+	)
+	// History only covers the past; a future as_of uses the live pack sizes, which include the
+	// changes scheduled by then.
+	var v *models.PackSetVersion
+	if req.Version != 0 || at.Before(now) {
+		if v, ok = calculationPackSetVersion(w, r, set, at, req.Version); !ok {
+			return nil, false
+		}
+	}
+	if v != nil {
+		packs, version = v.Packs, v.Version
+		if req.Version != 0 {
+			// A version is replayed as it was when it was recorded, not with the sizes that
+			// have expired or started since.
+			at = v.CreatedAt
+		}
+	} else {
+		packs, err = repository.PackSizes().List(r.Context(), set)
+		if err != nil {
+			log.Error("error listing pack sizes for calculate", "err", err)
+			response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+			return nil, false
+		}
+	}
+	packs = repository.EffectivePackSizes(packs, at)
+
+	var stock map[int]int
+	if req.InStockOnly {
+		levels, err := repository.Inventory().Stock(r.Context(), set)
+		if err != nil {
+			log.Error("error listing stock levels for calculate", "err", err)
+			response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+			return nil, false
+		}
+		stock = make(map[int]int, len(levels))
+		for _, l := range levels {
+			stock[l.Size] = l.OnHand
+		}
+	}
+
+	start := time.Now()
+	var allocations []models.PackAllocation
+	if req.InStockOnly {
+		allocations, err = packcalc.CalculateInStock(req.Quantity, packs, stock)
+	} else {
+		allocations, err = packcalc.Calculate(req.Quantity, packs)
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		writeCalculateError(w, err)
+		return nil, false
+	}
+	allocations = withPackMetadata(allocations, packs)
+
+	calc, err := newCalculation(req, set, version, packs, allocations, elapsed)
+	if err != nil {
+		log.Error("error encoding calculation request", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return nil, false
+	}
+	return calc, true
+}
+
+// newCalculation builds the calculation history entry of a successful calculation.
+func newCalculation(req models.CalculateRequest, set string, version int64, packs []models.PackSize, allocations []models.PackAllocation, elapsed time.Duration) (*models.Calculation, error) {
+	request, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make([]int, len(packs))
+	for i, p := range packs {
+		sizes[i] = p.Size
+	}
+	slices.Sort(sizes)
+	shipped := 0
+	for _, a := range allocations {
+		shipped += a.Size * a.Count
+	}
+
+	return &models.Calculation{
+		Reference:      req.Reference,
+		PackSet:        set,
+		Version:        version,
+		Quantity:       req.Quantity,
+		Request:        request,
+		Packs:          allocations,
+		PackSizes:      sizes,
+		Shipped:        shipped,
+		Overage:        shipped - req.Quantity,
+		DurationMicros: elapsed.Microseconds(),
+	}, nil
+}
+
+// calculationPackSetVersion loads the historical pack set version selected by version, or the one
+// that was current at asOf when version is 0. It returns nil when asOf is given for a set without
+// any history, which means the live pack sizes apply. On failure it writes the error response and
+// returns false.
+func calculationPackSetVersion(w http.ResponseWriter, r *http.Request, set string, asOf time.Time, version int64) (*models.PackSetVersion, bool) {
+	var (
+		v   *models.PackSetVersion
+		err error
+	)
+	if version != 0 {
+		if version < 0 {
+			response.WriteError(w, http.StatusBadRequest, "invalid version")
+			return nil, false
+		}
+		v, err = repository.PackSetVersions().Get(r.Context(), set, version)
+	} else {
+		v, err = repository.PackSetVersions().AsOf(r.Context(), set, asOf)
+		if errors.Is(err, repository.ErrNotFound) {
+			// A set that never changed has no history; its live pack sizes are what was in effect.
+			history, listErr := repository.PackSetVersions().List(r.Context(), set)
+			if listErr != nil {
+				log.Error("error listing pack set versions for calculate", "err", listErr)
+				response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+				return nil, false
+			}
+			if len(history) == 0 {
+				return nil, true
+			}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.WriteError(w, http.StatusNotFound, "pack set version not found")
+			return nil, false
+		}
+		log.Error("error loading pack set version for calculate", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return nil, false
+	}
+	return v, true
+}
+
+const (
+	maxAmendBytes            = 64 << 10
+	maxPreviousAllocationLen = 100
+)
+
+func AmendCalculationHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.AmendRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAmendBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.WriteError(w, http.StatusRequestEntityTooLarge, "request body is too large")
+			return
+		}
+		response.WriteError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if len(req.Previous) > maxPreviousAllocationLen {
+		response.WriteError(w, http.StatusBadRequest, "previous allocation must have at most 100 entries")
+		return
+	}
 	if req.Quantity <= 0 {
 		response.WriteError(w, http.StatusBadRequest, "quantity must be > 0")
 		return
@@ -26,35 +243,84 @@ func CalculateHandler(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusBadRequest, "quantity too large")
 		return
 	}
+	set, ok := calculationPackSet(w, r, req.PackSet, req.SKU)
+	if !ok {
+		return
+	}
 
-	packs, err := repository.PackSizes().List(r.Context())
+	packs, err := repository.PackSizes().List(r.Context(), set)
 	if err != nil {
-		log.Error("error listing pack sizes for calculate", "err", err)
+		log.Error("error listing pack sizes for amend", "err", err)
 		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
 		return
 	}
+	packs = repository.EffectivePackSizes(packs, time.Now())
 
-	allocations, err := packcalc.Calculate(req.Quantity, packs)
+	amendment, err := packcalc.Amend(req.Previous, req.Quantity, packs)
 	if err != nil {
-		switch err {
-		case packcalc.ErrInvalidQuantity:
-			response.WriteError(w, http.StatusBadRequest, "quantity must be > 0")
-			return
-		case packcalc.ErrQuantityTooLarge:
-			response.WriteError(w, http.StatusBadRequest, "quantity too large")
-			return
-		case packcalc.ErrNoPackSizes:
-			response.WriteError(w, http.StatusBadRequest, "no pack sizes configured")
-			return
-		case packcalc.ErrInvalidPackSizes:
-			response.WriteError(w, http.StatusBadRequest, "invalid pack sizes configured")
-			return
-		default:
-			log.Error("error calculating pack allocation", "err", err)
-			response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
-			return
+		writeCalculateError(w, err)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.AmendResponse{
+		Packs:  withPackMetadata(amendment.Packs, packs),
+		Add:    withPackMetadata(amendment.Add, packs),
+		Remove: withPackMetadata(amendment.Remove, packs),
+	})
+}
+
+// calculationPackSet resolves the pack set a calculation uses: the product's set when a SKU is
+// given, otherwise the requested (or default) pack set. On failure it writes the error response
+// and returns false.
+func calculationPackSet(w http.ResponseWriter, r *http.Request, packSet, sku string) (string, bool) {
+	if sku != "" {
+		if packSet != "" {
+			response.WriteError(w, http.StatusBadRequest, "specify either sku or pack_set, not both")
+			return "", false
+		}
+		if !skuRe.MatchString(sku) {
+			response.WriteError(w, http.StatusBadRequest, "invalid sku")
+			return "", false
 		}
+		return productPackSet(w, r, sku)
 	}
 
-	response.WriteSuccess(w, http.StatusOK, models.CalculateResponse{Packs: allocations})
+	set, ok := resolvePackSet(packSet)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return "", false
+	}
+	return set, true
+}
+
+func writeCalculateError(w http.ResponseWriter, err error) {
+	switch err {
+	case packcalc.ErrInvalidQuantity:
+		response.WriteError(w, http.StatusBadRequest, "quantity must be > 0")
+	case packcalc.ErrQuantityTooLarge:
+		response.WriteError(w, http.StatusBadRequest, "quantity too large")
+	case packcalc.ErrNoPackSizes:
+		response.WriteError(w, http.StatusBadRequest, "no pack sizes configured")
+	case packcalc.ErrInvalidPackSizes:
+		response.WriteError(w, http.StatusBadRequest, "invalid pack sizes configured")
+	case packcalc.ErrInvalidAllocation:
+		response.WriteError(w, http.StatusBadRequest, "invalid previous allocation")
+	case packcalc.ErrInsufficientStock:
+		response.WriteError(w, http.StatusConflict, "not enough packs in stock")
+	default:
+		log.Error("error calculating pack allocation", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+	}
+}
+
+// withPackMetadata fills in the metadata of the pack sizes each allocation uses.
+func withPackMetadata(allocations []models.PackAllocation, packs []models.PackSize) []models.PackAllocation {
+	bySize := make(map[int]models.PackMetadata, len(packs))
+	for _, p := range packs {
+		bySize[p.Size] = p.PackMetadata
+	}
+	for i := range allocations {
+		allocations[i].PackMetadata = bySize[allocations[i].Size]
+	}
+	return allocations
 }
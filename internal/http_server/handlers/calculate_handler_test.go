@@ -2,9 +2,13 @@ package handlers_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
@@ -14,20 +18,27 @@ import (
 )
 
 func TestCalculateHandler(t *testing.T) {
+	useMemoryRepositories(t)
 	fake := &fakePackSizesRepo{
-		listFn: func(ctx context.Context) ([]models.PackSize, error) {
-			_ = ctx
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
 			return []models.PackSize{{ID: 1, Size: 250}, {ID: 2, Size: 500}}, nil
 		},
-		createFn: func(ctx context.Context, size int) (*models.PackSize, error) { _ = ctx; _ = size; return nil, nil },
-		updateFn: func(ctx context.Context, id int64, size int) (*models.PackSize, error) {
-			_ = ctx
+		createFn: func(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+			_, _, _ = ctx, set, size
+			return nil, nil
+		},
+		updateFn: func(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+			_, _ = ctx, set
 			_ = id
 			_ = size
 			return nil, nil
 		},
-		deleteFn: func(ctx context.Context, id int64) error { _ = ctx; _ = id; return nil },
-		resetFn:  func(ctx context.Context) ([]int, error) { _ = ctx; return nil, nil },
+		deleteFn: func(ctx context.Context, set string, id int64, ifVersion int64) error {
+			_, _, _ = ctx, set, id
+			return nil
+		},
+		resetFn: func(ctx context.Context, set string) ([]int, error) { _, _ = ctx, set; return nil, nil },
 	}
 	repository.SetPackSizesRepository(fake)
 
@@ -37,7 +48,7 @@ func TestCalculateHandler(t *testing.T) {
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
 	}
-	mustJSONEqual(t, rr, `{"data":{"packs":[{"size":250,"count":1}]}}`)
+	mustJSONEqual(t, rr, `{"data":{"packs":[{"size":250,"count":1}],"calculation_id":1}}`)
 }
 
 func TestCalculateHandler_InvalidQuantity(t *testing.T) {
@@ -77,19 +88,25 @@ func TestCalculateHandler_ErrorMappingFromPackcalc(t *testing.T) {
 	})
 
 	fakeRepo := &fakePackSizesRepo{
-		listFn: func(ctx context.Context) ([]models.PackSize, error) {
-			_ = ctx
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
 			return []models.PackSize{{ID: 1, Size: 250}}, nil
 		},
-		createFn: func(ctx context.Context, size int) (*models.PackSize, error) { _ = ctx; _ = size; return nil, nil },
-		updateFn: func(ctx context.Context, id int64, size int) (*models.PackSize, error) {
-			_ = ctx
+		createFn: func(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+			_, _, _ = ctx, set, size
+			return nil, nil
+		},
+		updateFn: func(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+			_, _ = ctx, set
 			_ = id
 			_ = size
 			return nil, nil
 		},
-		deleteFn: func(ctx context.Context, id int64) error { _ = ctx; _ = id; return nil },
-		resetFn:  func(ctx context.Context) ([]int, error) { _ = ctx; return nil, nil },
+		deleteFn: func(ctx context.Context, set string, id int64, ifVersion int64) error {
+			_, _, _ = ctx, set, id
+			return nil
+		},
+		resetFn: func(ctx context.Context, set string) ([]int, error) { _, _ = ctx, set; return nil, nil },
 	}
 	repository.SetPackSizesRepository(fakeRepo)
 
@@ -128,3 +145,204 @@ func TestCalculateHandler_ErrorMappingFromPackcalc(t *testing.T) {
 		})
 	}
 }
+
+func TestAmendCalculationHandler(t *testing.T) {
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+
+	fake := &fakePackSizesRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
+			return []models.PackSize{{ID: 1, Size: 250}, {ID: 2, Size: 500}, {ID: 3, Size: 1000}}, nil
+		},
+	}
+	repository.SetPackSizesRepository(fake)
+
+	h := http_server.NewHTTPHandler()
+
+	t.Run("ok", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/calculate/amend", models.AmendRequest{
+			Quantity: 1000,
+			Previous: []models.PackAllocation{{Size: 500, Count: 1}},
+		})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"packs":[{"size":500,"count":2}],"add":[{"size":500,"count":1}],"remove":[]}}`)
+	})
+
+	t.Run("invalid previous allocation -> 400", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/calculate/amend", models.AmendRequest{
+			Quantity: 1000,
+			Previous: []models.PackAllocation{{Size: -1, Count: 1}},
+		})
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"invalid previous allocation"}}`)
+	})
+
+	t.Run("invalid quantity -> 400", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/calculate/amend", models.AmendRequest{Quantity: 0})
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"quantity must be > 0"}}`)
+	})
+
+	t.Run("too many previous entries -> 400", func(t *testing.T) {
+		previous := make([]models.PackAllocation, 101)
+		for i := range previous {
+			previous[i] = models.PackAllocation{Size: i + 1, Count: 1}
+		}
+		rr := doJSON(t, h, http.MethodPost, "/api/calculate/amend", models.AmendRequest{Quantity: 1000, Previous: previous})
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"previous allocation must have at most 100 entries"}}`)
+	})
+
+	t.Run("body too large -> 413", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/calculate/amend",
+			strings.NewReader(`{"quantity":1,"previous":[`+strings.Repeat(`{"size":1,"count":1},`, 5000)+`]}`))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected 413, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"request body is too large"}}`)
+	})
+}
+
+func TestCalculateHandler_PackSet(t *testing.T) {
+	useMemoryRepositories(t)
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+
+	fake := &fakePackSizesRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_ = ctx
+			if set == "retail" {
+				return []models.PackSize{{ID: 1, Size: 300}}, nil
+			}
+			return []models.PackSize{{ID: 2, Size: 250}}, nil
+		},
+	}
+	repository.SetPackSizesRepository(fake)
+
+	h := http_server.NewHTTPHandler()
+
+	rr := doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1, PackSet: "retail"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	mustJSONEqual(t, rr, `{"data":{"packs":[{"size":300,"count":1}],"calculation_id":1}}`)
+
+	rr = doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	mustJSONEqual(t, rr, `{"data":{"packs":[{"size":250,"count":1}],"calculation_id":2}}`)
+
+	rr = doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1, PackSet: "no/such"})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	mustJSONEqual(t, rr, `{"error":{"message":"invalid pack set name"}}`)
+}
+
+func TestCalculateHandler_IncludesPackMetadata(t *testing.T) {
+	useMemoryRepositories(t)
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+	repository.SetPackSizesRepository(&fakePackSizesRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			return []models.PackSize{
+				{ID: 1, Size: 250, PackMetadata: models.PackMetadata{Label: "Small", GTIN: "96385074"}},
+				{ID: 2, Size: 500, PackMetadata: models.PackMetadata{
+					Label:      "Large",
+					Dimensions: &models.PackDimensions{LengthMM: 400, WidthMM: 300, HeightMM: 200},
+				}},
+			}, nil
+		},
+	})
+
+	h := http_server.NewHTTPHandler()
+
+	rr := doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 751})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	mustJSONEqual(t, rr, `{"data":{"packs":[
+		{"size":500,"count":2,"label":"Large","dimensions":{"length_mm":400,"width_mm":300,"height_mm":200}}],"calculation_id":1}}`)
+
+	rr = doJSON(t, h, http.MethodPost, "/api/calculate/amend", models.AmendRequest{
+		Quantity: 251,
+		Previous: []models.PackAllocation{{Size: 250, Count: 1}},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	mustJSONEqual(t, rr, `{"data":{"packs":[{"size":250,"count":2,"label":"Small","gtin":"96385074"}],
+		"add":[{"size":250,"count":1,"label":"Small","gtin":"96385074"}],"remove":[]}}`)
+}
+
+func TestCalculateHandler_ScheduledPackSizes(t *testing.T) {
+	useMemoryRepositories(t)
+	h := http_server.NewHTTPHandler()
+
+	// From the switch date on, the 2000-pack becomes a 2500-pack.
+	switchAt := time.Now().UTC().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	for _, req := range []models.CreatePackSizeRequest{
+		{Size: 250},
+		{Size: 2000, EffectivePeriod: models.EffectivePeriod{EffectiveTo: &switchAt}},
+		{Size: 2500, EffectivePeriod: models.EffectivePeriod{EffectiveFrom: &switchAt}},
+	} {
+		if rr := doJSON(t, h, http.MethodPost, "/api/packs/", req); rr.Code != http.StatusCreated {
+			t.Fatalf("create %d: expected 201, got %d body=%s", req.Size, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 2500})
+	mustJSONEqual(t, rr, `{"data":{"packs":[{"size":2000,"count":1},{"size":250,"count":2}],"calculation_id":1}}`)
+
+	rr = doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{
+		Quantity: 2500, AsOf: switchAt.Format(time.RFC3339),
+	})
+	mustJSONEqual(t, rr, `{"data":{"packs":[{"size":2500,"count":1}],"calculation_id":2}}`)
+
+	rr = doJSON(t, h, http.MethodGet, "/api/packs/upcoming", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upcoming: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var upcoming struct {
+		Data models.ListPackSizeChangesResponse
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &upcoming); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	changes := upcoming.Data.Changes
+	if len(changes) != 2 ||
+		changes[0].Change != models.PackSizeChangeEnd || changes[0].Pack.Size != 2000 ||
+		changes[1].Change != models.PackSizeChangeStart || changes[1].Pack.Size != 2500 ||
+		!changes[1].EffectiveAt.Equal(switchAt) {
+		t.Fatalf("changes = %+v", changes)
+	}
+
+	rr = doJSON(t, h, http.MethodGet, "/api/packs/upcoming?until="+switchAt.Format(time.RFC3339), nil)
+	mustJSONEqual(t, rr, `{"data":{"changes":[]}}`)
+
+	rr = doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{
+		Size: 5000, EffectivePeriod: models.EffectivePeriod{EffectiveFrom: &switchAt, EffectiveTo: &switchAt},
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty period: expected 400, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	mustJSONEqual(t, rr, `{"error":{"message":"effective_to must be after effective_from"}}`)
+}
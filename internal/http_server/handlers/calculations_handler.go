@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxCalculationsLimit = 1000
+	maxReferenceLen      = 100
+)
+
+// ListCalculationsHandler lists stored calculations, newest first. Supported query parameters:
+// reference, from and to (RFC 3339, to is exclusive) and limit.
+func ListCalculationsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.CalculationFilter{Reference: q.Get("reference")}
+
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, p.name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*p.dst = t
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxCalculationsLimit {
+			response.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	calculations, err := repository.Calculations().List(r.Context(), filter)
+	if err != nil {
+		log.Error("error listing calculations", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.ListCalculationsResponse{Calculations: calculations})
+}
+
+func GetCalculationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	c, err := repository.Calculations().Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.WriteError(w, http.StatusNotFound, "calculation not found")
+			return
+		}
+		log.Error("error getting calculation", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, c)
+}
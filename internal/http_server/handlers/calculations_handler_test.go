@@ -0,0 +1,129 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func TestCalculationHistory(t *testing.T) {
+	useMemoryRepositories(t)
+	h := http_server.NewHTTPHandler()
+
+	for _, size := range []int{250, 500, 1000} {
+		if rr := doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: size}); rr.Code != http.StatusCreated {
+			t.Fatalf("create %d: expected 201, got %d body=%s", size, rr.Code, rr.Body.String())
+		}
+	}
+
+	calculate := func(req models.CalculateRequest) models.CalculateResponse {
+		t.Helper()
+		rr := doJSON(t, h, http.MethodPost, "/api/calculate", req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("calculate: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.CalculateResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Data
+	}
+	first := calculate(models.CalculateRequest{Quantity: 251, Reference: "PO-1"})
+	second := calculate(models.CalculateRequest{Quantity: 1})
+
+	list := func(query string) models.ListCalculationsResponse {
+		t.Helper()
+		rr := doJSON(t, h, http.MethodGet, "/api/calculations"+query, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("list: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.ListCalculationsResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Data
+	}
+
+	t.Run("list newest first", func(t *testing.T) {
+		calcs := list("").Calculations
+		if len(calcs) != 2 || calcs[0].ID != second.CalculationID || calcs[1].ID != first.CalculationID {
+			t.Fatalf("calculations = %+v", calcs)
+		}
+	})
+
+	t.Run("filter by reference", func(t *testing.T) {
+		calcs := list("?reference=PO-1").Calculations
+		if len(calcs) != 1 {
+			t.Fatalf("calculations = %+v", calcs)
+		}
+		c := calcs[0]
+		if c.Quantity != 251 || c.Shipped != 500 || c.Overage != 249 || c.PackSet != "default" {
+			t.Fatalf("calculation = %+v", c)
+		}
+		if len(c.PackSizes) != 3 || c.PackSizes[0] != 250 || len(c.Packs) != 1 || c.Packs[0].Size != 500 {
+			t.Fatalf("calculation = %+v", c)
+		}
+		if c.RequestID == "" || c.CreatedAt.IsZero() || c.DurationMicros < 0 {
+			t.Fatalf("calculation = %+v", c)
+		}
+	})
+
+	t.Run("filter by date", func(t *testing.T) {
+		if calcs := list("?to=2000-01-01T00:00:00Z").Calculations; len(calcs) != 0 {
+			t.Fatalf("calculations = %+v", calcs)
+		}
+		if calcs := list("?from=2000-01-01T00:00:00Z&limit=1").Calculations; len(calcs) != 1 {
+			t.Fatalf("calculations = %+v", calcs)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/calculations/"+strconv.FormatInt(first.CalculationID, 10), nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.Calculation `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Data.Reference != "PO-1" {
+			t.Fatalf("calculation = %+v", resp.Data)
+		}
+		var req models.CalculateRequest
+		if err := json.Unmarshal(resp.Data.Request, &req); err != nil || req.Quantity != 251 || req.Reference != "PO-1" {
+			t.Fatalf("request = %s, %v", resp.Data.Request, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			method, path string
+			body         any
+			status       int
+			want         string
+		}{
+			{http.MethodGet, "/api/calculations/99", nil, http.StatusNotFound, `{"error":{"message":"calculation not found"}}`},
+			{http.MethodGet, "/api/calculations/abc", nil, http.StatusBadRequest, `{"error":{"message":"invalid id"}}`},
+			{http.MethodGet, "/api/calculations?from=yesterday", nil, http.StatusBadRequest, `{"error":{"message":"from must be an RFC 3339 timestamp"}}`},
+			{http.MethodGet, "/api/calculations?limit=0", nil, http.StatusBadRequest, `{"error":{"message":"limit must be between 1 and 1000"}}`},
+			{http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1, Reference: string(make([]byte, 101))}, http.StatusBadRequest, `{"error":{"message":"reference too long"}}`},
+		}
+		for _, tc := range cases {
+			rr := doJSON(t, h, tc.method, tc.path, tc.body)
+			if rr.Code != tc.status {
+				t.Fatalf("%s %s: expected %d, got %d body=%s", tc.method, tc.path, tc.status, rr.Code, rr.Body.String())
+			}
+			mustJSONEqual(t, rr, tc.want)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+// requireIfMatch makes If-Match mandatory on pack size PUT and DELETE requests.
+var requireIfMatch bool
+
+// SetRequireIfMatch configures whether pack size updates and deletes must send If-Match.
+func SetRequireIfMatch(v bool) {
+	requireIfMatch = v
+}
+
+func packSizeETag(p models.PackSize) string {
+	return strconv.Quote(strconv.FormatInt(p.Version, 10))
+}
+
+// packListETag identifies a list of pack sizes; it changes whenever any of them changes.
+func packListETag(packs []models.PackSize) string {
+	h := sha256.New()
+	for _, p := range packs {
+		_, _ = fmt.Fprintf(h, "%d:%d:%d;", p.ID, p.Size, p.Version)
+	}
+	return strconv.Quote(hex.EncodeToString(h.Sum(nil))[:16])
+}
+
+// ifMatchVersions reads the pack size versions listed in the If-Match header. It returns nil when
+// the header is absent or "*" (any version), and ok=false when it cannot match any version.
+func ifMatchVersions(r *http.Request) (versions []int64, present, ok bool) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" {
+		return nil, false, true
+	}
+	for _, tag := range strings.Split(v, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true, true
+		}
+		// Weak ETags never match under If-Match (RFC 9110, strong comparison).
+		tag, err := strconv.Unquote(tag)
+		if err != nil {
+			continue
+		}
+		version, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil || version <= 0 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, true, len(versions) > 0
+}
+
+// notModified answers a conditional GET with 304 when If-None-Match matches etag.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	for _, v := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch resolves the version a PUT or DELETE of pack size id is conditional on, writing
+// 428 or 412 when the request cannot proceed.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, set string, id int64) (int64, bool) {
+	versions, present, ok := ifMatchVersions(r)
+	if !present && requireIfMatch {
+		writePreconditionRequired(w)
+		return 0, false
+	}
+	if !ok {
+		writePreconditionFailed(w)
+		return 0, false
+	}
+	switch len(versions) {
+	case 0:
+		return 0, true
+	case 1:
+		return versions[0], true
+	}
+
+	// A list matches when any of its tags is the current version. That version is what the
+	// repository checks, so a change made since this lookup still fails the request.
+	current, err := repository.PackSizes().Get(r.Context(), set, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Let the update or delete report the missing pack size.
+		return versions[0], true
+	}
+	if err != nil {
+		log.Error("error getting pack size for If-Match", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return 0, false
+	}
+	if !slices.Contains(versions, current.Version) {
+		writePreconditionFailed(w)
+		return 0, false
+	}
+	return current.Version, true
+}
+
+func writePreconditionRequired(w http.ResponseWriter) {
+	response.WriteError(w, http.StatusPreconditionRequired, "If-Match header is required")
+}
+
+func writePreconditionFailed(w http.ResponseWriter) {
+	response.WriteError(w, http.StatusPreconditionFailed, "pack size was modified; reload and try again")
+}
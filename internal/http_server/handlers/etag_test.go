@@ -0,0 +1,189 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/handlers"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+func TestPackSizeETags(t *testing.T) {
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+		handlers.SetRequireIfMatch(false)
+	})
+
+	current := models.PackSize{ID: 1, Size: 250, Version: 3}
+	var gotIfVersion int64
+	repository.SetPackSizesRepository(&fakePackSizesRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
+			return []models.PackSize{current}, nil
+		},
+		getFn: func(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+			_, _ = ctx, set
+			if id != current.ID {
+				return nil, repository.ErrNotFound
+			}
+			p := current
+			return &p, nil
+		},
+		updateFn: func(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+			_, _ = ctx, set
+			gotIfVersion = ifVersion
+			if ifVersion != 0 && ifVersion != current.Version {
+				return nil, repository.ErrVersionMismatch
+			}
+			return &models.PackSize{ID: id, Size: size, Version: current.Version + 1}, nil
+		},
+		deleteFn: func(ctx context.Context, set string, id int64, ifVersion int64) error {
+			_, _ = ctx, set
+			gotIfVersion = ifVersion
+			if id != current.ID {
+				return repository.ErrNotFound
+			}
+			if ifVersion != 0 && ifVersion != current.Version {
+				return repository.ErrVersionMismatch
+			}
+			return nil
+		},
+	})
+
+	h := http_server.NewHTTPHandler()
+
+	do := func(t *testing.T, method, path string, body any, headers map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("list returns etag and honours If-None-Match", func(t *testing.T) {
+		rr := do(t, http.MethodGet, "/api/packs/", nil, nil)
+		etag := rr.Header().Get("ETag")
+		if rr.Code != http.StatusOK || etag == "" {
+			t.Fatalf("expected 200 with ETag, got %d etag=%q", rr.Code, etag)
+		}
+		rr = do(t, http.MethodGet, "/api/packs/", nil, map[string]string{"If-None-Match": etag})
+		if rr.Code != http.StatusNotModified {
+			t.Fatalf("expected 304, got %d", rr.Code)
+		}
+	})
+
+	t.Run("get returns version etag", func(t *testing.T) {
+		rr := do(t, http.MethodGet, "/api/packs/1", nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if got := rr.Header().Get("ETag"); got != `"3"` {
+			t.Fatalf("unexpected ETag %q", got)
+		}
+		mustJSONEqual(t, rr, `{"data":{"id":1,"size":250,"version":3}}`)
+	})
+
+	t.Run("get not found -> 404", func(t *testing.T) {
+		rr := do(t, http.MethodGet, "/api/packs/2", nil, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", rr.Code)
+		}
+	})
+
+	t.Run("update with matching If-Match", func(t *testing.T) {
+		rr := do(t, http.MethodPut, "/api/packs/1", map[string]any{"size": 300}, map[string]string{"If-Match": `"3"`})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if gotIfVersion != 3 {
+			t.Fatalf("expected repository to check version 3, got %d", gotIfVersion)
+		}
+		if got := rr.Header().Get("ETag"); got != `"4"` {
+			t.Fatalf("unexpected ETag %q", got)
+		}
+	})
+
+	t.Run("update with stale If-Match -> 412", func(t *testing.T) {
+		rr := do(t, http.MethodPut, "/api/packs/1", map[string]any{"size": 300}, map[string]string{"If-Match": `"2"`})
+		if rr.Code != http.StatusPreconditionFailed {
+			t.Fatalf("expected 412, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"pack size was modified; reload and try again"}}`)
+	})
+
+	t.Run("If-Match list matches any strong tag", func(t *testing.T) {
+		for _, v := range []string{`"3", "4"`, `"2","3"`, `W/"4", "3"`} {
+			gotIfVersion = -1
+			rr := do(t, http.MethodPut, "/api/packs/1", map[string]any{"size": 300}, map[string]string{"If-Match": v})
+			if rr.Code != http.StatusOK {
+				t.Fatalf("If-Match %s: expected 200, got %d body=%s", v, rr.Code, rr.Body.String())
+			}
+			if gotIfVersion != 3 {
+				t.Fatalf("If-Match %s: expected repository to check version 3, got %d", v, gotIfVersion)
+			}
+		}
+	})
+
+	t.Run("If-Match list without the current version -> 412", func(t *testing.T) {
+		for _, v := range []string{`"1", "2"`, `W/"3", "4"`} {
+			rr := do(t, http.MethodDelete, "/api/packs/1", nil, map[string]string{"If-Match": v})
+			if rr.Code != http.StatusPreconditionFailed {
+				t.Fatalf("If-Match %s: expected 412, got %d body=%s", v, rr.Code, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("If-Match list for a missing pack size -> 404", func(t *testing.T) {
+		rr := do(t, http.MethodDelete, "/api/packs/2", nil, map[string]string{"If-Match": `"3", "4"`})
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("malformed If-Match -> 412", func(t *testing.T) {
+		for _, v := range []string{`W/"3"`, `3`, `"abc"`} {
+			rr := do(t, http.MethodDelete, "/api/packs/1", nil, map[string]string{"If-Match": v})
+			if rr.Code != http.StatusPreconditionFailed {
+				t.Fatalf("If-Match %s: expected 412, got %d", v, rr.Code)
+			}
+		}
+	})
+
+	t.Run("wildcard and missing If-Match skip the check", func(t *testing.T) {
+		for _, headers := range []map[string]string{{"If-Match": "*"}, nil} {
+			gotIfVersion = -1
+			rr := do(t, http.MethodDelete, "/api/packs/1", nil, headers)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+			}
+			if gotIfVersion != 0 {
+				t.Fatalf("expected unconditional delete, got version %d", gotIfVersion)
+			}
+		}
+	})
+
+	t.Run("required If-Match missing -> 428", func(t *testing.T) {
+		handlers.SetRequireIfMatch(true)
+		t.Cleanup(func() { handlers.SetRequireIfMatch(false) })
+
+		rr := do(t, http.MethodPut, "/api/packs/1", map[string]any{"size": 300}, nil)
+		if rr.Code != http.StatusPreconditionRequired {
+			t.Fatalf("expected 428, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"If-Match header is required"}}`)
+
+		rr = do(t, http.MethodDelete, "/api/packs/1", nil, map[string]string{"If-Match": `"3"`})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+}
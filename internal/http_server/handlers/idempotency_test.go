@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	useMemoryRepositories(t)
+	h := http_server.NewHTTPHandler()
+	key := func(k string) map[string]string { return map[string]string{"Idempotency-Key": k} }
+
+	t.Run("retried create is replayed", func(t *testing.T) {
+		first := doAs(t, h, key("create-1"), http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 250})
+		if first.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", first.Code, first.Body.String())
+		}
+		retry := doAs(t, h, key("create-1"), http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 250})
+		if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+			t.Fatalf("replay = %d %s, want %d %s", retry.Code, retry.Body.String(), first.Code, first.Body.String())
+		}
+		if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Header().Get("Content-Type") != "application/json" ||
+			retry.Header().Get("ETag") == "" || retry.Header().Get("ETag") != first.Header().Get("ETag") {
+			t.Fatalf("replay headers = %v", retry.Header())
+		}
+
+		packs, err := repository.PackSizes().List(context.Background(), repository.DefaultPackSet)
+		if err != nil || len(packs) != 1 {
+			t.Fatalf("pack sizes = %+v, %v", packs, err)
+		}
+	})
+
+	t.Run("errors are replayed too", func(t *testing.T) {
+		first := doAs(t, h, key("create-2"), http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 250})
+		if first.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d body=%s", first.Code, first.Body.String())
+		}
+		retry := doAs(t, h, key("create-2"), http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 250})
+		if retry.Code != http.StatusConflict || retry.Header().Get("Idempotent-Replayed") != "true" {
+			t.Fatalf("replay = %d %v", retry.Code, retry.Header())
+		}
+	})
+
+	t.Run("key reused for a different request", func(t *testing.T) {
+		rr := doAs(t, h, key("create-1"), http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 500})
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected 422, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"Idempotency-Key was already used for a different request"}}`)
+
+		rr = doAs(t, h, key("create-1"), http.MethodPost, "/api/packs/reset", nil)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("other path: expected 422, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("keys are per tenant", func(t *testing.T) {
+		rr := doAs(t, h, map[string]string{"Idempotency-Key": "create-1", "X-Tenant": "acme"}, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 500})
+		if rr.Code != http.StatusCreated || rr.Header().Get("Idempotent-Replayed") != "" {
+			t.Fatalf("expected a fresh 201, got %d %v body=%s", rr.Code, rr.Header(), rr.Body.String())
+		}
+	})
+
+	t.Run("order creation", func(t *testing.T) {
+		first := doAs(t, h, key("order-1"), http.MethodPost, "/api/orders/", models.CreateOrderRequest{Quantity: 1, Reference: "PO-1"})
+		if first.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", first.Code, first.Body.String())
+		}
+		retry := doAs(t, h, key("order-1"), http.MethodPost, "/api/orders/", models.CreateOrderRequest{Quantity: 1, Reference: "PO-1"})
+		if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+			t.Fatalf("replay = %d %s", retry.Code, retry.Body.String())
+		}
+		orders, err := repository.Orders().List(context.Background(), models.OrderFilter{Reference: "PO-1"})
+		if err != nil || len(orders) != 1 {
+			t.Fatalf("orders = %+v, %v", orders, err)
+		}
+	})
+
+	t.Run("panicking request releases the key", func(t *testing.T) {
+		packs := repository.PackSizes()
+		repository.SetPackSizesRepository(&fakePackSizesRepo{
+			resetFn: func(ctx context.Context, set string) ([]int, error) { panic("boom") },
+		})
+		rr := doAs(t, h, key("reset-1"), http.MethodPost, "/api/packs/reset", nil)
+		repository.SetPackSizesRepository(packs)
+		if rr.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d body=%s", rr.Code, rr.Body.String())
+		}
+
+		retry := doAs(t, h, key("reset-1"), http.MethodPost, "/api/packs/reset", nil)
+		if retry.Code != http.StatusOK || retry.Header().Get("Idempotent-Replayed") != "" {
+			t.Fatalf("expected a fresh 200, got %d %v body=%s", retry.Code, retry.Header(), retry.Body.String())
+		}
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		rr := doAs(t, h, key(strings.Repeat("k", 256)), http.MethodPost, "/api/packs/reset", nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+const (
+	maxLedgerLimit = 1000
+	maxStockMove   = 1_000_000_000
+	maxNoteLen     = 200
+)
+
+var ledgerKinds = map[string]bool{
+	models.LedgerKindReceipt:    true,
+	models.LedgerKindAdjustment: true,
+	models.LedgerKindDeduction:  true,
+	models.LedgerKindRelease:    true,
+}
+
+// ListStockHandler lists the packs on hand of a pack set (query parameter pack_set). Every pack
+// size of the set is listed, as are sizes no longer in the set that still have packs on hand.
+func ListStockHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := resolvePackSet(r.URL.Query().Get("pack_set"))
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	packs, err := repository.PackSizes().List(r.Context(), set)
+	if err != nil {
+		log.Error("error listing pack sizes for stock", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	stock, err := repository.Inventory().Stock(r.Context(), set)
+	if err != nil {
+		log.Error("error listing stock levels", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	out := []models.StockLevel{}
+	for _, l := range stock {
+		if l.OnHand > 0 || hasPackSize(packs, l.Size) {
+			out = append(out, l)
+		}
+	}
+	for _, p := range packs {
+		if !slices.ContainsFunc(out, func(l models.StockLevel) bool { return l.Size == p.Size }) {
+			out = append(out, models.StockLevel{PackSet: set, Size: p.Size})
+		}
+	}
+	slices.SortFunc(out, func(a, b models.StockLevel) int { return a.Size - b.Size })
+
+	response.WriteSuccess(w, http.StatusOK, models.ListStockResponse{PackSet: set, Stock: out})
+}
+
+// ReceiveStockHandler adds packs delivered to the warehouse.
+func ReceiveStockHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ReceiveStockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.Quantity <= 0 || req.Quantity > maxStockMove {
+		response.WriteError(w, http.StatusBadRequest, "quantity must be between 1 and 1000000000")
+		return
+	}
+
+	moveStock(w, r, req.PackSet, req.Size, models.LedgerKindReceipt, req.Quantity, req.Note)
+}
+
+// AdjustStockHandler corrects the packs on hand, e.g. after a stock count or for damaged packs.
+func AdjustStockHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.AdjustStockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.Delta == 0 || req.Delta < -maxStockMove || req.Delta > maxStockMove {
+		response.WriteError(w, http.StatusBadRequest, "delta must be non-zero and between -1000000000 and 1000000000")
+		return
+	}
+
+	moveStock(w, r, req.PackSet, req.Size, models.LedgerKindAdjustment, req.Delta, req.Note)
+}
+
+// moveStock validates and records a receipt or adjustment and writes the response.
+func moveStock(w http.ResponseWriter, r *http.Request, packSet string, size int, kind string, quantity int, note string) {
+	set, ok := resolvePackSet(packSet)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+	if size <= 0 {
+		response.WriteError(w, http.StatusBadRequest, "size must be > 0")
+		return
+	}
+	if len(note) > maxNoteLen {
+		response.WriteError(w, http.StatusBadRequest, "note too long")
+		return
+	}
+
+	packs, err := repository.PackSizes().List(r.Context(), set)
+	if err != nil {
+		log.Error("error listing pack sizes for stock", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	if !hasPackSize(packs, size) {
+		response.WriteError(w, http.StatusBadRequest, "size is not a pack size of the pack set")
+		return
+	}
+
+	e, err := repository.Inventory().Move(r.Context(), set, size, kind, quantity, note)
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			response.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		log.Error("error moving stock", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusCreated, e)
+}
+
+// ListLedgerHandler lists inventory ledger entries, newest first. Supported query parameters:
+// pack_set, size, kind, order_id, from and to (RFC 3339, to is exclusive) and limit.
+func ListLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.LedgerFilter{PackSet: q.Get("pack_set"), Kind: q.Get("kind")}
+
+	if filter.Kind != "" && !ledgerKinds[filter.Kind] {
+		response.WriteError(w, http.StatusBadRequest, "invalid kind")
+		return
+	}
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 {
+			response.WriteError(w, http.StatusBadRequest, "size must be > 0")
+			return
+		}
+		filter.Size = size
+	}
+	if v := q.Get("order_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			response.WriteError(w, http.StatusBadRequest, "invalid order_id")
+			return
+		}
+		filter.OrderID = id
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, p.name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*p.dst = t
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLedgerLimit {
+			response.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := repository.Inventory().Ledger(r.Context(), filter)
+	if err != nil {
+		log.Error("error listing ledger entries", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.ListLedgerResponse{Entries: entries})
+}
+
+func hasPackSize(packs []models.PackSize, size int) bool {
+	return slices.ContainsFunc(packs, func(p models.PackSize) bool { return p.Size == size })
+}
@@ -0,0 +1,168 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func TestInventory(t *testing.T) {
+	useMemoryRepositories(t)
+	h := http_server.NewHTTPHandler()
+
+	for _, size := range []int{250, 500, 1000} {
+		if rr := doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: size}); rr.Code != http.StatusCreated {
+			t.Fatalf("create %d: expected 201, got %d body=%s", size, rr.Code, rr.Body.String())
+		}
+	}
+
+	stock := func(t *testing.T) map[int]int {
+		t.Helper()
+		rr := doJSON(t, h, http.MethodGet, "/api/inventory", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("stock: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.ListStockResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		out := map[int]int{}
+		for _, l := range resp.Data.Stock {
+			out[l.Size] = l.OnHand
+		}
+		return out
+	}
+
+	t.Run("every pack size is listed", func(t *testing.T) {
+		if got := stock(t); len(got) != 3 || got[250] != 0 || got[500] != 0 || got[1000] != 0 {
+			t.Fatalf("stock = %v", got)
+		}
+	})
+
+	t.Run("receipts and adjustments", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/inventory/receipts", models.ReceiveStockRequest{Size: 250, Quantity: 4, Note: "delivery"})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("receive: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.LedgerEntry `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if e := resp.Data; e.Kind != models.LedgerKindReceipt || e.Quantity != 4 || e.Balance != 4 || e.PackSet != "default" || e.RequestID == "" {
+			t.Fatalf("entry = %+v", e)
+		}
+		if rr := doJSON(t, h, http.MethodPost, "/api/inventory/receipts", models.ReceiveStockRequest{Size: 1000, Quantity: 1}); rr.Code != http.StatusCreated {
+			t.Fatalf("receive: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if rr := doJSON(t, h, http.MethodPost, "/api/inventory/adjustments", models.AdjustStockRequest{Size: 250, Delta: -1}); rr.Code != http.StatusCreated {
+			t.Fatalf("adjust: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if got := stock(t); got[250] != 3 || got[500] != 0 || got[1000] != 1 {
+			t.Fatalf("stock = %v", got)
+		}
+	})
+
+	t.Run("in stock only calculation", func(t *testing.T) {
+		// Without stock a single 500 pack ships 251; with only 250 and 1000 packs on hand the
+		// smallest shipment is two 250 packs.
+		rr := doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 251, InStockOnly: true})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("calculate: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.CalculateResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if p := resp.Data.Packs; len(p) != 1 || p[0].Size != 250 || p[0].Count != 2 {
+			t.Fatalf("packs = %+v", p)
+		}
+
+	})
+
+	t.Run("confirming an order deducts its packs", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/orders/", models.CreateOrderRequest{Quantity: 1001, InStockOnly: true})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("create order: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var order struct {
+			Data models.Order `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &order); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if order.Data.Shipped != 1250 {
+			t.Fatalf("order = %+v", order.Data)
+		}
+		if rr := doJSON(t, h, http.MethodPost, "/api/orders/1/status", models.UpdateOrderStatusRequest{Status: models.OrderStatusConfirmed}); rr.Code != http.StatusOK {
+			t.Fatalf("confirm: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if got := stock(t); got[250] != 2 || got[1000] != 0 {
+			t.Fatalf("stock = %v", got)
+		}
+
+		rr = doJSON(t, h, http.MethodGet, "/api/inventory/ledger?order_id=1", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("ledger: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.ListLedgerResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(resp.Data.Entries) != 2 {
+			t.Fatalf("entries = %+v", resp.Data.Entries)
+		}
+		for _, e := range resp.Data.Entries {
+			if e.Kind != models.LedgerKindDeduction || e.Quantity != -1 || e.OrderID == nil || *e.OrderID != 1 {
+				t.Fatalf("entry = %+v", e)
+			}
+		}
+	})
+
+	t.Run("confirming fails when a tracked size runs out", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/orders/", models.CreateOrderRequest{Quantity: 1000})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("create order: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		rr = doJSON(t, h, http.MethodPost, "/api/orders/2/status", models.UpdateOrderStatusRequest{Status: models.OrderStatusConfirmed})
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("confirm: expected 409, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"insufficient stock of size 1000"}}`)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			method, path string
+			body         any
+			status       int
+			want         string
+		}{
+			{http.MethodPost, "/api/inventory/receipts", models.ReceiveStockRequest{Size: 250}, http.StatusBadRequest, `{"error":{"message":"quantity must be between 1 and 1000000000"}}`},
+			{http.MethodPost, "/api/inventory/receipts", models.ReceiveStockRequest{Size: 300, Quantity: 1}, http.StatusBadRequest, `{"error":{"message":"size is not a pack size of the pack set"}}`},
+			{http.MethodPost, "/api/inventory/adjustments", models.AdjustStockRequest{Size: 250}, http.StatusBadRequest, `{"error":{"message":"delta must be non-zero and between -1000000000 and 1000000000"}}`},
+			{http.MethodPost, "/api/inventory/adjustments", models.AdjustStockRequest{Size: 500, Delta: -1}, http.StatusConflict, `{"error":{"message":"insufficient stock of size 500"}}`},
+			{http.MethodGet, "/api/inventory/ledger?kind=theft", nil, http.StatusBadRequest, `{"error":{"message":"invalid kind"}}`},
+			{http.MethodGet, "/api/inventory?pack_set=no%20spaces", nil, http.StatusBadRequest, `{"error":{"message":"invalid pack set name"}}`},
+			{http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 100_000, InStockOnly: true}, http.StatusConflict, `{"error":{"message":"not enough packs in stock"}}`},
+			{http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1, InStockOnly: true, Version: 1}, http.StatusBadRequest, `{"error":{"message":"in_stock_only cannot be combined with as_of or version"}}`},
+		}
+		for _, tc := range cases {
+			rr := doJSON(t, h, tc.method, tc.path, tc.body)
+			if rr.Code != tc.status {
+				t.Fatalf("%s %s: expected %d, got %d body=%s", tc.method, tc.path, tc.status, rr.Code, rr.Body.String())
+			}
+			mustJSONEqual(t, rr, tc.want)
+		}
+	})
+}
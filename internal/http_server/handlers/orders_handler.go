@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxOrdersLimit = 1000
+
+// ListOrdersHandler lists orders, newest first. Supported query parameters: status, reference and
+// limit.
+func ListOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.OrderFilter{Status: q.Get("status"), Reference: q.Get("reference")}
+
+	if filter.Status != "" && !repository.IsOrderStatus(filter.Status) {
+		response.WriteError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxOrdersLimit {
+			response.WriteError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	orders, err := repository.Orders().List(r.Context(), filter)
+	if err != nil {
+		log.Error("error listing orders", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.ListOrdersResponse{Orders: orders})
+}
+
+func GetOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+
+	o, err := repository.Orders().Get(r.Context(), id)
+	if err != nil {
+		writeOrderError(w, err, "error getting order")
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, o)
+}
+
+// CreateOrderHandler creates a draft order. Its allocation is calculated like POST /api/calculate
+// does and frozen with the order; the calculation is only recorded together with the order.
+func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	calc, ok := calculate(w, r, models.CalculateRequest{
+		Quantity: req.Quantity, PackSet: req.PackSet, SKU: req.SKU, Reference: req.Reference, InStockOnly: req.InStockOnly,
+	})
+	if !ok {
+		return
+	}
+
+	o, err := repository.Orders().Create(r.Context(), models.Order{
+		Reference: req.Reference,
+		PackSet:   calc.PackSet,
+		SKU:       req.SKU,
+		Quantity:  calc.Quantity,
+		Packs:     calc.Packs,
+		Shipped:   calc.Shipped,
+		Overage:   calc.Overage,
+	}, calc)
+	if err != nil {
+		log.Error("error creating order", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusCreated, o)
+}
+
+// UpdateOrderStatusHandler moves an order to the next status of its lifecycle.
+func UpdateOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+	var req models.UpdateOrderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if !repository.IsOrderStatus(req.Status) {
+		response.WriteError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+
+	o, err := repository.Orders().Transition(r.Context(), id, req.Status)
+	if err != nil {
+		writeOrderError(w, err, "error updating order status")
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, o)
+}
+
+func orderID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.WriteError(w, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeOrderError(w http.ResponseWriter, err error, logMsg string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		response.WriteError(w, http.StatusNotFound, "order not found")
+	case errors.Is(err, repository.ErrInvalidTransition), errors.Is(err, repository.ErrInsufficientStock):
+		response.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		log.Error(logMsg, "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+	}
+}
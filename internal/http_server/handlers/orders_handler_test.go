@@ -0,0 +1,163 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+func TestOrderLifecycle(t *testing.T) {
+	useMemoryRepositories(t)
+	h := http_server.NewHTTPHandler()
+
+	for _, size := range []int{250, 500, 1000} {
+		if rr := doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: size}); rr.Code != http.StatusCreated {
+			t.Fatalf("create %d: expected 201, got %d body=%s", size, rr.Code, rr.Body.String())
+		}
+	}
+
+	decodeOrder := func(t *testing.T, body []byte) models.Order {
+		t.Helper()
+		var resp struct {
+			Data models.Order `json:"data"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Data
+	}
+
+	rr := doJSON(t, h, http.MethodPost, "/api/orders/", models.CreateOrderRequest{Quantity: 251, Reference: "PO-1"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	order := decodeOrder(t, rr.Body.Bytes())
+	if order.Status != models.OrderStatusDraft || order.Shipped != 500 || order.Overage != 249 || order.PackSet != "default" {
+		t.Fatalf("order = %+v", order)
+	}
+	if len(order.Packs) != 1 || order.Packs[0] != (models.PackAllocation{Size: 500, Count: 1}) {
+		t.Fatalf("packs = %+v", order.Packs)
+	}
+	path := "/api/orders/" + strconv.FormatInt(order.ID, 10)
+
+	t.Run("allocation is frozen", func(t *testing.T) {
+		if rr := doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 251}); rr.Code != http.StatusCreated {
+			t.Fatalf("create pack size: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		rr := doJSON(t, h, http.MethodGet, path, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("get: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if got := decodeOrder(t, rr.Body.Bytes()); got.Packs[0].Size != 500 {
+			t.Fatalf("packs = %+v", got.Packs)
+		}
+	})
+
+	t.Run("allocation is in the calculation history", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/calculations/"+strconv.FormatInt(order.CalculationID, 10), nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("status transitions", func(t *testing.T) {
+		steps := []struct {
+			status     string
+			wantStatus int
+			want       string
+		}{
+			{models.OrderStatusPicked, http.StatusConflict, `{"error":{"message":"invalid order status transition from draft to picked"}}`},
+			{models.OrderStatusConfirmed, http.StatusOK, ""},
+			{models.OrderStatusPicked, http.StatusOK, ""},
+			{models.OrderStatusShipped, http.StatusOK, ""},
+			{models.OrderStatusCancelled, http.StatusConflict, `{"error":{"message":"invalid order status transition from shipped to cancelled"}}`},
+			{"lost", http.StatusBadRequest, `{"error":{"message":"invalid status"}}`},
+		}
+		for _, step := range steps {
+			rr := doJSON(t, h, http.MethodPost, path+"/status", models.UpdateOrderStatusRequest{Status: step.status})
+			if rr.Code != step.wantStatus {
+				t.Fatalf("%s: expected %d, got %d body=%s", step.status, step.wantStatus, rr.Code, rr.Body.String())
+			}
+			if step.want != "" {
+				mustJSONEqual(t, rr, step.want)
+			} else if got := decodeOrder(t, rr.Body.Bytes()); got.Status != step.status {
+				t.Fatalf("status = %s, want %s", got.Status, step.status)
+			}
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/orders?status=shipped&reference=PO-1", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.ListOrdersResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(resp.Data.Orders) != 1 || resp.Data.Orders[0].ID != order.ID {
+			t.Fatalf("orders = %+v", resp.Data.Orders)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			method, path string
+			body         any
+			status       int
+			want         string
+		}{
+			{http.MethodPost, "/api/orders/", models.CreateOrderRequest{Quantity: 0}, http.StatusBadRequest, `{"error":{"message":"quantity must be > 0"}}`},
+			{http.MethodPost, "/api/orders/", models.CreateOrderRequest{Quantity: 1, SKU: "NOPE-1"}, http.StatusNotFound, `{"error":{"message":"product not found"}}`},
+			{http.MethodGet, "/api/orders/99", nil, http.StatusNotFound, `{"error":{"message":"order not found"}}`},
+			{http.MethodPost, "/api/orders/99/status", models.UpdateOrderStatusRequest{Status: models.OrderStatusConfirmed}, http.StatusNotFound, `{"error":{"message":"order not found"}}`},
+			{http.MethodGet, "/api/orders/abc", nil, http.StatusBadRequest, `{"error":{"message":"invalid id"}}`},
+			{http.MethodGet, "/api/orders?status=lost", nil, http.StatusBadRequest, `{"error":{"message":"invalid status"}}`},
+		}
+		for _, tc := range cases {
+			rr := doJSON(t, h, tc.method, tc.path, tc.body)
+			if rr.Code != tc.status {
+				t.Fatalf("%s %s: expected %d, got %d body=%s", tc.method, tc.path, tc.status, rr.Code, rr.Body.String())
+			}
+			mustJSONEqual(t, rr, tc.want)
+		}
+	})
+}
+
+// failingOrdersRepo fails every order create, like a database error would.
+type failingOrdersRepo struct {
+	repository.OrdersRepository
+}
+
+func (failingOrdersRepo) Create(context.Context, models.Order, *models.Calculation) (*models.Order, error) {
+	return nil, errors.New("db down")
+}
+
+func TestCreateOrderHandler_FailedCreateRecordsNoCalculation(t *testing.T) {
+	useMemoryRepositories(t)
+	repository.SetOrdersRepository(failingOrdersRepo{repository.Orders()})
+	h := http_server.NewHTTPHandler()
+
+	if rr := doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 250}); rr.Code != http.StatusCreated {
+		t.Fatalf("create pack size: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	rr := doJSON(t, h, http.MethodPost, "/api/orders/", models.CreateOrderRequest{Quantity: 251})
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("create: expected 500, got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	rr = doJSON(t, h, http.MethodGet, "/api/calculations", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list calculations: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	mustJSONEqual(t, rr, `{"data":{"calculations":[]}}`)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+func ListPackSetVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	versions, err := repository.PackSetVersions().List(r.Context(), set)
+	if err != nil {
+		log.Error("error listing pack set versions", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	if versions == nil {
+		versions = []models.PackSetVersion{}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.ListPackSetVersionsResponse{Versions: versions})
+}
+
+func GetPackSetVersionHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	version, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 64)
+	if err != nil || version <= 0 {
+		response.WriteError(w, http.StatusBadRequest, "invalid version")
+		return
+	}
+
+	v, err := repository.PackSetVersions().Get(r.Context(), set, version)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		log.Error("error getting pack set version", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	response.WriteSuccess(w, http.StatusOK, v)
+}
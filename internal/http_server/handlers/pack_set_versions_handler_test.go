@@ -0,0 +1,187 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+type fakePackSetVersionsRepo struct {
+	listFn func(ctx context.Context, set string) ([]models.PackSetVersion, error)
+	getFn  func(ctx context.Context, set string, version int64) (*models.PackSetVersion, error)
+	asOfFn func(ctx context.Context, set string, t time.Time) (*models.PackSetVersion, error)
+}
+
+func (f *fakePackSetVersionsRepo) List(ctx context.Context, set string) ([]models.PackSetVersion, error) {
+	return f.listFn(ctx, set)
+}
+func (f *fakePackSetVersionsRepo) Get(ctx context.Context, set string, version int64) (*models.PackSetVersion, error) {
+	return f.getFn(ctx, set, version)
+}
+func (f *fakePackSetVersionsRepo) AsOf(ctx context.Context, set string, t time.Time) (*models.PackSetVersion, error) {
+	return f.asOfFn(ctx, set, t)
+}
+
+var (
+	versionOneAt = time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	versionTwoAt = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	packVersions = []models.PackSetVersion{
+		{PackSet: "default", Version: 1, CreatedAt: versionOneAt, Packs: []models.PackSize{{ID: 1, Size: 250}, {ID: 2, Size: 500}}},
+		{PackSet: "default", Version: 2, CreatedAt: versionTwoAt, Packs: []models.PackSize{{ID: 2, Size: 500}}},
+	}
+)
+
+func withFakePackSetVersions(t *testing.T) {
+	t.Helper()
+	orig := repository.PackSetVersions()
+	t.Cleanup(func() {
+		repository.SetPackSetVersionsRepository(orig)
+	})
+	repository.SetPackSetVersionsRepository(&fakePackSetVersionsRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSetVersion, error) {
+			_ = ctx
+			if set != "default" {
+				return nil, nil
+			}
+			return packVersions, nil
+		},
+		getFn: func(ctx context.Context, set string, version int64) (*models.PackSetVersion, error) {
+			_, _ = ctx, set
+			for _, v := range packVersions {
+				if v.Version == version {
+					return &v, nil
+				}
+			}
+			return nil, repository.ErrNotFound
+		},
+		asOfFn: func(ctx context.Context, set string, t time.Time) (*models.PackSetVersion, error) {
+			_, _ = ctx, set
+			var found *models.PackSetVersion
+			for _, v := range packVersions {
+				if !v.CreatedAt.After(t) {
+					found = &v
+				}
+			}
+			if found == nil {
+				return nil, repository.ErrNotFound
+			}
+			return found, nil
+		},
+	})
+}
+
+func TestPackSetVersionsHandlers(t *testing.T) {
+	withFakePackSetVersions(t)
+	h := http_server.NewHTTPHandler()
+
+	t.Run("list ok", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/packs/versions", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"versions":[
+			{"pack_set":"default","version":1,"created_at":"2026-09-01T12:00:00Z","packs":[{"id":1,"size":250},{"id":2,"size":500}]},
+			{"pack_set":"default","version":2,"created_at":"2026-10-01T12:00:00Z","packs":[{"id":2,"size":500}]}
+		]}}`)
+	})
+
+	t.Run("list empty set", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/pack-sets/retail/packs/versions", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"versions":[]}}`)
+	})
+
+	t.Run("get ok", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/packs/versions/2", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"pack_set":"default","version":2,"created_at":"2026-10-01T12:00:00Z","packs":[{"id":2,"size":500}]}}`)
+	})
+
+	t.Run("get not found -> 404", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/packs/versions/99", nil)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("get invalid version -> 400", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/packs/versions/abc", nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"invalid version"}}`)
+	})
+}
+
+func TestCalculateHandler_Historical(t *testing.T) {
+	useMemoryRepositories(t)
+	withFakePackSetVersions(t)
+
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+	repository.SetPackSizesRepository(&fakePackSizesRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
+			return []models.PackSize{{ID: 3, Size: 1000}}, nil
+		},
+	})
+
+	h := http_server.NewHTTPHandler()
+
+	cases := []struct {
+		name       string
+		req        models.CalculateRequest
+		wantStatus int
+		wantJSON   string
+	}{
+		{"live", models.CalculateRequest{Quantity: 1}, http.StatusOK, `{"data":{"packs":[{"size":1000,"count":1}],"calculation_id":1}}`},
+		{"by version", models.CalculateRequest{Quantity: 1, Version: 1}, http.StatusOK, `{"data":{"packs":[{"size":250,"count":1}],"version":1,"calculation_id":2}}`},
+		{"as_of between versions", models.CalculateRequest{Quantity: 1, AsOf: "2026-09-15T00:00:00Z"}, http.StatusOK, `{"data":{"packs":[{"size":250,"count":1}],"version":1,"calculation_id":3}}`},
+		{"as_of after last version", models.CalculateRequest{Quantity: 1, AsOf: "2026-10-15T00:00:00+02:00"}, http.StatusOK, `{"data":{"packs":[{"size":500,"count":1}],"version":2,"calculation_id":4}}`},
+		{"as_of before history", models.CalculateRequest{Quantity: 1, AsOf: "2020-01-01T00:00:00Z"}, http.StatusNotFound, `{"error":{"message":"pack set version not found"}}`},
+		{"as_of on a set that never changed", models.CalculateRequest{Quantity: 1, PackSet: "retail", AsOf: "2020-01-01T00:00:00Z"}, http.StatusOK, `{"data":{"packs":[{"size":1000,"count":1}],"calculation_id":5}}`},
+		{"unknown version", models.CalculateRequest{Quantity: 1, Version: 7}, http.StatusNotFound, `{"error":{"message":"pack set version not found"}}`},
+		{"invalid as_of", models.CalculateRequest{Quantity: 1, AsOf: "yesterday"}, http.StatusBadRequest, `{"error":{"message":"as_of must be an RFC 3339 timestamp"}}`},
+		{"both", models.CalculateRequest{Quantity: 1, AsOf: "2026-09-15T00:00:00Z", Version: 1}, http.StatusBadRequest, `{"error":{"message":"specify either as_of or version, not both"}}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := doJSON(t, h, http.MethodPost, "/api/calculate", tc.req)
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected %d, got %d body=%s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			mustJSONEqual(t, rr, tc.wantJSON)
+		})
+	}
+}
+
+func TestCalculateHandler_VersionReplaysItsOwnTime(t *testing.T) {
+	useMemoryRepositories(t)
+	expiredAt := versionOneAt.Add(24 * time.Hour)
+	repository.SetPackSetVersionsRepository(&fakePackSetVersionsRepo{
+		getFn: func(ctx context.Context, set string, version int64) (*models.PackSetVersion, error) {
+			_, _ = ctx, set
+			return &models.PackSetVersion{PackSet: "default", Version: version, CreatedAt: versionOneAt, Packs: []models.PackSize{
+				{ID: 1, Size: 250, EffectivePeriod: models.EffectivePeriod{EffectiveTo: &expiredAt}},
+				{ID: 2, Size: 500},
+			}}, nil
+		},
+	})
+
+	rr := doJSON(t, http_server.NewHTTPHandler(), http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1, Version: 1})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	mustJSONEqual(t, rr, `{"data":{"packs":[{"size":250,"count":1}],"version":1,"calculation_id":1}}`)
+}
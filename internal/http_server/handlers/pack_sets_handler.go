@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+func ListPackSetsHandler(w http.ResponseWriter, r *http.Request) {
+	sets, err := repository.PackSizes().ListSets(r.Context())
+	if err != nil {
+		log.Error("error listing pack sets", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.ListPackSetsResponse{PackSets: sets})
+}
+
+// packSetFromRequest returns the pack set addressed by the route.
+// Routes under /api/pack-sets/{name} use the URL name; /api/packs uses the default set.
+func packSetFromRequest(r *http.Request) (string, bool) {
+	return resolvePackSet(chi.URLParam(r, "name"))
+}
+
+// resolvePackSet maps an empty name to the default set and validates the rest.
+func resolvePackSet(name string) (string, bool) {
+	if name == "" {
+		return repository.DefaultPackSet, true
+	}
+	return name, repository.ValidPackSetName(name)
+}
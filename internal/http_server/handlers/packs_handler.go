@@ -3,29 +3,127 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packmeta"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packpolicy"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
 	"github.com/go-chi/chi/v5"
 )
 
 func ListPackSizesHandler(w http.ResponseWriter, r *http.Request) {
-	packs, err := repository.PackSizes().List(r.Context())
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	var err error
+	includeDeleted := false
+	if v := r.URL.Query().Get("include_deleted"); v != "" {
+		includeDeleted, err = strconv.ParseBool(v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "include_deleted must be a boolean")
+			return
+		}
+	}
+
+	var packs []models.PackSize
+	if includeDeleted {
+		packs, err = repository.PackSizes().ListAll(r.Context(), set)
+	} else {
+		packs, err = repository.PackSizes().List(r.Context(), set)
+	}
 	if err != nil {
 		log.Error("error listing pack sizes", "err", err)
 		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
 		return
 	}
 
+	etag := packListETag(packs)
+	w.Header().Set("ETag", etag)
+	if notModified(w, r, etag) {
+		return
+	}
 	response.WriteSuccess(w, http.StatusOK, models.ListPackSizesResponse{Packs: packs})
 }
 
+// ListUpcomingPackSizeChangesHandler lists the pack sizes that come into or go out of effect after
+// now, optionally only those before the "until" query parameter.
+func ListUpcomingPackSizeChangesHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	var until time.Time
+	if v := r.URL.Query().Get("until"); v != "" {
+		var err error
+		if until, err = time.Parse(time.RFC3339, v); err != nil {
+			response.WriteError(w, http.StatusBadRequest, "until must be an RFC 3339 timestamp")
+			return
+		}
+	}
+
+	packs, err := repository.PackSizes().List(r.Context(), set)
+	if err != nil {
+		log.Error("error listing pack sizes for upcoming changes", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	response.WriteSuccess(w, http.StatusOK, models.ListPackSizeChangesResponse{
+		Changes: repository.UpcomingChanges(packs, time.Now(), until),
+	})
+}
+
+func GetPackSizeHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		response.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	p, err := repository.PackSizes().Get(r.Context(), set, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		log.Error("error getting pack size", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	etag := packSizeETag(*p)
+	w.Header().Set("ETag", etag)
+	if notModified(w, r, etag) {
+		return
+	}
+	response.WriteSuccess(w, http.StatusOK, p)
+}
+
 func CreatePackSizeHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
 	var req models.CreatePackSizeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		response.WriteError(w, http.StatusBadRequest, "invalid json")
@@ -35,9 +133,21 @@ func CreatePackSizeHandler(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusBadRequest, "size must be > 0")
 		return
 	}
+	meta, err := packmeta.Normalize(req.PackMetadata)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateEffectivePeriod(req.EffectivePeriod); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	created, err := repository.PackSizes().Create(r.Context(), req.Size)
+	created, err := repository.PackSizes().Create(r.Context(), set, req.Size, meta, req.EffectivePeriod)
 	if err != nil {
+		if writePolicyError(w, err) {
+			return
+		}
 		if errors.Is(err, repository.ErrConflict) {
 			response.WriteError(w, http.StatusConflict, "pack size already exists")
 			return
@@ -46,10 +156,17 @@ func CreatePackSizeHandler(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
 		return
 	}
+	w.Header().Set("ETag", packSizeETag(*created))
 	response.WriteSuccess(w, http.StatusCreated, created)
 }
 
 func UpdatePackSizeHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
@@ -66,13 +183,34 @@ func UpdatePackSizeHandler(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusBadRequest, "size must be > 0")
 		return
 	}
+	meta, err := packmeta.Normalize(req.PackMetadata)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateEffectivePeriod(req.EffectivePeriod); err != nil {
+		response.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	updated, err := repository.PackSizes().Update(r.Context(), id, req.Size)
+	ifVersion, ok := checkIfMatch(w, r, set, id)
+	if !ok {
+		return
+	}
+
+	updated, err := repository.PackSizes().Update(r.Context(), set, id, req.Size, meta, req.EffectivePeriod, ifVersion)
 	if err != nil {
+		if writePolicyError(w, err) {
+			return
+		}
 		if errors.Is(err, repository.ErrNotFound) {
 			response.WriteError(w, http.StatusNotFound, "not found")
 			return
 		}
+		if errors.Is(err, repository.ErrVersionMismatch) {
+			writePreconditionFailed(w)
+			return
+		}
 		if errors.Is(err, repository.ErrConflict) {
 			response.WriteError(w, http.StatusConflict, "pack size already exists")
 			return
@@ -81,10 +219,17 @@ func UpdatePackSizeHandler(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
 		return
 	}
+	w.Header().Set("ETag", packSizeETag(*updated))
 	response.WriteSuccess(w, http.StatusOK, updated)
 }
 
 func DeletePackSizeHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
@@ -92,11 +237,20 @@ func DeletePackSizeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := repository.PackSizes().Delete(r.Context(), id); err != nil {
+	ifVersion, ok := checkIfMatch(w, r, set, id)
+	if !ok {
+		return
+	}
+
+	if err := repository.PackSizes().Delete(r.Context(), set, id, ifVersion); err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			response.WriteError(w, http.StatusNotFound, "not found")
 			return
 		}
+		if errors.Is(err, repository.ErrVersionMismatch) {
+			writePreconditionFailed(w)
+			return
+		}
 		log.Error("error deleting pack size", "err", err)
 		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
 		return
@@ -106,9 +260,98 @@ func DeletePackSizeHandler(w http.ResponseWriter, r *http.Request) {
 	response.WriteSuccess(w, http.StatusOK, struct{}{})
 }
 
+func RestorePackSizeHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		response.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	restored, err := repository.PackSizes().Restore(r.Context(), set, id)
+	if err != nil {
+		if writePolicyError(w, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			response.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if errors.Is(err, repository.ErrConflict) {
+			response.WriteError(w, http.StatusConflict, "pack size already exists")
+			return
+		}
+		log.Error("error restoring pack size", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	response.WriteSuccess(w, http.StatusOK, restored)
+}
+
+// ReplacePackSizesHandler replaces the whole pack set atomically and returns what changed.
+func ReplacePackSizesHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	var req models.ReplacePackSizesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if len(req.Sizes) == 0 {
+		response.WriteError(w, http.StatusBadRequest, "sizes must not be empty")
+		return
+	}
+	seen := make(map[int]bool, len(req.Sizes))
+	for _, s := range req.Sizes {
+		if s <= 0 {
+			response.WriteError(w, http.StatusBadRequest, "size must be > 0")
+			return
+		}
+		if seen[s] {
+			response.WriteError(w, http.StatusBadRequest, fmt.Sprintf("duplicate size %d", s))
+			return
+		}
+		seen[s] = true
+	}
+
+	diff, err := repository.PackSizes().Replace(r.Context(), set, req.Sizes)
+	if err != nil {
+		if writePolicyError(w, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrConflict) {
+			response.WriteError(w, http.StatusConflict, "pack size already exists")
+			return
+		}
+		log.Error("error replacing pack sizes", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	response.WriteSuccess(w, http.StatusOK, diff)
+}
+
 func ResetPackSizesHandler(w http.ResponseWriter, r *http.Request) {
-	sizes, err := repository.PackSizes().ResetToDefault(r.Context())
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	sizes, err := repository.PackSizes().ResetToDefault(r.Context(), set)
 	if err != nil {
+		if writePolicyError(w, err) {
+			return
+		}
 		log.Error("error resetting pack sizes", "err", err)
 		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
 		return
@@ -116,3 +359,21 @@ func ResetPackSizesHandler(w http.ResponseWriter, r *http.Request) {
 
 	response.WriteSuccess(w, http.StatusOK, models.ResetPackSizesResponse{Sizes: sizes})
 }
+
+func validateEffectivePeriod(period models.EffectivePeriod) error {
+	if period.EffectiveFrom != nil && period.EffectiveTo != nil && !period.EffectiveTo.After(*period.EffectiveFrom) {
+		return errors.New("effective_to must be after effective_from")
+	}
+	return nil
+}
+
+// writePolicyError writes the field errors of a pack size policy violation and reports whether err
+// was one.
+func writePolicyError(w http.ResponseWriter, err error) bool {
+	var violation *packpolicy.Error
+	if !errors.As(err, &violation) {
+		return false
+	}
+	response.WriteErrorDetails(w, http.StatusBadRequest, "pack sizes violate the validation policy", violation.Fields)
+	return true
+}
@@ -7,35 +7,68 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packpolicy"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
 )
 
 type fakePackSizesRepo struct {
-	listFn   func(ctx context.Context) ([]models.PackSize, error)
-	createFn func(ctx context.Context, size int) (*models.PackSize, error)
-	updateFn func(ctx context.Context, id int64, size int) (*models.PackSize, error)
-	deleteFn func(ctx context.Context, id int64) error
-	resetFn  func(ctx context.Context) ([]int, error)
+	listSetsFn func(ctx context.Context) ([]string, error)
+	listFn     func(ctx context.Context, set string) ([]models.PackSize, error)
+	listAllFn  func(ctx context.Context, set string) ([]models.PackSize, error)
+	getFn      func(ctx context.Context, set string, id int64) (*models.PackSize, error)
+	createFn   func(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error)
+	updateFn   func(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error)
+	deleteFn   func(ctx context.Context, set string, id int64, ifVersion int64) error
+	restoreFn  func(ctx context.Context, set string, id int64) (*models.PackSize, error)
+	resetFn    func(ctx context.Context, set string) ([]int, error)
+	replaceFn  func(ctx context.Context, set string, sizes []int) (*models.PackSizesDiff, error)
+	importFn   func(ctx context.Context, set string, sizes []int, mode string, dryRun bool) (*models.PackSizesDiff, error)
 }
 
-func (f *fakePackSizesRepo) List(ctx context.Context) ([]models.PackSize, error) {
-	return f.listFn(ctx)
+func (f *fakePackSizesRepo) ListSets(ctx context.Context) ([]string, error) {
+	return f.listSetsFn(ctx)
 }
-func (f *fakePackSizesRepo) Create(ctx context.Context, size int) (*models.PackSize, error) {
-	return f.createFn(ctx, size)
+func (f *fakePackSizesRepo) List(ctx context.Context, set string) ([]models.PackSize, error) {
+	return f.listFn(ctx, set)
 }
-func (f *fakePackSizesRepo) Update(ctx context.Context, id int64, size int) (*models.PackSize, error) {
-	return f.updateFn(ctx, id, size)
+func (f *fakePackSizesRepo) ListAll(ctx context.Context, set string) ([]models.PackSize, error) {
+	return f.listAllFn(ctx, set)
+}
+func (f *fakePackSizesRepo) Create(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+	return f.createFn(ctx, set, size, meta, period)
+}
+func (f *fakePackSizesRepo) Get(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+	return f.getFn(ctx, set, id)
+}
+func (f *fakePackSizesRepo) Update(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+	return f.updateFn(ctx, set, id, size, meta, period, ifVersion)
+}
+func (f *fakePackSizesRepo) Delete(ctx context.Context, set string, id int64, ifVersion int64) error {
+	return f.deleteFn(ctx, set, id, ifVersion)
+}
+func (f *fakePackSizesRepo) Restore(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+	return f.restoreFn(ctx, set, id)
+}
+func (f *fakePackSizesRepo) ResetToDefault(ctx context.Context, set string) ([]int, error) {
+	return f.resetFn(ctx, set)
 }
-func (f *fakePackSizesRepo) Delete(ctx context.Context, id int64) error        { return f.deleteFn(ctx, id) }
-func (f *fakePackSizesRepo) ResetToDefault(ctx context.Context) ([]int, error) { return f.resetFn(ctx) }
 
-func doJSON(t *testing.T, h http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+func (f *fakePackSizesRepo) Replace(ctx context.Context, set string, sizes []int) (*models.PackSizesDiff, error) {
+	return f.replaceFn(ctx, set, sizes)
+}
+
+func (f *fakePackSizesRepo) Import(ctx context.Context, set string, sizes []int, mode string, dryRun bool) (*models.PackSizesDiff, error) {
+	return f.importFn(ctx, set, sizes, mode, dryRun)
+}
+
+func jsonBody(t *testing.T, body any) *bytes.Reader {
 	t.Helper()
 
 	var b []byte
@@ -46,8 +79,13 @@ func doJSON(t *testing.T, h http.Handler, method, path string, body any) *httpte
 			t.Fatalf("marshal body: %v", err)
 		}
 	}
+	return bytes.NewReader(b)
+}
 
-	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+func doJSON(t *testing.T, h http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, jsonBody(t, body))
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 	h.ServeHTTP(rr, req)
@@ -86,19 +124,19 @@ func mustJSONEqual(t *testing.T, rr *httptest.ResponseRecorder, expectedJSON str
 
 func TestPackSizesCRUDAndReset(t *testing.T) {
 	fake := &fakePackSizesRepo{
-		listFn: func(ctx context.Context) ([]models.PackSize, error) {
-			_ = ctx
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
 			return []models.PackSize{{ID: 1, Size: 250}}, nil
 		},
-		createFn: func(ctx context.Context, size int) (*models.PackSize, error) {
-			_ = ctx
+		createFn: func(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+			_, _ = ctx, set
 			if size == 777 {
 				return &models.PackSize{ID: 10, Size: 777}, nil
 			}
 			return nil, repository.ErrConflict
 		},
-		updateFn: func(ctx context.Context, id int64, size int) (*models.PackSize, error) {
-			_ = ctx
+		updateFn: func(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+			_, _ = ctx, set
 			if id == 9999999 {
 				return nil, repository.ErrNotFound
 			}
@@ -107,15 +145,15 @@ func TestPackSizesCRUDAndReset(t *testing.T) {
 			}
 			return &models.PackSize{ID: id, Size: size}, nil
 		},
-		deleteFn: func(ctx context.Context, id int64) error {
-			_ = ctx
+		deleteFn: func(ctx context.Context, set string, id int64, ifVersion int64) error {
+			_, _ = ctx, set
 			if id == 9999999 {
 				return repository.ErrNotFound
 			}
 			return nil
 		},
-		resetFn: func(ctx context.Context) ([]int, error) {
-			_ = ctx
+		resetFn: func(ctx context.Context, set string) ([]int, error) {
+			_, _ = ctx, set
 			return []int{250, 500, 1000, 2000, 5000}, nil
 		},
 	}
@@ -215,8 +253,8 @@ func TestPackSizesCRUDAndReset(t *testing.T) {
 	})
 
 	t.Run("list internal error mapping", func(t *testing.T) {
-		fake.listFn = func(ctx context.Context) ([]models.PackSize, error) {
-			_ = ctx
+		fake.listFn = func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
 			return nil, errors.New("db down")
 		}
 		rr := doJSON(t, h, http.MethodGet, "/api/packs/", nil)
@@ -262,8 +300,8 @@ func TestPackSizesCRUDAndReset(t *testing.T) {
 	})
 
 	t.Run("reset internal error -> 500", func(t *testing.T) {
-		fake.resetFn = func(ctx context.Context) ([]int, error) {
-			_ = ctx
+		fake.resetFn = func(ctx context.Context, set string) ([]int, error) {
+			_, _ = ctx, set
 			return nil, errors.New("db down")
 		}
 		rr := doJSON(t, h, http.MethodPost, "/api/packs/reset", nil)
@@ -274,8 +312,8 @@ func TestPackSizesCRUDAndReset(t *testing.T) {
 	})
 
 	t.Run("create internal error -> 500", func(t *testing.T) {
-		fake.createFn = func(ctx context.Context, size int) (*models.PackSize, error) {
-			_ = ctx
+		fake.createFn = func(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+			_, _ = ctx, set
 			_ = size
 			return nil, errors.New("db down")
 		}
@@ -287,8 +325,8 @@ func TestPackSizesCRUDAndReset(t *testing.T) {
 	})
 
 	t.Run("update internal error -> 500", func(t *testing.T) {
-		fake.updateFn = func(ctx context.Context, id int64, size int) (*models.PackSize, error) {
-			_ = ctx
+		fake.updateFn = func(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+			_, _ = ctx, set
 			_ = id
 			_ = size
 			return nil, errors.New("db down")
@@ -301,8 +339,8 @@ func TestPackSizesCRUDAndReset(t *testing.T) {
 	})
 
 	t.Run("delete internal error -> 500", func(t *testing.T) {
-		fake.deleteFn = func(ctx context.Context, id int64) error {
-			_ = ctx
+		fake.deleteFn = func(ctx context.Context, set string, id int64, ifVersion int64) error {
+			_, _ = ctx, set
 			_ = id
 			return errors.New("db down")
 		}
@@ -313,3 +351,366 @@ func TestPackSizesCRUDAndReset(t *testing.T) {
 		mustJSONEqual(t, rr, `{"error":{"message":"`+constants.InternalServerErrorMsg+`"}}`)
 	})
 }
+
+func TestPackSetsRoutes(t *testing.T) {
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+
+	var gotSets []string
+	fake := &fakePackSizesRepo{
+		listSetsFn: func(ctx context.Context) ([]string, error) {
+			_ = ctx
+			return []string{"default", "retail"}, nil
+		},
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_ = ctx
+			gotSets = append(gotSets, set)
+			return []models.PackSize{{ID: 7, Size: 300}}, nil
+		},
+		createFn: func(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+			_ = ctx
+			gotSets = append(gotSets, set)
+			return &models.PackSize{ID: 8, Size: size}, nil
+		},
+		updateFn: func(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+			_ = ctx
+			gotSets = append(gotSets, set)
+			return &models.PackSize{ID: id, Size: size}, nil
+		},
+		deleteFn: func(ctx context.Context, set string, id int64, ifVersion int64) error {
+			_, _ = ctx, id
+			gotSets = append(gotSets, set)
+			return nil
+		},
+		resetFn: func(ctx context.Context, set string) ([]int, error) {
+			_ = ctx
+			gotSets = append(gotSets, set)
+			return []int{250, 500, 1000, 2000, 5000}, nil
+		},
+	}
+	repository.SetPackSizesRepository(fake)
+
+	h := http_server.NewHTTPHandler()
+
+	t.Run("list sets", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/pack-sets", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"pack_sets":["default","retail"]}}`)
+	})
+
+	t.Run("routes are scoped to the named set", func(t *testing.T) {
+		gotSets = nil
+		calls := []struct {
+			method string
+			path   string
+			body   any
+		}{
+			{http.MethodGet, "/api/pack-sets/retail/packs/", nil},
+			{http.MethodPost, "/api/pack-sets/retail/packs/", models.CreatePackSizeRequest{Size: 300}},
+			{http.MethodPut, "/api/pack-sets/retail/packs/8", models.UpdatePackSizeRequest{Size: 600}},
+			{http.MethodDelete, "/api/pack-sets/retail/packs/8", nil},
+			{http.MethodPost, "/api/pack-sets/retail/packs/reset", nil},
+		}
+		for _, c := range calls {
+			rr := doJSON(t, h, c.method, c.path, c.body)
+			if rr.Code >= 300 {
+				t.Fatalf("%s %s: unexpected status %d body=%s", c.method, c.path, rr.Code, rr.Body.String())
+			}
+		}
+		if len(gotSets) != len(calls) {
+			t.Fatalf("expected %d repository calls, got %v", len(calls), gotSets)
+		}
+		for _, s := range gotSets {
+			if s != "retail" {
+				t.Fatalf("expected all calls scoped to retail, got %v", gotSets)
+			}
+		}
+	})
+
+	t.Run("legacy routes use the default set", func(t *testing.T) {
+		gotSets = nil
+		rr := doJSON(t, h, http.MethodGet, "/api/packs/", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if len(gotSets) != 1 || gotSets[0] != repository.DefaultPackSet {
+			t.Fatalf("expected default set, got %v", gotSets)
+		}
+	})
+
+	t.Run("invalid set name -> 400", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/pack-sets/bad%20name/packs/", nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"invalid pack set name"}}`)
+	})
+}
+
+func TestPackSizesSoftDeleteAndRestore(t *testing.T) {
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+
+	deletedAt := time.Date(2026, 10, 13, 9, 30, 0, 0, time.UTC)
+	repository.SetPackSizesRepository(&fakePackSizesRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
+			return []models.PackSize{{ID: 1, Size: 250}}, nil
+		},
+		listAllFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_, _ = ctx, set
+			return []models.PackSize{{ID: 1, Size: 250}, {ID: 2, Size: 1000, DeletedAt: &deletedAt}}, nil
+		},
+		restoreFn: func(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+			_, _ = ctx, set
+			switch id {
+			case 2:
+				return &models.PackSize{ID: 2, Size: 1000}, nil
+			case 3:
+				return nil, repository.ErrConflict
+			default:
+				return nil, repository.ErrNotFound
+			}
+		},
+	})
+
+	h := http_server.NewHTTPHandler()
+
+	t.Run("list excludes deleted by default", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/packs/", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"packs":[{"id":1,"size":250}]}}`)
+	})
+
+	t.Run("list include_deleted", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/packs/?include_deleted=true", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"packs":[{"id":1,"size":250},{"id":2,"size":1000,"deleted_at":"2026-10-13T09:30:00Z"}]}}`)
+	})
+
+	t.Run("list include_deleted invalid -> 400", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/packs/?include_deleted=maybe", nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"include_deleted must be a boolean"}}`)
+	})
+
+	t.Run("restore ok", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/packs/2/restore", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"id":2,"size":1000}}`)
+	})
+
+	t.Run("restore recreated size -> 409", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/packs/3/restore", nil)
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"pack size already exists"}}`)
+	})
+
+	t.Run("restore not found -> 404", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/packs/9/restore", nil)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("restore invalid id -> 400", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/packs/abc/restore", nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"invalid id"}}`)
+	})
+}
+
+func TestReplacePackSizesHandler(t *testing.T) {
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+
+	var gotSet string
+	var gotSizes []int
+	repository.SetPackSizesRepository(&fakePackSizesRepo{
+		replaceFn: func(ctx context.Context, set string, sizes []int) (*models.PackSizesDiff, error) {
+			_ = ctx
+			gotSet, gotSizes = set, sizes
+			if sizes[0] == 999 {
+				return nil, errors.New("boom")
+			}
+			return &models.PackSizesDiff{
+				Added:   []models.PackSize{{ID: 4, Size: 300}},
+				Removed: []models.PackSize{{ID: 1, Size: 250}},
+				Kept:    []models.PackSize{{ID: 2, Size: 500}},
+			}, nil
+		},
+	})
+
+	h := http_server.NewHTTPHandler()
+
+	t.Run("ok", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPut, "/api/packs", map[string]any{"sizes": []int{300, 500}})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"added":[{"id":4,"size":300}],"removed":[{"id":1,"size":250}],"kept":[{"id":2,"size":500}]}}`)
+		if gotSet != repository.DefaultPackSet || len(gotSizes) != 2 {
+			t.Fatalf("unexpected repo args set=%q sizes=%v", gotSet, gotSizes)
+		}
+	})
+
+	t.Run("named pack set", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPut, "/api/pack-sets/retail/packs/", map[string]any{"sizes": []int{300}})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if gotSet != "retail" {
+			t.Fatalf("expected set retail, got %q", gotSet)
+		}
+	})
+
+	cases := []struct {
+		name    string
+		body    any
+		wantMsg string
+	}{
+		{"empty sizes", map[string]any{"sizes": []int{}}, "sizes must not be empty"},
+		{"missing sizes", map[string]any{}, "sizes must not be empty"},
+		{"non-positive size", map[string]any{"sizes": []int{250, 0}}, "size must be > 0"},
+		{"duplicate size", map[string]any{"sizes": []int{250, 500, 250}}, "duplicate size 250"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name+" -> 400", func(t *testing.T) {
+			gotSizes = nil
+			rr := doJSON(t, h, http.MethodPut, "/api/packs/", tc.body)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+			}
+			mustJSONEqual(t, rr, `{"error":{"message":"`+tc.wantMsg+`"}}`)
+			if gotSizes != nil {
+				t.Fatalf("repository must not be called on invalid input")
+			}
+		})
+	}
+
+	t.Run("repo error -> 500", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPut, "/api/packs/", map[string]any{"sizes": []int{999}})
+		if rr.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"`+constants.InternalServerErrorMsg+`"}}`)
+	})
+}
+
+func TestPackSizeMetadata(t *testing.T) {
+	var gotMeta models.PackMetadata
+	fake := &fakePackSizesRepo{
+		createFn: func(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+			gotMeta = meta
+			return &models.PackSize{ID: 1, Size: size, PackMetadata: meta, Version: 1}, nil
+		},
+		updateFn: func(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+			gotMeta = meta
+			return &models.PackSize{ID: id, Size: size, PackMetadata: meta, Version: 2}, nil
+		},
+	}
+	repository.SetPackSizesRepository(fake)
+
+	h := http_server.NewHTTPHandler()
+
+	t.Run("create with metadata", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/packs/", map[string]any{
+			"size":         250,
+			"label":        "  Small carton ",
+			"supplier_sku": "SUP-250",
+			"gtin":         "4006381333931",
+			"dimensions":   map[string]int{"length_mm": 300, "width_mm": 200, "height_mm": 100},
+			"weight_grams": 1200,
+		})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if gotMeta.Label != "Small carton" {
+			t.Fatalf("expected the label to be trimmed, got %q", gotMeta.Label)
+		}
+		mustJSONEqual(t, rr, `{"data":{"id":1,"size":250,"label":"Small carton","supplier_sku":"SUP-250",
+			"gtin":"4006381333931","dimensions":{"length_mm":300,"width_mm":200,"height_mm":100},
+			"weight_grams":1200,"version":1}}`)
+	})
+
+	t.Run("update replaces metadata", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPut, "/api/packs/1", map[string]any{"size": 250, "gtin": "96385074"})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if gotMeta != (models.PackMetadata{GTIN: "96385074"}) {
+			t.Fatalf("metadata = %+v", gotMeta)
+		}
+	})
+
+	for _, tc := range []struct {
+		name string
+		body map[string]any
+		msg  string
+	}{
+		{"bad check digit", map[string]any{"gtin": "4006381333932"}, "gtin check digit is invalid"},
+		{"bad length", map[string]any{"gtin": "12345"}, "gtin must have 8, 12, 13 or 14 digits"},
+		{"not digits", map[string]any{"gtin": "40063813339A1"}, "gtin must contain digits only"},
+		{"long label", map[string]any{"label": strings.Repeat("x", 101)}, "label must be at most 100 characters"},
+		{"long supplier sku", map[string]any{"supplier_sku": strings.Repeat("x", 65)}, "supplier_sku must be at most 64 characters"},
+		{"zero dimension", map[string]any{"dimensions": map[string]int{"length_mm": 300, "width_mm": 200}}, "dimensions must all be > 0"},
+		{"negative weight", map[string]any{"weight_grams": -1}, "weight_grams must be >= 0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.body["size"] = 250
+			for _, req := range []struct{ method, path string }{
+				{http.MethodPost, "/api/packs/"},
+				{http.MethodPut, "/api/packs/1"},
+			} {
+				rr := doJSON(t, h, req.method, req.path, tc.body)
+				if rr.Code != http.StatusBadRequest {
+					t.Fatalf("%s: expected 400, got %d body=%s", req.method, rr.Code, rr.Body.String())
+				}
+				mustJSONEqual(t, rr, `{"error":{"message":"`+tc.msg+`"}}`)
+			}
+		})
+	}
+}
+
+func TestPackSizePolicyFieldErrors(t *testing.T) {
+	useMemoryRepositories(t)
+	repository.SetPackSizePolicy(packpolicy.Policy{MinSize: 10, Multiples: []int{5}})
+	t.Cleanup(func() { repository.SetPackSizePolicy(packpolicy.Policy{}) })
+	h := http_server.NewHTTPHandler()
+
+	rr := doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 1})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("create: expected 400, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	mustJSONEqual(t, rr, `{"error":{"message":"pack sizes violate the validation policy","details":[
+		{"field":"size","message":"size must be at least 10"},
+		{"field":"size","message":"size must be a multiple of 5"}]}}`)
+
+	rr = doJSON(t, h, http.MethodPut, "/api/packs/", models.ReplacePackSizesRequest{Sizes: []int{250, 12}})
+	mustJSONEqual(t, rr, `{"error":{"message":"pack sizes violate the validation policy","details":[
+		{"field":"sizes","message":"size 12 must be a multiple of 5"}]}}`)
+
+	if rr := doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 250}); rr.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packio"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+const maxImportBytes = 1 << 20
+
+// ExportPackSizesHandler downloads the pack sizes of a set that are in effect now as CSV, JSON or
+// YAML (query parameter format, default json).
+func ExportPackSizesHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	format := packio.FormatJSON
+	if v := r.URL.Query().Get("format"); v != "" {
+		format = packio.ParseFormat(v)
+		if format == "" {
+			response.WriteError(w, http.StatusBadRequest, "format must be csv, json or yaml")
+			return
+		}
+	}
+
+	packs, err := repository.PackSizes().List(r.Context(), set)
+	if err != nil {
+		log.Error("error listing pack sizes", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	// The file only holds sizes, which become active as soon as they are imported, so expired and
+	// scheduled sizes are left out.
+	packs = repository.EffectivePackSizes(packs, time.Now())
+
+	w.Header().Set("Content-Type", packio.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-packs.%s"`, set, format))
+	w.WriteHeader(http.StatusOK)
+	if err := packio.Encode(w, format, packs); err != nil {
+		log.Error("error exporting pack sizes", "err", err)
+	}
+}
+
+// ImportPackSizesHandler imports pack sizes from the request body. The format comes from the
+// format query parameter or the Content-Type header; mode is merge (default) or replace, and
+// dry_run=true only previews the changes.
+func ImportPackSizesHandler(w http.ResponseWriter, r *http.Request) {
+	set, ok := packSetFromRequest(r)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid pack set name")
+		return
+	}
+
+	q := r.URL.Query()
+	format := packio.FormatFromContentType(r.Header.Get("Content-Type"))
+	if v := q.Get("format"); v != "" {
+		format = packio.ParseFormat(v)
+	}
+	if format == "" {
+		response.WriteError(w, http.StatusBadRequest, "format must be csv, json or yaml")
+		return
+	}
+
+	mode := models.ImportModeMerge
+	if v := q.Get("mode"); v != "" {
+		if v != models.ImportModeMerge && v != models.ImportModeReplace {
+			response.WriteError(w, http.StatusBadRequest, "mode must be merge or replace")
+			return
+		}
+		mode = v
+	}
+
+	dryRun := false
+	if v := q.Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "dry_run must be a boolean")
+			return
+		}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.WriteError(w, http.StatusRequestEntityTooLarge, "import is too large")
+			return
+		}
+		response.WriteError(w, http.StatusBadRequest, "error reading request body")
+		return
+	}
+
+	sizes, err := packio.Decode(data, format)
+	if err != nil {
+		var invalid *packio.InvalidError
+		if errors.As(err, &invalid) {
+			response.WriteErrorDetails(w, http.StatusBadRequest, invalid.Message, invalid.Rows)
+			return
+		}
+		log.Error("error decoding pack sizes import", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	diff, err := repository.PackSizes().Import(r.Context(), set, sizes, mode, dryRun)
+	if err != nil {
+		if writePolicyError(w, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrConflict) {
+			response.WriteError(w, http.StatusConflict, "pack size already exists")
+			return
+		}
+		log.Error("error importing pack sizes", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.ImportPackSizesResponse{
+		Mode:          mode,
+		DryRun:        dryRun,
+		PackSizesDiff: *diff,
+	})
+}
@@ -0,0 +1,191 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+func TestExportPackSizesHandler(t *testing.T) {
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+
+	var gotSet string
+	repository.SetPackSizesRepository(&fakePackSizesRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_ = ctx
+			gotSet = set
+			past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+			return []models.PackSize{
+				{ID: 1, Size: 250, PackMetadata: models.PackMetadata{Label: "Small"}},
+				{ID: 2, Size: 500},
+				{ID: 3, Size: 750, EffectivePeriod: models.EffectivePeriod{EffectiveTo: &past}},
+				{ID: 4, Size: 1000, EffectivePeriod: models.EffectivePeriod{EffectiveFrom: &future}},
+			}, nil
+		},
+	})
+
+	h := http_server.NewHTTPHandler()
+
+	cases := []struct {
+		path            string
+		wantType        string
+		wantDisposition string
+		wantBody        string
+	}{
+		{"/api/packs/export?format=csv", "text/csv; charset=utf-8", `attachment; filename="default-packs.csv"`, "size\n250\n500\n"},
+		{"/api/packs/export", "application/json", `attachment; filename="default-packs.json"`, "{\n  \"packs\": [\n    {\n      \"size\": 250\n    },\n    {\n      \"size\": 500\n    }\n  ]\n}\n"},
+		{"/api/pack-sets/retail/packs/export?format=yaml", "application/yaml", `attachment; filename="retail-packs.yaml"`, "packs:\n  - size: 250\n  - size: 500\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			rr := doJSON(t, h, http.MethodGet, tc.path, nil)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("Content-Type"); got != tc.wantType {
+				t.Fatalf("unexpected content type %q", got)
+			}
+			if got := rr.Header().Get("Content-Disposition"); got != tc.wantDisposition {
+				t.Fatalf("unexpected content disposition %q", got)
+			}
+			if rr.Body.String() != tc.wantBody {
+				t.Fatalf("unexpected body; got=%q expected=%q", rr.Body.String(), tc.wantBody)
+			}
+		})
+	}
+	if gotSet != "retail" {
+		t.Fatalf("expected last export from retail, got %q", gotSet)
+	}
+
+	t.Run("unsupported format -> 400", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/packs/export?format=xml", nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"format must be csv, json or yaml"}}`)
+	})
+}
+
+func TestImportPackSizesHandler(t *testing.T) {
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+
+	type importCall struct {
+		set    string
+		sizes  []int
+		mode   string
+		dryRun bool
+	}
+	var calls []importCall
+	repository.SetPackSizesRepository(&fakePackSizesRepo{
+		importFn: func(ctx context.Context, set string, sizes []int, mode string, dryRun bool) (*models.PackSizesDiff, error) {
+			_ = ctx
+			calls = append(calls, importCall{set, sizes, mode, dryRun})
+			if sizes[0] == 42 {
+				return nil, repository.ErrConflict
+			}
+			return &models.PackSizesDiff{
+				Added:   []models.PackSize{{Size: 300}},
+				Removed: []models.PackSize{},
+				Kept:    []models.PackSize{{ID: 1, Size: 250}},
+			}, nil
+		},
+	})
+
+	h := http_server.NewHTTPHandler()
+
+	doImport := func(t *testing.T, path, contentType, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("csv dry run", func(t *testing.T) {
+		calls = nil
+		rr := doImport(t, "/api/packs/import?dry_run=true", "text/csv", "size\n250\n300\n")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"mode":"merge","dry_run":true,"added":[{"id":0,"size":300}],"removed":[],"kept":[{"id":1,"size":250}]}}`)
+		if len(calls) != 1 || calls[0].set != repository.DefaultPackSet || calls[0].mode != models.ImportModeMerge || !calls[0].dryRun {
+			t.Fatalf("unexpected repo calls %+v", calls)
+		}
+	})
+
+	t.Run("yaml replace via format param", func(t *testing.T) {
+		calls = nil
+		rr := doImport(t, "/api/pack-sets/retail/packs/import?format=yaml&mode=replace", "", "packs:\n  - size: 300\n")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if len(calls) != 1 || calls[0].set != "retail" || calls[0].mode != models.ImportModeReplace || calls[0].dryRun {
+			t.Fatalf("unexpected repo calls %+v", calls)
+		}
+		if len(calls[0].sizes) != 1 || calls[0].sizes[0] != 300 {
+			t.Fatalf("unexpected sizes %v", calls[0].sizes)
+		}
+	})
+
+	t.Run("row errors -> 400 with details", func(t *testing.T) {
+		calls = nil
+		rr := doImport(t, "/api/packs/import", "application/json", `{"packs":[{"size":250},{"size":0},{"size":250}]}`)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"invalid rows","details":[{"row":2,"message":"size must be > 0"},{"row":3,"message":"duplicate size 250 (first in row 1)"}]}}`)
+		if len(calls) != 0 {
+			t.Fatalf("repository must not be called on invalid input")
+		}
+	})
+
+	t.Run("conflict -> 409", func(t *testing.T) {
+		rr := doImport(t, "/api/packs/import", "text/csv", "size\n42\n")
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	badRequests := []struct {
+		name        string
+		path        string
+		contentType string
+		wantMsg     string
+	}{
+		{"unknown format", "/api/packs/import", "text/plain", "format must be csv, json or yaml"},
+		{"invalid mode", "/api/packs/import?mode=upsert", "text/csv", "mode must be merge or replace"},
+		{"invalid dry_run", "/api/packs/import?dry_run=maybe", "text/csv", "dry_run must be a boolean"},
+	}
+	for _, tc := range badRequests {
+		t.Run(tc.name+" -> 400", func(t *testing.T) {
+			rr := doImport(t, tc.path, tc.contentType, "size\n250\n")
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+			}
+			mustJSONEqual(t, rr, `{"error":{"message":"`+tc.wantMsg+`"}}`)
+		})
+	}
+
+	t.Run("too large -> 413", func(t *testing.T) {
+		rr := doImport(t, "/api/packs/import", "text/csv", "size\n"+strings.Repeat("250\n", 300_000))
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected 413, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+var skuRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+const (
+	maxProductNameLen        = 200
+	maxProductDescriptionLen = 2000
+)
+
+func ListProductsHandler(w http.ResponseWriter, r *http.Request) {
+	products, err := repository.Products().List(r.Context())
+	if err != nil {
+		log.Error("error listing products", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, models.ListProductsResponse{Products: products})
+}
+
+func GetProductHandler(w http.ResponseWriter, r *http.Request) {
+	sku := chi.URLParam(r, "sku")
+	if !skuRe.MatchString(sku) {
+		response.WriteError(w, http.StatusBadRequest, "invalid sku")
+		return
+	}
+
+	product, err := repository.Products().Get(r.Context(), sku)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		log.Error("error getting product", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	response.WriteSuccess(w, http.StatusOK, product)
+}
+
+func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if !skuRe.MatchString(req.SKU) {
+		response.WriteError(w, http.StatusBadRequest, "invalid sku")
+		return
+	}
+	product, msg := productFromRequest(req.SKU, req.Name, req.Description, req.PackSet)
+	if msg != "" {
+		response.WriteError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !requireExistingPackSet(w, r, product.PackSet) {
+		return
+	}
+
+	created, err := repository.Products().Create(r.Context(), product)
+	if err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			response.WriteError(w, http.StatusConflict, "product already exists")
+			return
+		}
+		log.Error("error creating product", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	response.WriteSuccess(w, http.StatusCreated, created)
+}
+
+func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
+	sku := chi.URLParam(r, "sku")
+	if !skuRe.MatchString(sku) {
+		response.WriteError(w, http.StatusBadRequest, "invalid sku")
+		return
+	}
+
+	var req models.UpdateProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	product, msg := productFromRequest(sku, req.Name, req.Description, req.PackSet)
+	if msg != "" {
+		response.WriteError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !requireExistingPackSet(w, r, product.PackSet) {
+		return
+	}
+
+	updated, err := repository.Products().Update(r.Context(), sku, product)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		log.Error("error updating product", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	response.WriteSuccess(w, http.StatusOK, updated)
+}
+
+func DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
+	sku := chi.URLParam(r, "sku")
+	if !skuRe.MatchString(sku) {
+		response.WriteError(w, http.StatusBadRequest, "invalid sku")
+		return
+	}
+
+	if err := repository.Products().Delete(r.Context(), sku); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		log.Error("error deleting product", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, struct{}{})
+}
+
+// ListProductPackSizesHandler lists the pack sizes of the pack set the product points at.
+func ListProductPackSizesHandler(w http.ResponseWriter, r *http.Request) {
+	sku := chi.URLParam(r, "sku")
+	if !skuRe.MatchString(sku) {
+		response.WriteError(w, http.StatusBadRequest, "invalid sku")
+		return
+	}
+
+	set, ok := productPackSet(w, r, sku)
+	if !ok {
+		return
+	}
+
+	packs, err := repository.PackSizes().List(r.Context(), set)
+	if err != nil {
+		log.Error("error listing product pack sizes", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	response.WriteSuccess(w, http.StatusOK, models.ListPackSizesResponse{Packs: packs})
+}
+
+// productPackSet looks up the pack set of a product. On failure it writes the error response
+// and returns false.
+func productPackSet(w http.ResponseWriter, r *http.Request, sku string) (string, bool) {
+	product, err := repository.Products().Get(r.Context(), sku)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.WriteError(w, http.StatusNotFound, "product not found")
+			return "", false
+		}
+		log.Error("error getting product", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return "", false
+	}
+	return product.PackSet, true
+}
+
+// requireExistingPackSet checks that a product points at one of the tenant's pack sets. On
+// failure it writes the error response and returns false.
+func requireExistingPackSet(w http.ResponseWriter, r *http.Request, set string) bool {
+	sets, err := repository.PackSizes().ListSets(r.Context())
+	if err != nil {
+		log.Error("error listing pack sets", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return false
+	}
+	if !slices.Contains(sets, set) {
+		response.WriteError(w, http.StatusBadRequest, "pack set does not exist")
+		return false
+	}
+	return true
+}
+
+// productFromRequest validates product fields and returns a validation message on failure.
+func productFromRequest(sku, name, description, packSet string) (models.Product, string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.Product{}, "name is required"
+	}
+	if len(name) > maxProductNameLen {
+		return models.Product{}, "name too long"
+	}
+	if len(description) > maxProductDescriptionLen {
+		return models.Product{}, "description too long"
+	}
+	set, ok := resolvePackSet(packSet)
+	if !ok {
+		return models.Product{}, "invalid pack set name"
+	}
+	return models.Product{SKU: sku, Name: name, Description: description, PackSet: set}, ""
+}
@@ -0,0 +1,261 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+type fakeProductsRepo struct {
+	listFn   func(ctx context.Context) ([]models.Product, error)
+	getFn    func(ctx context.Context, sku string) (*models.Product, error)
+	createFn func(ctx context.Context, p models.Product) (*models.Product, error)
+	updateFn func(ctx context.Context, sku string, p models.Product) (*models.Product, error)
+	deleteFn func(ctx context.Context, sku string) error
+}
+
+func (f *fakeProductsRepo) List(ctx context.Context) ([]models.Product, error) { return f.listFn(ctx) }
+func (f *fakeProductsRepo) Get(ctx context.Context, sku string) (*models.Product, error) {
+	return f.getFn(ctx, sku)
+}
+func (f *fakeProductsRepo) Create(ctx context.Context, p models.Product) (*models.Product, error) {
+	return f.createFn(ctx, p)
+}
+func (f *fakeProductsRepo) Update(ctx context.Context, sku string, p models.Product) (*models.Product, error) {
+	return f.updateFn(ctx, sku, p)
+}
+func (f *fakeProductsRepo) Delete(ctx context.Context, sku string) error { return f.deleteFn(ctx, sku) }
+
+func withFakeProducts(t *testing.T, fake *fakeProductsRepo) {
+	t.Helper()
+	orig := repository.Products()
+	t.Cleanup(func() {
+		repository.SetProductsRepository(orig)
+	})
+	repository.SetProductsRepository(fake)
+}
+
+func TestProductsCRUD(t *testing.T) {
+	widget := models.Product{ID: 1, SKU: "WID-1", Name: "Widget", Description: "A widget", PackSet: "retail"}
+	fake := &fakeProductsRepo{
+		listFn: func(ctx context.Context) ([]models.Product, error) {
+			_ = ctx
+			return []models.Product{widget}, nil
+		},
+		getFn: func(ctx context.Context, sku string) (*models.Product, error) {
+			_ = ctx
+			if sku == widget.SKU {
+				p := widget
+				return &p, nil
+			}
+			return nil, repository.ErrNotFound
+		},
+		createFn: func(ctx context.Context, p models.Product) (*models.Product, error) {
+			_ = ctx
+			if p.SKU == widget.SKU {
+				return nil, repository.ErrConflict
+			}
+			p.ID = 2
+			return &p, nil
+		},
+		updateFn: func(ctx context.Context, sku string, p models.Product) (*models.Product, error) {
+			_ = ctx
+			if sku != widget.SKU {
+				return nil, repository.ErrNotFound
+			}
+			p.ID = widget.ID
+			return &p, nil
+		},
+		deleteFn: func(ctx context.Context, sku string) error {
+			_ = ctx
+			if sku != widget.SKU {
+				return repository.ErrNotFound
+			}
+			return nil
+		},
+	}
+	useMemoryRepositories(t)
+	withFakeProducts(t, fake)
+	if _, err := repository.PackSizes().Create(context.Background(), "wholesale", 1000, models.PackMetadata{}, models.EffectivePeriod{}); err != nil {
+		t.Fatalf("create pack size: %v", err)
+	}
+
+	h := http_server.NewHTTPHandler()
+
+	t.Run("list ok", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/products/", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"products":[{"id":1,"sku":"WID-1","name":"Widget","description":"A widget","pack_set":"retail"}]}}`)
+	})
+
+	t.Run("get ok", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/products/WID-1", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"id":1,"sku":"WID-1","name":"Widget","description":"A widget","pack_set":"retail"}}`)
+	})
+
+	t.Run("get not found -> 404", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/products/NOPE", nil)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"not found"}}`)
+	})
+
+	t.Run("create defaults pack set", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/products/", models.CreateProductRequest{SKU: "GAD-2", Name: " Gadget "})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"id":2,"sku":"GAD-2","name":"Gadget","description":"","pack_set":"default"}}`)
+	})
+
+	t.Run("create conflict -> 409", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/products/", models.CreateProductRequest{SKU: "WID-1", Name: "Widget"})
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"product already exists"}}`)
+	})
+
+	t.Run("create validation -> 400", func(t *testing.T) {
+		cases := []struct {
+			req  models.CreateProductRequest
+			want string
+		}{
+			{models.CreateProductRequest{SKU: "", Name: "x"}, "invalid sku"},
+			{models.CreateProductRequest{SKU: "A B", Name: "x"}, "invalid sku"},
+			{models.CreateProductRequest{SKU: "A", Name: "  "}, "name is required"},
+			{models.CreateProductRequest{SKU: "A", Name: "x", PackSet: "bad set"}, "invalid pack set name"},
+			{models.CreateProductRequest{SKU: "A", Name: "x", PackSet: "missing"}, "pack set does not exist"},
+		}
+		for _, c := range cases {
+			rr := doJSON(t, h, http.MethodPost, "/api/products/", c.req)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+			}
+			mustJSONEqual(t, rr, `{"error":{"message":"`+c.want+`"}}`)
+		}
+	})
+
+	t.Run("update ok", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPut, "/api/products/WID-1", models.UpdateProductRequest{Name: "Widget v2", PackSet: "wholesale"})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"id":1,"sku":"WID-1","name":"Widget v2","description":"","pack_set":"wholesale"}}`)
+	})
+
+	t.Run("update to unknown pack set -> 400", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPut, "/api/products/WID-1", models.UpdateProductRequest{Name: "Widget", PackSet: "missing"})
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"pack set does not exist"}}`)
+	})
+
+	t.Run("update not found -> 404", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPut, "/api/products/NOPE", models.UpdateProductRequest{Name: "x"})
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("delete ok", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodDelete, "/api/products/WID-1", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{}}`)
+	})
+
+	t.Run("delete not found -> 404", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodDelete, "/api/products/NOPE", nil)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("list internal error -> 500", func(t *testing.T) {
+		fake.listFn = func(ctx context.Context) ([]models.Product, error) {
+			_ = ctx
+			return nil, errors.New("db down")
+		}
+		rr := doJSON(t, h, http.MethodGet, "/api/products/", nil)
+		if rr.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"`+constants.InternalServerErrorMsg+`"}}`)
+	})
+}
+
+func TestProductPacksAndCalculateBySKU(t *testing.T) {
+	useMemoryRepositories(t)
+	withFakeProducts(t, &fakeProductsRepo{
+		getFn: func(ctx context.Context, sku string) (*models.Product, error) {
+			_ = ctx
+			if sku == "WID-1" {
+				return &models.Product{ID: 1, SKU: sku, Name: "Widget", PackSet: "retail"}, nil
+			}
+			return nil, repository.ErrNotFound
+		},
+	})
+
+	origRepo := repository.PackSizes()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(origRepo)
+	})
+	repository.SetPackSizesRepository(&fakePackSizesRepo{
+		listFn: func(ctx context.Context, set string) ([]models.PackSize, error) {
+			_ = ctx
+			if set == "retail" {
+				return []models.PackSize{{ID: 3, Size: 300}}, nil
+			}
+			return []models.PackSize{{ID: 1, Size: 250}}, nil
+		},
+	})
+
+	h := http_server.NewHTTPHandler()
+
+	t.Run("product packs", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/products/WID-1/packs", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"packs":[{"id":3,"size":300}]}}`)
+	})
+
+	t.Run("calculate uses product pack set", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1, SKU: "WID-1"})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"data":{"packs":[{"size":300,"count":1}],"calculation_id":1}}`)
+	})
+
+	t.Run("calculate unknown sku -> 404", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1, SKU: "NOPE"})
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"product not found"}}`)
+	})
+
+	t.Run("calculate sku and pack set -> 400", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1, SKU: "WID-1", PackSet: "retail"})
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		mustJSONEqual(t, rr, `{"error":{"message":"specify either sku or pack_set, not both"}}`)
+	})
+}
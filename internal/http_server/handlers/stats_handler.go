@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/stats"
+)
+
+// defaultStatsRange is the range covered when the request sets no from.
+const defaultStatsRange = 30 * 24 * time.Hour
+
+// StatsHandler aggregates the calculation history. Supported query parameters: from and to
+// (RFC 3339, to is exclusive; default the last 30 days), bucket (hour, day, week or month; default
+// day) and format (json or csv; default json).
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	to := time.Now()
+	from := time.Time{}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, p.name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*p.dst = t
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultStatsRange)
+	}
+	bucket := q.Get("bucket")
+	if bucket == "" {
+		bucket = models.StatsBucketDay
+	}
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		response.WriteError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	agg, err := stats.New(from, to, bucket)
+	if err != nil {
+		switch {
+		case errors.Is(err, stats.ErrInvalidBucket):
+			response.WriteError(w, http.StatusBadRequest, "bucket must be hour, day, week or month")
+		case errors.Is(err, stats.ErrInvalidRange):
+			response.WriteError(w, http.StatusBadRequest, "to must be after from")
+		default:
+			response.WriteError(w, http.StatusBadRequest, fmt.Sprintf("range is too long for %s buckets", bucket))
+		}
+		return
+	}
+
+	err = repository.Calculations().Each(r.Context(), models.CalculationFilter{From: from, To: to}, func(c models.Calculation) error {
+		agg.Add(c)
+		return nil
+	})
+	if err != nil {
+		log.Error("error aggregating calculations", "err", err)
+		response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+		return
+	}
+	result := agg.Result()
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="stats.csv"`)
+		w.WriteHeader(http.StatusOK)
+		if err := stats.WriteCSV(w, result); err != nil {
+			log.Error("error exporting stats", "err", err)
+		}
+		return
+	}
+	response.WriteSuccess(w, http.StatusOK, result)
+}
@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func TestStatsHandler(t *testing.T) {
+	useMemoryRepositories(t)
+	h := http_server.NewHTTPHandler()
+
+	for _, size := range []int{250, 500} {
+		if rr := doJSON(t, h, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: size}); rr.Code != http.StatusCreated {
+			t.Fatalf("create %d: expected 201, got %d body=%s", size, rr.Code, rr.Body.String())
+		}
+	}
+	for _, qty := range []int{1, 251, 500} {
+		if rr := doJSON(t, h, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: qty}); rr.Code != http.StatusOK {
+			t.Fatalf("calculate %d: expected 200, got %d body=%s", qty, rr.Code, rr.Body.String())
+		}
+	}
+
+	t.Run("json", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/stats?bucket=hour&from="+time.Now().Add(-2*time.Hour).UTC().Format(time.RFC3339), nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.StatsResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		got := resp.Data
+		if got.Bucket != models.StatsBucketHour || len(got.Buckets) < 2 {
+			t.Fatalf("stats = %+v", got)
+		}
+		total := got.Total
+		if total.Calculations != 3 || total.Requested != 752 || total.Shipped != 1250 || total.Overage != 498 || total.OveragePercent != 66.22 {
+			t.Fatalf("total = %+v", total)
+		}
+		want := []models.PackUsage{{Size: 250, Count: 1}, {Size: 500, Count: 2}}
+		if len(total.Packs) != 2 || total.Packs[0] != want[0] || total.Packs[1] != want[1] {
+			t.Fatalf("packs = %+v", total.Packs)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/stats?format=csv", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+			t.Fatalf("content type = %q", ct)
+		}
+		lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+		if !strings.HasSuffix(lines[0], ",packs_250,packs_500") || !strings.HasPrefix(lines[len(lines)-1], "total,") {
+			t.Fatalf("csv = %s", rr.Body.String())
+		}
+	})
+
+	t.Run("outside the range", func(t *testing.T) {
+		rr := doJSON(t, h, http.MethodGet, "/api/stats?from=2020-01-01T00:00:00Z&to=2020-02-01T00:00:00Z&bucket=week", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data models.StatsResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Data.Total.Calculations != 0 || len(resp.Data.Buckets) != 5 {
+			t.Fatalf("stats = %+v", resp.Data)
+		}
+	})
+
+	t.Run("invalid params -> 400", func(t *testing.T) {
+		cases := []struct {
+			query string
+			want  string
+		}{
+			{"?bucket=year", "bucket must be hour, day, week or month"},
+			{"?format=xml", "format must be json or csv"},
+			{"?from=yesterday", "from must be an RFC 3339 timestamp"},
+			{"?from=2026-10-02T00:00:00Z&to=2026-10-01T00:00:00Z", "to must be after from"},
+			{"?from=2020-01-01T00:00:00Z&to=2026-01-01T00:00:00Z&bucket=hour", "range is too long for hour buckets"},
+		}
+		for _, tc := range cases {
+			rr := doJSON(t, h, http.MethodGet, "/api/stats"+tc.query, nil)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d body=%s", tc.query, rr.Code, rr.Body.String())
+			}
+			mustJSONEqual(t, rr, `{"error":{"message":"`+tc.want+`"}}`)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	httpmw "github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/middleware"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/tenant"
+)
+
+// useMemoryRepositories switches every repository to a fresh in-memory store for one test.
+func useMemoryRepositories(t *testing.T) {
+	t.Helper()
+
+	packSizes, audit := repository.PackSizes(), repository.Audit()
+	versions, products := repository.PackSetVersions(), repository.Products()
+	calculations, orders := repository.Calculations(), repository.Orders()
+	idempotency, inventory := repository.IdempotencyKeys(), repository.Inventory()
+	t.Cleanup(func() {
+		repository.SetPackSizesRepository(packSizes)
+		repository.SetAuditRepository(audit)
+		repository.SetPackSetVersionsRepository(versions)
+		repository.SetProductsRepository(products)
+		repository.SetCalculationsRepository(calculations)
+		repository.SetOrdersRepository(orders)
+		repository.SetIdempotencyRepository(idempotency)
+		repository.SetInventoryRepository(inventory)
+	})
+	repository.UseMemoryBackend()
+}
+
+func doAs(t *testing.T, h http.Handler, headers map[string]string, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, jsonBody(t, body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestTenantIsolation(t *testing.T) {
+	useMemoryRepositories(t)
+	h := http_server.NewHTTPHandler()
+	acme := map[string]string{httpmw.TenantHeader: "acme"}
+	globex := map[string]string{httpmw.TenantHeader: "globex"}
+
+	rr := doAs(t, h, acme, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 250})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var created struct{ Data models.PackSize }
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	packPath := "/api/packs/" + strconv.FormatInt(created.Data.ID, 10)
+	rr = doAs(t, h, acme, http.MethodPost, "/api/products/", models.Product{SKU: "P-1", Name: "Bolts"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create product: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	// Neither another tenant nor the default one can see or touch acme's data.
+	for _, headers := range []map[string]string{globex, nil} {
+		for _, tc := range []struct {
+			method, path string
+			body         any
+			want         int
+		}{
+			{http.MethodGet, packPath, nil, http.StatusNotFound},
+			{http.MethodPut, packPath, models.UpdatePackSizeRequest{Size: 300}, http.StatusNotFound},
+			{http.MethodDelete, packPath, nil, http.StatusNotFound},
+			{http.MethodPost, packPath + "/restore", nil, http.StatusNotFound},
+			{http.MethodGet, "/api/products/P-1", nil, http.StatusNotFound},
+			{http.MethodPut, "/api/products/P-1", models.Product{Name: "Mine"}, http.StatusNotFound},
+			{http.MethodDelete, "/api/products/P-1", nil, http.StatusNotFound},
+		} {
+			if rr := doAs(t, h, headers, tc.method, tc.path, tc.body); rr.Code != tc.want {
+				t.Fatalf("%v %s %s: expected %d, got %d body=%s", headers, tc.method, tc.path, tc.want, rr.Code, rr.Body.String())
+			}
+		}
+
+		rr := doAs(t, h, headers, http.MethodGet, "/api/packs/", nil)
+		mustJSONEqual(t, rr, `{"data":{"packs":null}}`)
+		rr = doAs(t, h, headers, http.MethodGet, "/api/audit", nil)
+		mustJSONEqual(t, rr, `{"data":{"entries":[]}}`)
+		rr = doAs(t, h, headers, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1})
+		if rr.Code == http.StatusOK {
+			t.Fatalf("%v calculate: expected no pack sizes, got body=%s", headers, rr.Body.String())
+		}
+	}
+
+	rr = doAs(t, h, acme, http.MethodGet, packPath, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("acme get: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	rr = doAs(t, h, acme, http.MethodPost, "/api/calculate", models.CalculateRequest{Quantity: 1, SKU: "P-1"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("acme calculate: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	rr = doAs(t, h, map[string]string{httpmw.TenantHeader: "not a tenant"}, http.MethodGet, "/api/packs/", nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid tenant: expected 400, got %d body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestTenantAPIKeys(t *testing.T) {
+	useMemoryRepositories(t)
+	f, err := tenant.Parse([]byte(`{"tenants":[
+		{"id":"default","api_keys":["admin-key-0123456789"]},
+		{"id":"acme","api_keys":["acme-key-0123456789"]},
+		{"id":"globex","api_keys":["globex-key-01234567"]}]}`), "json")
+	if err != nil {
+		t.Fatalf("parse tenants: %v", err)
+	}
+	httpmw.SetTenantResolver(tenant.NewResolver(f))
+	t.Cleanup(func() { httpmw.SetTenantResolver(tenant.NewResolver(nil)) })
+	h := http_server.NewHTTPHandler()
+
+	acme := map[string]string{httpmw.APIKeyHeader: "acme-key-0123456789"}
+	if rr := doAs(t, h, acme, http.MethodPost, "/api/packs/", models.CreatePackSizeRequest{Size: 250}); rr.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		path    string
+		want    int
+	}{
+		{"no key", nil, "/api/packs/", http.StatusUnauthorized},
+		{"unknown key", map[string]string{httpmw.APIKeyHeader: "wrong-key-0123456789"}, "/api/packs/", http.StatusUnauthorized},
+		{"header cannot override the key", map[string]string{
+			httpmw.APIKeyHeader: "globex-key-01234567", httpmw.TenantHeader: "acme",
+		}, "/api/packs/", http.StatusForbidden},
+		{"api keys do not open the admin endpoints", map[string]string{httpmw.APIKeyHeader: "admin-key-0123456789"}, "/api/admin/backup", http.StatusNotFound},
+		{"own data", acme, "/api/packs/", http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if rr := doAs(t, h, tc.headers, http.MethodGet, tc.path, nil); rr.Code != tc.want {
+				t.Fatalf("expected %d, got %d body=%s", tc.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	rr := doAs(t, h, map[string]string{httpmw.APIKeyHeader: "globex-key-01234567"}, http.MethodGet, "/api/packs/", nil)
+	mustJSONEqual(t, rr, `{"data":{"packs":null}}`)
+
+	// The UI itself is served without a key.
+	if rr := doAs(t, h, nil, http.MethodGet, "/", nil); rr.Code != http.StatusOK {
+		t.Fatalf("ui: expected 200, got %d", rr.Code)
+	}
+}
@@ -1,7 +1,8 @@
-async function apiFetch(path, options) {
+async function apiFetch(path, options = {}) {
+  const { headers, ...rest } = options;
   const res = await fetch(path, {
-    headers: { "Content-Type": "application/json" },
-    ...options,
+    ...rest,
+    headers: { "Content-Type": "application/json", ...tenantHeaders(), ...headers },
   });
 
   let payload = null;
@@ -18,11 +19,66 @@ async function apiFetch(path, options) {
     const msg = payload?.error?.message || `Request failed (${res.status})`;
     const err = new Error(msg);
     err.status = res.status;
+    err.details = payload?.error?.details;
     throw err;
   }
   return payload?.data;
 }
 
+// ifMatch makes updates and deletes fail with 412 if the pack size changed since it was loaded.
+function ifMatch(p) {
+  const headers = { "Content-Type": "application/json" };
+  if (p.version) headers["If-Match"] = `"${p.version}"`;
+  return headers;
+}
+
+const tenantInput = document.getElementById("tenant");
+const apiKeyInput = document.getElementById("apiKey");
+
+// tenantHeaders tell the API which tenant's data to use. Both values are remembered in this browser.
+function tenantHeaders() {
+  const headers = {};
+  const tenant = tenantInput.value.trim();
+  const apiKey = apiKeyInput.value.trim();
+  if (tenant) headers["X-Tenant"] = tenant;
+  if (apiKey) headers["X-API-Key"] = apiKey;
+  return headers;
+}
+
+// errorText adds the messages of field errors that are not shown next to an input.
+function errorText(err) {
+  const details = Array.isArray(err.details) ? err.details.filter((d) => d.field && d.message) : [];
+  if (details.length === 0) return err.message;
+  return `${err.message}: ${details.map((d) => d.message).join("; ")}`;
+}
+
+function clearFieldErrors(inputs) {
+  for (const input of inputs) {
+    input.classList.remove("invalid");
+    input.parentElement.querySelector(".field-error")?.remove();
+  }
+}
+
+// showFieldErrors puts the field errors of err next to the inputs they belong to, keyed by field
+// name, and reports whether it showed any.
+function showFieldErrors(err, inputs) {
+  clearFieldErrors(Object.values(inputs));
+  const messages = new Map();
+  for (const d of Array.isArray(err.details) ? err.details : []) {
+    const input = inputs[d.field];
+    if (!input || !d.message) continue;
+    messages.set(input, [...(messages.get(input) || []), d.message]);
+  }
+  for (const [input, msgs] of messages) {
+    input.classList.add("invalid");
+    const hint = document.createElement("span");
+    hint.className = "field-error";
+    hint.textContent = msgs.join("; ");
+    input.parentElement.appendChild(hint);
+  }
+  return messages.size > 0;
+}
+
 function setMsg(el, kind, msg) {
   el.classList.remove("ok", "err");
   if (!msg) {
@@ -33,17 +89,127 @@ function setMsg(el, kind, msg) {
   el.classList.add(kind);
 }
 
+const packSet = document.getElementById("packSet");
+const packSetList = document.getElementById("packSetList");
 const packsTbody = document.getElementById("packsTbody");
 const packsMsg = document.getElementById("packsMsg");
 const createForm = document.getElementById("createForm");
 const createSize = document.getElementById("createSize");
+const createMeta = {
+  label: document.getElementById("createLabel"),
+  supplier_sku: document.getElementById("createSupplierSku"),
+  gtin: document.getElementById("createGtin"),
+  length_mm: document.getElementById("createLength"),
+  width_mm: document.getElementById("createWidth"),
+  height_mm: document.getElementById("createHeight"),
+  weight_grams: document.getElementById("createWeight"),
+};
+const createPeriod = {
+  from: document.getElementById("createEffectiveFrom"),
+  to: document.getElementById("createEffectiveTo"),
+};
+const upcomingList = document.getElementById("upcomingList");
 const resetBtn = document.getElementById("resetBtn");
 
 const calcForm = document.getElementById("calcForm");
 const calcQty = document.getElementById("calcQty");
+const calcSku = document.getElementById("calcSku");
+const calcRef = document.getElementById("calcRef");
+const calcInStock = document.getElementById("calcInStock");
 const calcMsg = document.getElementById("calcMsg");
 const calcResult = document.getElementById("calcResult");
 
+const orderForm = document.getElementById("orderForm");
+const orderQty = document.getElementById("orderQty");
+const orderSku = document.getElementById("orderSku");
+const orderRef = document.getElementById("orderRef");
+const orderInStock = document.getElementById("orderInStock");
+const ordersMsg = document.getElementById("ordersMsg");
+const ordersTbody = document.getElementById("ordersTbody");
+
+const stockForm = document.getElementById("stockForm");
+const stockSize = document.getElementById("stockSize");
+const stockQty = document.getElementById("stockQty");
+const stockNote = document.getElementById("stockNote");
+const stockAdjustBtn = document.getElementById("stockAdjustBtn");
+const stockMsg = document.getElementById("stockMsg");
+const stockTbody = document.getElementById("stockTbody");
+
+// orderActions are the status changes offered for each order status; the API enforces the lifecycle.
+const orderActions = {
+  draft: [["confirmed", "Confirm"], ["cancelled", "Cancel"]],
+  confirmed: [["picked", "Mark picked"], ["cancelled", "Cancel"]],
+  picked: [["shipped", "Mark shipped"], ["cancelled", "Cancel"]],
+};
+
+function currentPackSet() {
+  return packSet.value.trim() || "default";
+}
+
+function packsPath(suffix = "") {
+  return `/api/pack-sets/${encodeURIComponent(currentPackSet())}/packs/${suffix}`;
+}
+
+// metadataBody turns the metadata inputs into request fields; empty inputs are left out.
+function metadataBody(inputs) {
+  const body = {};
+  for (const key of ["label", "supplier_sku", "gtin"]) {
+    const v = inputs[key].value.trim();
+    if (v) body[key] = v;
+  }
+  const dims = ["length_mm", "width_mm", "height_mm"].map((k) => inputs[k].value);
+  if (dims.some((v) => v !== "")) {
+    body.dimensions = { length_mm: Number(dims[0]), width_mm: Number(dims[1]), height_mm: Number(dims[2]) };
+  }
+  if (inputs.weight_grams.value !== "") body.weight_grams = Number(inputs.weight_grams.value);
+  return body;
+}
+
+function metadataValues(p) {
+  const d = p.dimensions || {};
+  return {
+    label: p.label || "",
+    supplier_sku: p.supplier_sku || "",
+    gtin: p.gtin || "",
+    length_mm: d.length_mm ? String(d.length_mm) : "",
+    width_mm: d.width_mm ? String(d.width_mm) : "",
+    height_mm: d.height_mm ? String(d.height_mm) : "",
+    weight_grams: p.weight_grams ? String(p.weight_grams) : "",
+  };
+}
+
+// dateValue formats a timestamp as a local date for a date input.
+function dateValue(iso) {
+  if (!iso) return "";
+  const d = new Date(iso);
+  const pad = (n) => String(n).padStart(2, "0");
+  return `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())}`;
+}
+
+// periodBody turns the effective date inputs into request fields; a date means local midnight.
+// Dates that were not changed keep the exact timestamp of p.
+function periodBody(inputs, p = {}) {
+  const body = {};
+  for (const [key, field] of [["from", "effective_from"], ["to", "effective_to"]]) {
+    const v = inputs[key].value;
+    if (!v) continue;
+    body[field] = v === dateValue(p[field]) ? p[field] : new Date(`${v}T00:00:00`).toISOString();
+  }
+  return body;
+}
+
+function metadataInput(type, value) {
+  const input = document.createElement("input");
+  input.type = type;
+  if (type === "number") {
+    input.min = "0";
+    input.step = "1";
+  }
+  input.value = value;
+  input.disabled = true;
+  return input;
+}
+
 function renderPacks(packs) {
   packsTbody.innerHTML = "";
   for (const p of packs) {
@@ -61,6 +227,40 @@ function renderPacks(packs) {
     input.disabled = true;
     tdSize.appendChild(input);
 
+    const values = metadataValues(p);
+    const meta = {};
+    const metaCells = [];
+    for (const key of ["label", "supplier_sku", "gtin"]) {
+      const td = document.createElement("td");
+      meta[key] = metadataInput("text", values[key]);
+      td.appendChild(meta[key]);
+      metaCells.push(td);
+    }
+    const tdDims = document.createElement("td");
+    const dims = document.createElement("div");
+    dims.className = "dims";
+    for (const key of ["length_mm", "width_mm", "height_mm"]) {
+      meta[key] = metadataInput("number", values[key]);
+      dims.appendChild(meta[key]);
+    }
+    tdDims.appendChild(dims);
+    metaCells.push(tdDims);
+    const tdWeight = document.createElement("td");
+    meta.weight_grams = metadataInput("number", values.weight_grams);
+    tdWeight.appendChild(meta.weight_grams);
+    metaCells.push(tdWeight);
+    const tdPeriod = document.createElement("td");
+    const periodBox = document.createElement("div");
+    periodBox.className = "dims";
+    const period = {
+      from: metadataInput("date", dateValue(p.effective_from)),
+      to: metadataInput("date", dateValue(p.effective_to)),
+    };
+    periodBox.appendChild(period.from);
+    periodBox.appendChild(period.to);
+    tdPeriod.appendChild(periodBox);
+    metaCells.push(tdPeriod);
+
     const tdActions = document.createElement("td");
     const actions = document.createElement("div");
     actions.className = "row-actions";
@@ -86,6 +286,15 @@ function renderPacks(packs) {
     let original = p.size;
     function setEditing(on) {
       input.disabled = !on;
+      if (!on) clearFieldErrors([input]);
+      for (const [key, el] of Object.entries(meta)) {
+        el.disabled = !on;
+        if (!on) el.value = values[key];
+      }
+      for (const [key, el] of Object.entries(period)) {
+        el.disabled = !on;
+        if (!on) el.value = dateValue(p[`effective_${key}`]);
+      }
       editBtn.style.display = on ? "none" : "";
       saveBtn.style.display = on ? "" : "none";
       cancelBtn.style.display = on ? "" : "none";
@@ -113,17 +322,22 @@ function renderPacks(packs) {
         setMsg(packsMsg, "err", "size must be > 0");
         return;
       }
+      // On field errors the row stays in edit mode, so the size can be corrected.
+      let keepEditing = false;
       try {
-        await apiFetch(`/api/packs/${p.id}`, {
+        await apiFetch(packsPath(p.id), {
           method: "PUT",
-          body: JSON.stringify({ size: newSize }),
+          headers: ifMatch(p),
+          body: JSON.stringify({ size: newSize, ...metadataBody(meta), ...periodBody(period, p) }),
         });
         setMsg(packsMsg, "ok", "Updated");
+        await loadPackSets();
         await loadPacks();
       } catch (err) {
-        setMsg(packsMsg, "err", err.message);
+        keepEditing = showFieldErrors(err, { size: input });
+        setMsg(packsMsg, "err", keepEditing ? err.message : errorText(err));
       } finally {
-        setEditing(false);
+        if (!keepEditing) setEditing(false);
       }
     });
 
@@ -131,8 +345,9 @@ function renderPacks(packs) {
       e.preventDefault();
       if (!confirm(`Delete pack size ${p.size}?`)) return;
       try {
-        await apiFetch(`/api/packs/${p.id}`, { method: "DELETE" });
+        await apiFetch(packsPath(p.id), { method: "DELETE", headers: ifMatch(p) });
         setMsg(packsMsg, "ok", "Deleted");
+        await loadPackSets();
         await loadPacks();
       } catch (err) {
         setMsg(packsMsg, "err", err.message);
@@ -147,6 +362,7 @@ function renderPacks(packs) {
 
     tr.appendChild(tdId);
     tr.appendChild(tdSize);
+    for (const td of metaCells) tr.appendChild(td);
     tr.appendChild(tdActions);
     packsTbody.appendChild(tr);
   }
@@ -154,13 +370,54 @@ function renderPacks(packs) {
 
 async function loadPacks() {
   try {
-    const data = await apiFetch("/api/packs/", { method: "GET" });
+    const data = await apiFetch(packsPath(), { method: "GET" });
     renderPacks(data.packs || []);
+    const upcoming = await apiFetch(packsPath("upcoming"), { method: "GET" });
+    renderUpcoming(upcoming.changes || []);
   } catch (err) {
     setMsg(packsMsg, "err", err.message);
   }
+  // The inventory lists every pack size of the set.
+  await loadStock();
 }
 
+function renderUpcoming(changes) {
+  upcomingList.innerHTML = "";
+  if (changes.length === 0) {
+    const li = document.createElement("li");
+    li.className = "muted";
+    li.textContent = "No scheduled changes.";
+    upcomingList.appendChild(li);
+    return;
+  }
+  for (const c of changes) {
+    const li = document.createElement("li");
+    const verb = c.change === "start" ? "comes into effect" : "goes out of effect";
+    li.textContent = `${new Date(c.effective_at).toLocaleString()}: pack size ${c.pack.size} ${verb}`;
+    upcomingList.appendChild(li);
+  }
+}
+
+async function loadPackSets() {
+  try {
+    const data = await apiFetch("/api/pack-sets", { method: "GET" });
+    packSetList.innerHTML = "";
+    for (const name of data.pack_sets || []) {
+      const opt = document.createElement("option");
+      opt.value = name;
+      packSetList.appendChild(opt);
+    }
+  } catch (err) {
+    setMsg(packsMsg, "err", err.message);
+  }
+}
+
+packSet.addEventListener("change", async () => {
+  setMsg(packsMsg, "", "");
+  await loadPackSets();
+  await loadPacks();
+});
+
 createForm.addEventListener("submit", async (e) => {
   e.preventDefault();
   const size = Number(createSize.value);
@@ -169,27 +426,34 @@ createForm.addEventListener("submit", async (e) => {
     return;
   }
   try {
-    await apiFetch("/api/packs/", {
+    await apiFetch(packsPath(), {
       method: "POST",
-      body: JSON.stringify({ size }),
+      body: JSON.stringify({ size, ...metadataBody(createMeta), ...periodBody(createPeriod) }),
     });
     createSize.value = "";
+    for (const el of Object.values(createMeta)) el.value = "";
+    for (const el of Object.values(createPeriod)) el.value = "";
+    clearFieldErrors([createSize]);
     setMsg(packsMsg, "ok", "Added");
+    await loadPackSets();
     await loadPacks();
+    await loadPackSets();
   } catch (err) {
-    setMsg(packsMsg, "err", err.message);
+    const shown = showFieldErrors(err, { size: createSize });
+    setMsg(packsMsg, "err", shown ? err.message : errorText(err));
   }
 });
 
 resetBtn.addEventListener("click", async (e) => {
   e.preventDefault();
-  if (!confirm("Reset pack sizes to defaults?")) return;
+  if (!confirm(`Reset pack set "${currentPackSet()}" to defaults?`)) return;
   try {
-    await apiFetch("/api/packs/reset", { method: "POST" });
+    await apiFetch(packsPath("reset"), { method: "POST" });
     setMsg(packsMsg, "ok", "Reset");
+    await loadPackSets();
     await loadPacks();
   } catch (err) {
-    setMsg(packsMsg, "err", err.message);
+    setMsg(packsMsg, "err", errorText(err));
   }
 });
 
@@ -201,20 +465,37 @@ calcForm.addEventListener("submit", async (e) => {
     setMsg(calcMsg, "err", "quantity must be > 0");
     return;
   }
+  const sku = calcSku.value.trim();
+  const body = sku ? { quantity, sku } : { quantity, pack_set: currentPackSet() };
+  const reference = calcRef.value.trim();
+  if (reference) body.reference = reference;
+  if (calcInStock.checked) body.in_stock_only = true;
   try {
     const data = await apiFetch("/api/calculate", {
       method: "POST",
-      body: JSON.stringify({ quantity }),
+      body: JSON.stringify(body),
     });
     const packs = data.packs || [];
     if (packs.length === 0) {
       setMsg(calcMsg, "ok", "No packs needed");
       return;
     }
-    setMsg(calcMsg, "ok", "Calculated");
+    setMsg(calcMsg, "ok", `Calculated (#${data.calculation_id})`);
     for (const p of packs) {
       const li = document.createElement("li");
       li.textContent = `${p.count} × ${p.size}`;
+      const details = [p.label, p.supplier_sku && `SKU ${p.supplier_sku}`, p.gtin && `GTIN ${p.gtin}`];
+      if (p.dimensions) {
+        details.push(`${p.dimensions.length_mm}×${p.dimensions.width_mm}×${p.dimensions.height_mm} mm`);
+      }
+      if (p.weight_grams) details.push(`${p.weight_grams} g`);
+      const text = details.filter(Boolean).join(", ");
+      if (text) {
+        const span = document.createElement("span");
+        span.className = "meta";
+        span.textContent = ` (${text})`;
+        li.appendChild(span);
+      }
       calcResult.appendChild(li);
     }
   } catch (err) {
@@ -222,6 +503,143 @@ calcForm.addEventListener("submit", async (e) => {
   }
 });
 
+function renderOrders(orders) {
+  ordersTbody.innerHTML = "";
+  for (const o of orders) {
+    const tr = document.createElement("tr");
+    const packs = (o.packs || []).map((p) => `${p.count} × ${p.size}`).join(", ");
+    const cells = [o.id, o.reference || "", o.quantity, packs, `${o.shipped} (+${o.overage})`, o.status];
+    for (const v of cells) {
+      const td = document.createElement("td");
+      td.textContent = v;
+      tr.appendChild(td);
+    }
+    const actions = document.createElement("td");
+    for (const [status, label] of orderActions[o.status] || []) {
+      const btn = document.createElement("button");
+      btn.className = status === "cancelled" ? "btn btn-danger" : "btn btn-secondary";
+      btn.textContent = label;
+      btn.addEventListener("click", async () => {
+        try {
+          await apiFetch(`/api/orders/${o.id}/status`, { method: "POST", body: JSON.stringify({ status }) });
+          setMsg(ordersMsg, "ok", `Order #${o.id} ${status}`);
+        } catch (err) {
+          setMsg(ordersMsg, "err", err.message);
+        }
+        await loadOrders();
+        await loadStock();
+      });
+      actions.appendChild(btn);
+    }
+    tr.appendChild(actions);
+    ordersTbody.appendChild(tr);
+  }
+}
+
+async function loadOrders() {
+  try {
+    const data = await apiFetch("/api/orders");
+    renderOrders(data.orders || []);
+  } catch (err) {
+    setMsg(ordersMsg, "err", err.message);
+  }
+}
+
+orderForm.addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const quantity = Number(orderQty.value);
+  if (!Number.isInteger(quantity) || quantity <= 0) {
+    setMsg(ordersMsg, "err", "quantity must be > 0");
+    return;
+  }
+  const sku = orderSku.value.trim();
+  const body = sku ? { quantity, sku } : { quantity, pack_set: currentPackSet() };
+  const reference = orderRef.value.trim();
+  if (reference) body.reference = reference;
+  if (orderInStock.checked) body.in_stock_only = true;
+  try {
+    const order = await apiFetch("/api/orders/", { method: "POST", body: JSON.stringify(body) });
+    setMsg(ordersMsg, "ok", `Created order #${order.id}`);
+    orderForm.reset();
+  } catch (err) {
+    setMsg(ordersMsg, "err", err.message);
+  }
+  await loadOrders();
+});
+
+function renderStock(levels) {
+  stockTbody.innerHTML = "";
+  for (const l of levels) {
+    const tr = document.createElement("tr");
+    const updated = l.updated_at ? new Date(l.updated_at).toLocaleString() : "never stocked";
+    for (const v of [l.size, l.on_hand, updated]) {
+      const td = document.createElement("td");
+      td.textContent = v;
+      tr.appendChild(td);
+    }
+    stockTbody.appendChild(tr);
+  }
+}
+
+async function loadStock() {
+  try {
+    const data = await apiFetch(`/api/inventory?pack_set=${encodeURIComponent(currentPackSet())}`);
+    renderStock(data.stock || []);
+  } catch (err) {
+    setMsg(stockMsg, "err", err.message);
+  }
+}
+
+// moveStock records a receipt or an adjustment of the packs entered in the inventory form.
+async function moveStock(kind) {
+  const size = Number(stockSize.value);
+  const packs = Number(stockQty.value);
+  if (!Number.isInteger(size) || size <= 0) {
+    setMsg(stockMsg, "err", "size must be > 0");
+    return;
+  }
+  if (!Number.isInteger(packs) || packs === 0 || (kind === "receipts" && packs < 0)) {
+    setMsg(stockMsg, "err", kind === "receipts" ? "packs must be > 0" : "packs must not be 0");
+    return;
+  }
+  const body = { pack_set: currentPackSet(), size };
+  if (kind === "receipts") body.quantity = packs;
+  else body.delta = packs;
+  const note = stockNote.value.trim();
+  if (note) body.note = note;
+  try {
+    const entry = await apiFetch(`/api/inventory/${kind}`, { method: "POST", body: JSON.stringify(body) });
+    setMsg(stockMsg, "ok", `${entry.balance} × ${entry.size} on hand`);
+    stockForm.reset();
+  } catch (err) {
+    setMsg(stockMsg, "err", err.message);
+  }
+  await loadStock();
+}
+
+stockForm.addEventListener("submit", async (e) => {
+  e.preventDefault();
+  await moveStock("receipts");
+});
+
+stockAdjustBtn.addEventListener("click", async () => {
+  await moveStock("adjustments");
+});
+
+tenantInput.value = localStorage.getItem("tenant") || "";
+apiKeyInput.value = localStorage.getItem("apiKey") || "";
+for (const [el, key] of [[tenantInput, "tenant"], [apiKeyInput, "apiKey"]]) {
+  el.addEventListener("change", async () => {
+    localStorage.setItem(key, el.value.trim());
+    setMsg(packsMsg, "", "");
+    await loadPackSets();
+    await loadPacks();
+    await loadOrders();
+  });
+}
+
+loadPackSets();
 loadPacks();
+loadOrders();
 
 
@@ -9,12 +9,19 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+const (
+	// requestTimeout bounds regular requests; admin requests transfer whole databases and get
+	// adminRequestTimeout instead.
+	requestTimeout      = 10 * time.Second
+	adminRequestTimeout = 10 * time.Minute
+)
+
 func NewHTTPHandler() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(httpmw.RequestInfo)
 	r.Use(httpmw.RequestLogger)
-	r.Use(middleware.Timeout(10 * time.Second))
 	r.Use(middleware.Recoverer)
 
 	DefineRoutes(r)
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+)
+
+// adminTokenHash is the SHA-256 of the admin token; nil until SetAdminToken configures one.
+var adminTokenHash []byte
+
+// SetAdminToken configures the bearer token admin requests must send. An empty token turns the
+// admin endpoints off.
+func SetAdminToken(token string) {
+	if token == "" {
+		adminTokenHash = nil
+		return
+	}
+	sum := sha256.Sum256([]byte(token))
+	adminTokenHash = sum[:]
+}
+
+// Admin only lets requests through that send the admin token as a bearer token. Without a
+// configured token the admin endpoints do not exist (404).
+func Admin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if adminTokenHash == nil {
+			response.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+		if !ok || subtle.ConstantTimeCompare(sum[:], adminTokenHash) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			response.WriteError(w, http.StatusUnauthorized, "invalid admin token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Deadline replaces the server's read and write timeouts for the request with d, e.g. for
+// endpoints that transfer large bodies.
+func Deadline(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rc := http.NewResponseController(w)
+			until := time.Now().Add(d)
+			for _, err := range []error{rc.SetReadDeadline(until), rc.SetWriteDeadline(until)} {
+				if err != nil && !errors.Is(err, http.ErrNotSupported) {
+					log.Warn("error extending request deadline", "err", err)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/constants"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+)
+
+const (
+	// IdempotencyKeyHeader lets clients retry a request without repeating its effect.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed for a retried request.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+const (
+	maxIdempotencyKeyLen = 255
+	// maxIdempotentBodyBytes limits the request bodies read to fingerprint a request.
+	maxIdempotentBodyBytes = 1 << 20
+)
+
+// staleClaimAfter is when a claim whose request never completed (e.g. the process died) may be
+// taken over by a retry. It outlasts the request timeout, so that running requests keep theirs.
+const staleClaimAfter = 30 * time.Second
+
+// idempotencyKeyTTL is how long a key is remembered until SetIdempotencyKeyTTL is called.
+var idempotencyKeyTTL = 24 * time.Hour
+
+// SetIdempotencyKeyTTL configures how long idempotency keys and their responses are kept.
+func SetIdempotencyKeyTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		panic("idempotency key TTL must be > 0")
+	}
+	idempotencyKeyTTL = ttl
+}
+
+// Idempotency stores the response of requests sent with an Idempotency-Key header and replays it
+// when the request is retried with the same key. Reusing a key for a different request (method,
+// path or body) is rejected with 422. Server errors and panics are not stored, so such requests
+// can be retried. It must run after Tenant, as keys are per tenant.
+func Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			response.WriteError(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				response.WriteError(w, http.StatusRequestEntityTooLarge, "request body is too large")
+				return
+			}
+			response.WriteError(w, http.StatusBadRequest, "error reading request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// The outcome is stored even if the client goes away meanwhile; that is what retries are for.
+		ctx := context.WithoutCancel(r.Context())
+		repo := repository.IdempotencyKeys()
+		started := time.Now()
+		stored, err := repo.Begin(ctx, key, requestFingerprint(r, body), started.Add(-idempotencyKeyTTL), started.Add(-staleClaimAfter))
+		switch {
+		case errors.Is(err, repository.ErrIdempotencyKeyMismatch):
+			response.WriteError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+			return
+		case errors.Is(err, repository.ErrRequestInProgress):
+			response.WriteError(w, http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+			return
+		case err != nil:
+			log.Error("error claiming idempotency key", "err", err)
+			response.WriteError(w, http.StatusInternalServerError, constants.InternalServerErrorMsg)
+			return
+		case stored != nil:
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+			if stored.ETag != "" {
+				w.Header().Set("ETag", stored.ETag)
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(stored.Status)
+			_, _ = w.Write(stored.Body)
+			return
+		}
+
+		completed := false
+		defer func() {
+			// The handler panicked; forget the key so that the request can be retried with it.
+			if !completed {
+				if err := repo.Release(ctx, key); err != nil {
+					log.Error("error releasing idempotency key", "err", err)
+				}
+			}
+		}()
+
+		rec := &bufferingRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		completed = true
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= http.StatusInternalServerError {
+			if err := repo.Release(ctx, key); err != nil {
+				log.Error("error releasing idempotency key", "err", err)
+			}
+			return
+		}
+		if err := repo.Complete(ctx, key, status, w.Header().Get("Content-Type"), w.Header().Get("ETag"), rec.body.Bytes()); err != nil {
+			log.Error("error storing idempotent response", "err", err)
+			// Without the response a retry could only be told that the request is in progress.
+			if err := repo.Release(ctx, key); err != nil {
+				log.Error("error releasing idempotency key", "err", err)
+			}
+		}
+	})
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bufferingRecorder writes the response through while keeping a copy of its status and body.
+type bufferingRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *bufferingRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *bufferingRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ActorHeader identifies who performs a request (e.g. set by an authenticating proxy).
+const ActorHeader = "X-Actor"
+
+const maxActorLen = 200
+
+// RequestInfo copies the actor and the chi request ID into the request context so that lower
+// layers (e.g. repositories writing audit records) can read them without depending on net/http.
+// The actor comes from the X-Actor header, falling back to the basic auth user name.
+func RequestInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := strings.TrimSpace(r.Header.Get(ActorHeader))
+		if actor == "" {
+			if user, _, ok := r.BasicAuth(); ok {
+				actor = strings.TrimSpace(user)
+			}
+		}
+		if utf8.RuneCountInString(actor) > maxActorLen {
+			actor = string([]rune(actor)[:maxActorLen])
+		}
+
+		ctx := requestinfo.WithRequestID(r.Context(), middleware.GetReqID(r.Context()))
+		if actor != "" {
+			ctx = requestinfo.WithActor(ctx, actor)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
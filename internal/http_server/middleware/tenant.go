@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/response"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/tenant"
+)
+
+const (
+	// TenantHeader names the tenant a request acts for.
+	TenantHeader = "X-Tenant"
+	// APIKeyHeader carries an API key from the tenants file; the key decides the tenant.
+	APIKeyHeader = "X-API-Key"
+)
+
+// tenantResolver accepts any tenant until SetTenantResolver is called.
+var tenantResolver = tenant.NewResolver(nil)
+
+// SetTenantResolver configures how requests are mapped to tenants.
+func SetTenantResolver(r *tenant.Resolver) {
+	if r == nil {
+		panic("tenant resolver must not be nil")
+	}
+	tenantResolver = r
+}
+
+// Tenant resolves the tenant of the request from its API key or X-Tenant header and stores it in
+// the request context, where repositories pick it up. Requests that cannot be mapped to a tenant
+// are rejected.
+func Tenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+		requested := strings.TrimSpace(r.Header.Get(TenantHeader))
+
+		id, err := tenantResolver.Resolve(apiKey, requested, requestinfo.DefaultTenant)
+		switch {
+		case err == nil:
+		case errors.Is(err, tenant.ErrInvalidTenant):
+			response.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, tenant.ErrAPIKeyRequired), errors.Is(err, tenant.ErrInvalidAPIKey):
+			w.Header().Set("WWW-Authenticate", `APIKey header="`+APIKeyHeader+`"`)
+			response.WriteError(w, http.StatusUnauthorized, err.Error())
+			return
+		default:
+			response.WriteError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(requestinfo.WithTenant(r.Context(), id)))
+	})
+}
@@ -13,6 +13,7 @@ type ErrorResponse struct {
 
 type ErrorBody struct {
 	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
 }
 
 type SuccessResponse struct {
@@ -25,6 +26,13 @@ func WriteError(w http.ResponseWriter, statusCode int, message string) {
 	})
 }
 
+// WriteErrorDetails writes an error with machine-readable details (e.g. per-row validation errors).
+func WriteErrorDetails(w http.ResponseWriter, statusCode int, message string, details any) {
+	WriteJSON(w, statusCode, ErrorResponse{
+		Error: ErrorBody{Message: message, Details: details},
+	})
+}
+
 func WriteSuccess(w http.ResponseWriter, statusCode int, data any) {
 	WriteJSON(w, statusCode, SuccessResponse{Data: data})
 }
@@ -2,21 +2,97 @@ package http_server
 
 import (
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/handlers"
+	httpmw "github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/middleware"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 func DefineRoutes(r chi.Router) {
-	r.Get("/", handlers.UIIndexHandler)
-	r.Get("/assets/*", handlers.UIAssetsHandler)
+	// Backups cover every tenant, so they are guarded by the admin token rather than a tenant.
+	r.Group(func(r chi.Router) {
+		r.Use(httpmw.Admin, httpmw.Deadline(adminRequestTimeout), middleware.Timeout(adminRequestTimeout))
+		r.Get("/api/admin/backup", handlers.BackupHandler)
+		r.Post("/api/admin/restore", handlers.RestoreHandler)
+	})
 
-	r.Route("/api/packs", func(r chi.Router) {
-		r.Get("/", handlers.ListPackSizesHandler)
-		r.Post("/", handlers.CreatePackSizeHandler)
-		r.Put("/{id}", handlers.UpdatePackSizeHandler)
-		r.Delete("/{id}", handlers.DeletePackSizeHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(requestTimeout))
 
-		r.Post("/reset", handlers.ResetPackSizesHandler)
-	})
+		r.Get("/", handlers.UIIndexHandler)
+		r.Get("/assets/*", handlers.UIAssetsHandler)
+
+		// Every API request acts for a single tenant.
+		r.Group(func(r chi.Router) {
+			r.Use(httpmw.Tenant)
+
+			r.Route("/api/packs", func(r chi.Router) {
+				r.Get("/", handlers.ListPackSizesHandler)
+				r.With(httpmw.Idempotency).Post("/", handlers.CreatePackSizeHandler)
+				r.Put("/", handlers.ReplacePackSizesHandler)
+				r.Get("/upcoming", handlers.ListUpcomingPackSizeChangesHandler)
+				r.Get("/{id}", handlers.GetPackSizeHandler)
+				r.Put("/{id}", handlers.UpdatePackSizeHandler)
+				r.Delete("/{id}", handlers.DeletePackSizeHandler)
+				r.Post("/{id}/restore", handlers.RestorePackSizeHandler)
+
+				r.With(httpmw.Idempotency).Post("/reset", handlers.ResetPackSizesHandler)
+				r.Get("/export", handlers.ExportPackSizesHandler)
+				r.Post("/import", handlers.ImportPackSizesHandler)
+
+				r.Get("/versions", handlers.ListPackSetVersionsHandler)
+				r.Get("/versions/{version}", handlers.GetPackSetVersionHandler)
+			})
+
+			r.Get("/api/pack-sets", handlers.ListPackSetsHandler)
+			r.Route("/api/pack-sets/{name}/packs", func(r chi.Router) {
+				r.Get("/", handlers.ListPackSizesHandler)
+				r.With(httpmw.Idempotency).Post("/", handlers.CreatePackSizeHandler)
+				r.Put("/", handlers.ReplacePackSizesHandler)
+				r.Get("/upcoming", handlers.ListUpcomingPackSizeChangesHandler)
+				r.Get("/{id}", handlers.GetPackSizeHandler)
+				r.Put("/{id}", handlers.UpdatePackSizeHandler)
+				r.Delete("/{id}", handlers.DeletePackSizeHandler)
+				r.Post("/{id}/restore", handlers.RestorePackSizeHandler)
 
-	r.Post("/api/calculate", handlers.CalculateHandler)
+				r.With(httpmw.Idempotency).Post("/reset", handlers.ResetPackSizesHandler)
+				r.Get("/export", handlers.ExportPackSizesHandler)
+				r.Post("/import", handlers.ImportPackSizesHandler)
+
+				r.Get("/versions", handlers.ListPackSetVersionsHandler)
+				r.Get("/versions/{version}", handlers.GetPackSetVersionHandler)
+			})
+
+			r.Route("/api/products", func(r chi.Router) {
+				r.Get("/", handlers.ListProductsHandler)
+				r.Post("/", handlers.CreateProductHandler)
+				r.Get("/{sku}", handlers.GetProductHandler)
+				r.Put("/{sku}", handlers.UpdateProductHandler)
+				r.Delete("/{sku}", handlers.DeleteProductHandler)
+				r.Get("/{sku}/packs", handlers.ListProductPackSizesHandler)
+			})
+
+			r.Get("/api/audit", handlers.ListAuditHandler)
+			r.Get("/api/cache/stats", handlers.CacheStatsHandler)
+
+			r.Post("/api/calculate", handlers.CalculateHandler)
+			r.Post("/api/calculate/amend", handlers.AmendCalculationHandler)
+			r.Get("/api/calculations", handlers.ListCalculationsHandler)
+			r.Get("/api/calculations/{id}", handlers.GetCalculationHandler)
+			r.Get("/api/stats", handlers.StatsHandler)
+
+			r.Route("/api/orders", func(r chi.Router) {
+				r.Get("/", handlers.ListOrdersHandler)
+				r.With(httpmw.Idempotency).Post("/", handlers.CreateOrderHandler)
+				r.Get("/{id}", handlers.GetOrderHandler)
+				r.Post("/{id}/status", handlers.UpdateOrderStatusHandler)
+			})
+
+			r.Route("/api/inventory", func(r chi.Router) {
+				r.Get("/", handlers.ListStockHandler)
+				r.Post("/receipts", handlers.ReceiveStockHandler)
+				r.Post("/adjustments", handlers.AdjustStockHandler)
+				r.Get("/ledger", handlers.ListLedgerHandler)
+			})
+		})
+	})
 }
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionReset   = "reset"
+	AuditActionRestore = "restore"
+	AuditActionReplace = "replace"
+	AuditActionImport  = "import"
+)
+
+// AuditEntry records a single pack size mutation. Before and After hold the affected pack size
+// (or the whole pack set for resets, replaces and imports) and are JSON null when not applicable.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"request_id"`
+	Action     string          `json:"action"`
+	PackSet    string          `json:"pack_set"`
+	PackSizeID *int64          `json:"pack_size_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+}
+
+// AuditFilter narrows down audit entries. Zero values mean "no filter".
+type AuditFilter struct {
+	PackSet   string
+	Action    string
+	Actor     string
+	RequestID string
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
+
+type ListAuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type RestoreResponse struct {
+	// SnapshotVersion is the schema version of the uploaded snapshot; it was migrated to SchemaVersion.
+	SnapshotVersion int `json:"snapshot_version"`
+	SchemaVersion   int `json:"schema_version"`
+}
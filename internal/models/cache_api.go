@@ -0,0 +1,14 @@
+package models
+
+// CacheStats describes the pack size listing cache.
+type CacheStats struct {
+	Enabled    bool    `json:"enabled"`
+	Hits       uint64  `json:"hits"`
+	Misses     uint64  `json:"misses"`
+	Entries    int     `json:"entries"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+}
+
+type CacheStatsResponse struct {
+	PackSizes CacheStats `json:"pack_sizes"`
+}
@@ -1,14 +1,45 @@
 package models
 
 type CalculateRequest struct {
-	Quantity int `json:"quantity"`
+	Quantity int    `json:"quantity"`
+	PackSet  string `json:"pack_set,omitempty"`
+	SKU      string `json:"sku,omitempty"`
+	// AsOf (RFC 3339) or Version select a historical pack set version instead of the live one.
+	// Only the pack sizes in effect at AsOf, or at the time of the request, are used; a future
+	// AsOf uses the live pack sizes scheduled for that time.
+	AsOf    string `json:"as_of,omitempty"`
+	Version int64  `json:"version,omitempty"`
+	// Reference is an optional order reference stored with the calculation.
+	Reference string `json:"reference,omitempty"`
+	// InStockOnly uses no more packs of a size than the inventory has on hand. It always uses the
+	// live pack sizes, so it cannot be combined with AsOf or Version.
+	InStockOnly bool `json:"in_stock_only,omitempty"`
 }
 
 type PackAllocation struct {
 	Size  int `json:"size"`
 	Count int `json:"count"`
+	// PackMetadata is filled in from the pack size in responses; it is ignored in requests.
+	PackMetadata
 }
 
 type CalculateResponse struct {
 	Packs []PackAllocation `json:"packs"`
+	// Version is the pack set version used, when the calculation was made against history.
+	Version int64 `json:"version,omitempty"`
+	// CalculationID identifies the stored calculation (see GET /api/calculations/{id}).
+	CalculationID int64 `json:"calculation_id"`
+}
+
+type AmendRequest struct {
+	Quantity int              `json:"quantity"`
+	Previous []PackAllocation `json:"previous"`
+	PackSet  string           `json:"pack_set,omitempty"`
+	SKU      string           `json:"sku,omitempty"`
+}
+
+type AmendResponse struct {
+	Packs  []PackAllocation `json:"packs"`
+	Add    []PackAllocation `json:"add"`
+	Remove []PackAllocation `json:"remove"`
 }
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Calculation is a stored result of POST /api/calculate.
+type Calculation struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	RequestID string    `json:"request_id"`
+	// Reference is the optional order reference sent with the request.
+	Reference string `json:"reference,omitempty"`
+	// PackSet is the pack set the calculation used, resolved from the SKU when one was given.
+	PackSet string `json:"pack_set"`
+	// Version is the pack set version used, when the calculation was made against history.
+	Version  int64           `json:"version,omitempty"`
+	Quantity int             `json:"quantity"`
+	Request  json.RawMessage `json:"request"`
+	// Packs is the allocation and PackSizes the normalized pack sizes it was calculated from.
+	Packs     []PackAllocation `json:"packs"`
+	PackSizes []int            `json:"pack_sizes"`
+	// Shipped is the number of items in the allocation and Overage how many of them exceed
+	// Quantity.
+	Shipped        int   `json:"shipped"`
+	Overage        int   `json:"overage"`
+	DurationMicros int64 `json:"duration_us"`
+}
+
+// CalculationFilter narrows down stored calculations. Zero values mean "no filter".
+type CalculationFilter struct {
+	Reference string
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
+
+type ListCalculationsResponse struct {
+	Calculations []Calculation `json:"calculations"`
+}
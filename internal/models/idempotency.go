@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request sent with an Idempotency-Key header.
+// Status is 0 while the request is still being processed.
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string
+	Status      int
+	ContentType string
+	ETag        string
+	Body        []byte
+	CreatedAt   time.Time
+}
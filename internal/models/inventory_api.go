@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Ledger entry kinds. Receipts and adjustments are recorded through the inventory API; orders
+// record a deduction when they are confirmed and a release when a confirmed order is cancelled.
+const (
+	LedgerKindReceipt    = "receipt"
+	LedgerKindAdjustment = "adjustment"
+	LedgerKindDeduction  = "deduction"
+	LedgerKindRelease    = "release"
+)
+
+// StockLevel is the number of packs of a size on hand. UpdatedAt is nil for sizes that were
+// never stocked.
+type StockLevel struct {
+	PackSet   string     `json:"pack_set"`
+	Size      int        `json:"size"`
+	OnHand    int        `json:"on_hand"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// LedgerEntry records a single change of the packs on hand. Quantity is negative when packs left
+// the warehouse and Balance is the number of packs on hand after the change.
+type LedgerEntry struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Actor     string    `json:"actor"`
+	RequestID string    `json:"request_id"`
+	PackSet   string    `json:"pack_set"`
+	Size      int       `json:"size"`
+	Kind      string    `json:"kind"`
+	Quantity  int       `json:"quantity"`
+	Balance   int       `json:"balance"`
+	// OrderID is the order that caused a deduction or release.
+	OrderID *int64 `json:"order_id"`
+	Note    string `json:"note,omitempty"`
+}
+
+// LedgerFilter narrows down ledger entries. Zero values mean "no filter".
+type LedgerFilter struct {
+	PackSet string
+	Size    int
+	Kind    string
+	OrderID int64
+	From    time.Time
+	To      time.Time
+	Limit   int
+}
+
+type ReceiveStockRequest struct {
+	PackSet  string `json:"pack_set,omitempty"`
+	Size     int    `json:"size"`
+	Quantity int    `json:"quantity"`
+	Note     string `json:"note,omitempty"`
+}
+
+// AdjustStockRequest corrects the packs on hand, e.g. after a stock count. Delta is negative to
+// take packs out.
+type AdjustStockRequest struct {
+	PackSet string `json:"pack_set,omitempty"`
+	Size    int    `json:"size"`
+	Delta   int    `json:"delta"`
+	Note    string `json:"note,omitempty"`
+}
+
+type ListStockResponse struct {
+	PackSet string       `json:"pack_set"`
+	Stock   []StockLevel `json:"stock"`
+}
+
+type ListLedgerResponse struct {
+	Entries []LedgerEntry `json:"entries"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Order statuses. An order starts as a draft and moves forward one step at a time; it can be
+// cancelled until it has shipped.
+const (
+	OrderStatusDraft     = "draft"
+	OrderStatusConfirmed = "confirmed"
+	OrderStatusPicked    = "picked"
+	OrderStatusShipped   = "shipped"
+	OrderStatusCancelled = "cancelled"
+)
+
+// Order is a quantity to ship together with the allocation calculated when it was created. The
+// allocation is frozen: later pack size changes do not affect it.
+type Order struct {
+	ID        int64  `json:"id"`
+	Reference string `json:"reference,omitempty"`
+	PackSet   string `json:"pack_set"`
+	SKU       string `json:"sku,omitempty"`
+	Quantity  int    `json:"quantity"`
+	Status    string `json:"status"`
+	// Packs is the frozen allocation; Shipped and Overage are derived from it.
+	Packs   []PackAllocation `json:"packs"`
+	Shipped int              `json:"shipped"`
+	Overage int              `json:"overage"`
+	// CalculationID is the calculation history entry the allocation comes from.
+	CalculationID int64     `json:"calculation_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type CreateOrderRequest struct {
+	Quantity  int    `json:"quantity"`
+	PackSet   string `json:"pack_set,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+	Reference string `json:"reference,omitempty"`
+	// InStockOnly calculates the allocation from the packs on hand, see CalculateRequest.
+	InStockOnly bool `json:"in_stock_only,omitempty"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// OrderFilter narrows down orders. Zero values mean "no filter".
+type OrderFilter struct {
+	Status    string
+	Reference string
+	Limit     int
+}
+
+type ListOrdersResponse struct {
+	Orders []Order `json:"orders"`
+}
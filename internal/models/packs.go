@@ -1,5 +1,23 @@
 package models
 
+import "time"
+
 type ResetPackSizesResponse struct {
 	Sizes []int `json:"sizes"`
 }
+
+type ListPackSetsResponse struct {
+	PackSets []string `json:"pack_sets"`
+}
+
+// PackSetVersion is an immutable snapshot of a pack set taken after a change.
+type PackSetVersion struct {
+	PackSet   string     `json:"pack_set"`
+	Version   int64      `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	Packs     []PackSize `json:"packs"`
+}
+
+type ListPackSetVersionsResponse struct {
+	Versions []PackSetVersion `json:"versions"`
+}
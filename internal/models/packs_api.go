@@ -1,8 +1,40 @@
 package models
 
+import "time"
+
 type PackSize struct {
 	ID   int64 `json:"id"`
 	Size int   `json:"size"`
+	PackMetadata
+	EffectivePeriod
+	// Version is incremented on every change and backs the pack size ETag.
+	Version   int64      `json:"version,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// PackMetadata describes the physical pack behind a size. All fields are optional.
+type PackMetadata struct {
+	Label       string `json:"label,omitempty"`
+	SupplierSKU string `json:"supplier_sku,omitempty"`
+	// GTIN is a GTIN-8, -12 (UPC-A), -13 (EAN-13) or -14 barcode with a valid check digit.
+	GTIN        string          `json:"gtin,omitempty"`
+	Dimensions  *PackDimensions `json:"dimensions,omitempty"`
+	WeightGrams int             `json:"weight_grams,omitempty"`
+}
+
+// EffectivePeriod limits when calculations use a pack size: from EffectiveFrom (inclusive) until
+// EffectiveTo (exclusive). A missing bound leaves that end of the period open, so a pack size
+// without either is always in effect.
+type EffectivePeriod struct {
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+}
+
+// PackDimensions are the outer dimensions of a pack in millimetres.
+type PackDimensions struct {
+	LengthMM int `json:"length_mm"`
+	WidthMM  int `json:"width_mm"`
+	HeightMM int `json:"height_mm"`
 }
 
 type ListPackSizesResponse struct {
@@ -11,8 +43,68 @@ type ListPackSizesResponse struct {
 
 type CreatePackSizeRequest struct {
 	Size int `json:"size"`
+	PackMetadata
+	EffectivePeriod
 }
 
+// UpdatePackSizeRequest replaces the size, metadata and effective period of a pack size; fields
+// that are left out are cleared.
 type UpdatePackSizeRequest struct {
 	Size int `json:"size"`
+	PackMetadata
+	EffectivePeriod
+}
+
+const (
+	PackSizeChangeStart = "start"
+	PackSizeChangeEnd   = "end"
+)
+
+// PackSizeChange is a scheduled change to a pack set: at EffectiveAt the pack size comes into
+// effect (PackSizeChangeStart) or goes out of effect (PackSizeChangeEnd).
+type PackSizeChange struct {
+	EffectiveAt time.Time `json:"effective_at"`
+	Change      string    `json:"change"`
+	Pack        PackSize  `json:"pack"`
+}
+
+type ListPackSizeChangesResponse struct {
+	Changes []PackSizeChange `json:"changes"`
+}
+
+// ReplacePackSizesRequest replaces all pack sizes of a pack set at once.
+type ReplacePackSizesRequest struct {
+	Sizes []int `json:"sizes"`
+}
+
+// PackSizesDiff describes how a bulk replace changed a pack set.
+type PackSizesDiff struct {
+	Added   []PackSize `json:"added"`
+	Removed []PackSize `json:"removed"`
+	Kept    []PackSize `json:"kept"`
+}
+
+const (
+	ImportModeMerge   = "merge"
+	ImportModeReplace = "replace"
+)
+
+type ImportPackSizesResponse struct {
+	Mode   string `json:"mode"`
+	DryRun bool   `json:"dry_run"`
+	PackSizesDiff
+}
+
+// FieldError reports why the value of a request field was rejected, so that clients can show the
+// message next to the input.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ImportRowError reports why a single row of an import was rejected. Rows are 1-based: for CSV
+// they are line numbers (the header is row 1), for JSON and YAML the position in the packs list.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
 }
@@ -0,0 +1,26 @@
+package models
+
+type Product struct {
+	ID          int64  `json:"id"`
+	SKU         string `json:"sku"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	PackSet     string `json:"pack_set"`
+}
+
+type ListProductsResponse struct {
+	Products []Product `json:"products"`
+}
+
+type CreateProductRequest struct {
+	SKU         string `json:"sku"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	PackSet     string `json:"pack_set"`
+}
+
+type UpdateProductRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	PackSet     string `json:"pack_set"`
+}
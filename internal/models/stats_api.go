@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Stats bucket sizes. Buckets start at UTC boundaries; weeks start on Monday.
+const (
+	StatsBucketHour  = "hour"
+	StatsBucketDay   = "day"
+	StatsBucketWeek  = "week"
+	StatsBucketMonth = "month"
+)
+
+// StatsBucket aggregates the calculations made in [Start, End).
+type StatsBucket struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Calculations int       `json:"calculations"`
+	Requested    int64     `json:"quantity_requested"`
+	Shipped      int64     `json:"quantity_shipped"`
+	Overage      int64     `json:"overage"`
+	// OveragePercent is Overage relative to Requested.
+	OveragePercent float64 `json:"overage_percent"`
+	// Packs counts the packs used per size, ordered by size.
+	Packs            []PackUsage `json:"packs"`
+	LatencyP50Micros int64       `json:"latency_p50_us"`
+	LatencyP95Micros int64       `json:"latency_p95_us"`
+}
+
+type PackUsage struct {
+	Size  int   `json:"size"`
+	Count int64 `json:"count"`
+}
+
+type StatsResponse struct {
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to"`
+	Bucket  string        `json:"bucket"`
+	Buckets []StatsBucket `json:"buckets"`
+	// Total aggregates the whole range.
+	Total StatsBucket `json:"total"`
+}
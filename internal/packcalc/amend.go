@@ -0,0 +1,206 @@
+package packcalc
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+var ErrInvalidAllocation = errors.New("invalid allocation")
+
+// maxKeptChoiceBits bounds the memory Amend needs to reconstruct the kept packs, one bit per
+// bundle of previous packs and sum (64 MiB). Larger problems fail with ErrQuantityTooLarge.
+const maxKeptChoiceBits = 1 << 29
+
+// Amendment describes how an existing allocation changes to fulfil a new quantity.
+type Amendment struct {
+	// Packs is the resulting allocation (kept + added packs).
+	Packs []models.PackAllocation
+	// Add lists the packs that need to be added to the previous allocation.
+	Add []models.PackAllocation
+	// Remove lists the packs that need to be taken out of the previous allocation.
+	Remove []models.PackAllocation
+}
+
+// Amend recalculates an allocation for quantity starting from a previous (possibly already picked)
+// allocation. The resulting allocation ships exactly as many items as a fresh Calculate would
+// (least overage), and among those it minimizes:
+// 1) the number of changed packs (added + removed) and then
+// 2) the total number of packs.
+//
+// Previous packs whose size is no longer configured may be kept, but new packs are only added
+// from packSizes. All returned lists are sorted by Size descending and contain only Count > 0.
+func Amend(previous []models.PackAllocation, quantity int, packSizes []models.PackSize) (*Amendment, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if quantity > maxExactSumForDP {
+		return nil, ErrQuantityTooLarge
+	}
+
+	sizes := make([]int, 0, len(packSizes))
+	for _, p := range packSizes {
+		sizes = append(sizes, p.Size)
+	}
+	sizes, err := normalizePackSizes(sizes)
+	if err != nil {
+		return nil, err
+	}
+	if len(sizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+
+	prev, err := normalizeAllocation(previous)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := minimalShippedAtLeast(quantity, sizes)
+	if err != nil {
+		return nil, err
+	}
+	if target > maxExactSumForDP {
+		return nil, ErrQuantityTooLarge
+	}
+
+	kept, bundles, err := maxKept(target, prev)
+	if err != nil {
+		return nil, err
+	}
+	dp, prevIdx, prevSize := minPacksTable(target, sizes)
+
+	prevCount := 0
+	for _, a := range prev {
+		prevCount += a.Count
+	}
+
+	// Pick the kept sum that minimizes (changes, total packs); prefer keeping more items on ties.
+	bestSum, bestChanges, bestPacks := -1, 0, 0
+	for v := target; v >= 0; v-- {
+		if kept[v] < 0 || dp[target-v] == unreachable {
+			continue
+		}
+		changes := prevCount - int(kept[v]) + dp[target-v]
+		packs := int(kept[v]) + dp[target-v]
+		if bestSum < 0 || changes < bestChanges || (changes == bestChanges && packs < bestPacks) {
+			bestSum, bestChanges, bestPacks = v, changes, packs
+		}
+	}
+	if bestSum < 0 {
+		return nil, ErrNoPackSizes
+	}
+
+	final, err := reconstructPacks(target-bestSum, prevIdx, prevSize)
+	if err != nil {
+		return nil, err
+	}
+	for size, count := range reconstructKept(bestSum, bundles) {
+		final[size] += count
+	}
+
+	before := make(map[int]int, len(prev))
+	for _, a := range prev {
+		before[a.Size] = a.Count
+	}
+	add := make(map[int]int)
+	remove := make(map[int]int)
+	for size, count := range final {
+		if d := count - before[size]; d > 0 {
+			add[size] = d
+		}
+	}
+	for size, count := range before {
+		if d := count - final[size]; d > 0 {
+			remove[size] = d
+		}
+	}
+
+	return &Amendment{
+		Packs:  allocationFromCounts(final),
+		Add:    allocationFromCounts(add),
+		Remove: allocationFromCounts(remove),
+	}, nil
+}
+
+// normalizeAllocation validates an allocation and merges duplicate sizes.
+// The result is sorted by Size descending.
+func normalizeAllocation(in []models.PackAllocation) ([]models.PackAllocation, error) {
+	counts := make(map[int]int, len(in))
+	for _, a := range in {
+		if a.Size <= 0 || a.Count < 0 {
+			return nil, ErrInvalidAllocation
+		}
+		if counts[a.Size]+a.Count > maxExactSumForDP/a.Size {
+			return nil, ErrQuantityTooLarge
+		}
+		counts[a.Size] += a.Count
+	}
+	return allocationFromCounts(counts), nil
+}
+
+// keptBundle is a bundle of previous packs of one size; took marks the sums whose maximum
+// uses it.
+type keptBundle struct {
+	size, count int
+	took        []uint64
+}
+
+// maxKept runs a bounded knapsack over the previous allocation. It returns, for every sum in
+// [0, maxSum], the maximum number of previous packs that add up to exactly that sum (-1 when
+// unreachable), and the bundles reconstructKept needs to tell which packs those are.
+func maxKept(maxSum int, prev []models.PackAllocation) ([]int32, []keptBundle, error) {
+	// Binary splitting turns the bounded item into O(log Count) 0/1 items. Packs beyond maxSum
+	// can never be kept.
+	var bundles []keptBundle
+	for _, a := range prev {
+		for remaining, chunk := min(a.Count, maxSum/a.Size), 1; remaining > 0; chunk *= 2 {
+			chunk = min(chunk, remaining)
+			remaining -= chunk
+			bundles = append(bundles, keptBundle{size: a.Size, count: chunk})
+		}
+	}
+	if len(bundles)*(maxSum+1) > maxKeptChoiceBits {
+		return nil, nil, ErrQuantityTooLarge
+	}
+
+	kept := make([]int32, maxSum+1)
+	for i := 1; i <= maxSum; i++ {
+		kept[i] = -1
+	}
+	for b := range bundles {
+		bu := &bundles[b]
+		bu.took = make([]uint64, maxSum/64+1)
+		w := bu.count * bu.size
+		for v := maxSum; v >= w; v-- {
+			if kept[v-w] >= 0 && kept[v-w]+int32(bu.count) > kept[v] {
+				kept[v] = kept[v-w] + int32(bu.count)
+				bu.took[v/64] |= 1 << (v % 64)
+			}
+		}
+	}
+	return kept, bundles, nil
+}
+
+// reconstructKept walks the bundles of maxKept backwards and returns map[size]count of kept packs.
+func reconstructKept(sum int, bundles []keptBundle) map[int]int {
+	out := make(map[int]int)
+	for b := len(bundles) - 1; b >= 0 && sum > 0; b-- {
+		if bundles[b].took[sum/64]&(1<<(sum%64)) != 0 {
+			out[bundles[b].size] += bundles[b].count
+			sum -= bundles[b].count * bundles[b].size
+		}
+	}
+	return out
+}
+
+func allocationFromCounts(counts map[int]int) []models.PackAllocation {
+	out := make([]models.PackAllocation, 0, len(counts))
+	for size, count := range counts {
+		if count > 0 {
+			out = append(out, models.PackAllocation{Size: size, Count: count})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Size > out[j].Size })
+	return out
+}
@@ -0,0 +1,182 @@
+package packcalc
+
+import (
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func sumAllocation(alloc []models.PackAllocation) int {
+	total := 0
+	for _, a := range alloc {
+		total += a.Size * a.Count
+	}
+	return total
+}
+
+func allocationsEqual(a, b []models.PackAllocation) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAmend_InvalidInputs(t *testing.T) {
+	packs := []models.PackSize{{Size: 250}, {Size: 500}}
+
+	t.Run("quantity <= 0", func(t *testing.T) {
+		if _, err := Amend(nil, 0, packs); err != ErrInvalidQuantity {
+			t.Fatalf("expected ErrInvalidQuantity, got %v", err)
+		}
+	})
+
+	t.Run("no pack sizes", func(t *testing.T) {
+		if _, err := Amend(nil, 1, nil); err != ErrNoPackSizes {
+			t.Fatalf("expected ErrNoPackSizes, got %v", err)
+		}
+	})
+
+	t.Run("invalid previous allocation", func(t *testing.T) {
+		if _, err := Amend([]models.PackAllocation{{Size: 0, Count: 1}}, 1, packs); err != ErrInvalidAllocation {
+			t.Fatalf("expected ErrInvalidAllocation, got %v", err)
+		}
+		if _, err := Amend([]models.PackAllocation{{Size: 250, Count: -1}}, 1, packs); err != ErrInvalidAllocation {
+			t.Fatalf("expected ErrInvalidAllocation, got %v", err)
+		}
+	})
+
+	t.Run("quantity too large", func(t *testing.T) {
+		if _, err := Amend(nil, 50_000_000, packs); err != ErrQuantityTooLarge {
+			t.Fatalf("expected ErrQuantityTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("too many previous packs to track", func(t *testing.T) {
+		var previous []models.PackAllocation
+		for size := 1; size <= 300; size++ {
+			previous = append(previous, models.PackAllocation{Size: size, Count: 1000})
+		}
+		if _, err := Amend(previous, 1_990_000, packs); err != ErrQuantityTooLarge {
+			t.Fatalf("expected ErrQuantityTooLarge, got %v", err)
+		}
+	})
+}
+
+func TestAmend_SpecificCases(t *testing.T) {
+	defaults := []models.PackSize{{Size: 250}, {Size: 500}, {Size: 1000}, {Size: 2000}, {Size: 5000}}
+
+	cases := []struct {
+		name       string
+		previous   []models.PackAllocation
+		qty        int
+		packs      []models.PackSize
+		wantPacks  []models.PackAllocation
+		wantAdd    []models.PackAllocation
+		wantRemove []models.PackAllocation
+	}{
+		{
+			name:       "bump keeps picked packs",
+			previous:   []models.PackAllocation{{Size: 5000, Count: 2}, {Size: 2000, Count: 1}, {Size: 250, Count: 1}},
+			qty:        12_600,
+			packs:      defaults,
+			wantPacks:  []models.PackAllocation{{Size: 5000, Count: 2}, {Size: 2000, Count: 1}, {Size: 500, Count: 1}, {Size: 250, Count: 1}},
+			wantAdd:    []models.PackAllocation{{Size: 500, Count: 1}},
+			wantRemove: []models.PackAllocation{},
+		},
+		{
+			name:       "adding beats swapping",
+			previous:   []models.PackAllocation{{Size: 500, Count: 1}},
+			qty:        1000,
+			packs:      defaults,
+			wantPacks:  []models.PackAllocation{{Size: 500, Count: 2}},
+			wantAdd:    []models.PackAllocation{{Size: 500, Count: 1}},
+			wantRemove: []models.PackAllocation{},
+		},
+		{
+			name:       "decrease removes packs",
+			previous:   []models.PackAllocation{{Size: 5000, Count: 2}, {Size: 2000, Count: 1}, {Size: 250, Count: 1}},
+			qty:        5100,
+			packs:      defaults,
+			wantPacks:  []models.PackAllocation{{Size: 5000, Count: 1}, {Size: 250, Count: 1}},
+			wantAdd:    []models.PackAllocation{},
+			wantRemove: []models.PackAllocation{{Size: 5000, Count: 1}, {Size: 2000, Count: 1}},
+		},
+		{
+			name:       "unchanged quantity is a no-op",
+			previous:   []models.PackAllocation{{Size: 250, Count: 1}},
+			qty:        1,
+			packs:      defaults,
+			wantPacks:  []models.PackAllocation{{Size: 250, Count: 1}},
+			wantAdd:    []models.PackAllocation{},
+			wantRemove: []models.PackAllocation{},
+		},
+		{
+			name:       "empty previous behaves like calculate",
+			previous:   nil,
+			qty:        12_001,
+			packs:      defaults,
+			wantPacks:  []models.PackAllocation{{Size: 5000, Count: 2}, {Size: 2000, Count: 1}, {Size: 250, Count: 1}},
+			wantAdd:    []models.PackAllocation{{Size: 5000, Count: 2}, {Size: 2000, Count: 1}, {Size: 250, Count: 1}},
+			wantRemove: []models.PackAllocation{},
+		},
+		{
+			name:       "keeps retired pack size",
+			previous:   []models.PackAllocation{{Size: 300, Count: 1}},
+			qty:        550,
+			packs:      []models.PackSize{{Size: 100}, {Size: 250}},
+			wantPacks:  []models.PackAllocation{{Size: 300, Count: 1}, {Size: 250, Count: 1}},
+			wantAdd:    []models.PackAllocation{{Size: 250, Count: 1}},
+			wantRemove: []models.PackAllocation{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Amend(tc.previous, tc.qty, tc.packs)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !allocationsEqual(got.Packs, tc.wantPacks) {
+				t.Fatalf("unexpected packs; got=%+v expected=%+v", got.Packs, tc.wantPacks)
+			}
+			if !allocationsEqual(got.Add, tc.wantAdd) {
+				t.Fatalf("unexpected add; got=%+v expected=%+v", got.Add, tc.wantAdd)
+			}
+			if !allocationsEqual(got.Remove, tc.wantRemove) {
+				t.Fatalf("unexpected remove; got=%+v expected=%+v", got.Remove, tc.wantRemove)
+			}
+		})
+	}
+}
+
+func TestAmend_ShipsSameTotalAsCalculate(t *testing.T) {
+	resetCalculatorToDefault(t)
+
+	packs := []models.PackSize{{Size: 23}, {Size: 31}, {Size: 53}}
+	previous := []models.PackAllocation{{Size: 53, Count: 40}, {Size: 23, Count: 3}}
+
+	for _, qty := range []int{1, 100, 2_000, 2_189, 5_000} {
+		fresh, err := Calculate(qty, packs)
+		if err != nil {
+			t.Fatalf("qty=%d: unexpected calculate err: %v", qty, err)
+		}
+		got, err := Amend(previous, qty, packs)
+		if err != nil {
+			t.Fatalf("qty=%d: unexpected amend err: %v", qty, err)
+		}
+		if sumAllocation(got.Packs) != sumAllocation(fresh) {
+			t.Fatalf("qty=%d: amended total=%d, fresh total=%d", qty, sumAllocation(got.Packs), sumAllocation(fresh))
+		}
+		if sumAllocation(previous)+sumAllocation(got.Add)-sumAllocation(got.Remove) != sumAllocation(got.Packs) {
+			t.Fatalf("qty=%d: delta does not reconcile; got=%+v", qty, got)
+		}
+		if !isSortedDesc(got.Packs) || !isSortedDesc(got.Add) || !isSortedDesc(got.Remove) {
+			t.Fatalf("qty=%d: expected size-descending output, got %+v", qty, got)
+		}
+	}
+}
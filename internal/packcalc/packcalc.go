@@ -184,13 +184,24 @@ func minimalShippedAtLeast(quantity int, sizes []int) (int, error) {
 // minPacksForExactSum computes the minimum number of packs needed to reach exactSum.
 // It returns a map[size]count allocation.
 func minPacksForExactSum(exactSum int, sizes []int) (map[int]int, error) {
-	const inf = int(^uint(0) >> 1)
+	dp, prevIdx, prevSize := minPacksTable(exactSum, sizes)
+	if dp[exactSum] == unreachable {
+		return nil, fmt.Errorf("no exact solution for %d", exactSum)
+	}
+	return reconstructPacks(exactSum, prevIdx, prevSize)
+}
 
-	dp := make([]int, exactSum+1)
-	prevIdx := make([]int, exactSum+1)
-	prevSize := make([]int, exactSum+1)
-	for i := 1; i <= exactSum; i++ {
-		dp[i] = inf
+const unreachable = int(^uint(0) >> 1)
+
+// minPacksTable computes, for every sum in [0, maxSum], the minimum number of packs needed to
+// reach it exactly (unreachable sums are marked with unreachable) together with the back-pointers
+// needed by reconstructPacks.
+func minPacksTable(maxSum int, sizes []int) (dp, prevIdx, prevSize []int) {
+	dp = make([]int, maxSum+1)
+	prevIdx = make([]int, maxSum+1)
+	prevSize = make([]int, maxSum+1)
+	for i := 1; i <= maxSum; i++ {
+		dp[i] = unreachable
 		prevIdx[i] = -1
 		prevSize[i] = 0
 	}
@@ -201,13 +212,13 @@ func minPacksForExactSum(exactSum int, sizes []int) (map[int]int, error) {
 	sizesDesc := append([]int(nil), sizes...)
 	sort.Sort(sort.Reverse(sort.IntSlice(sizesDesc)))
 
-	for i := 0; i <= exactSum; i++ {
-		if dp[i] == inf {
+	for i := 0; i <= maxSum; i++ {
+		if dp[i] == unreachable {
 			continue
 		}
 		for _, s := range sizesDesc {
 			j := i + s
-			if j > exactSum {
+			if j > maxSum {
 				continue
 			}
 			cand := dp[i] + 1
@@ -218,16 +229,16 @@ func minPacksForExactSum(exactSum int, sizes []int) (map[int]int, error) {
 			}
 		}
 	}
+	return dp, prevIdx, prevSize
+}
 
-	if dp[exactSum] == inf {
-		return nil, fmt.Errorf("no exact solution for %d", exactSum)
-	}
-
-	out := make(map[int]int, len(sizes))
-	for cur := exactSum; cur > 0; {
+// reconstructPacks walks the back-pointers produced by minPacksTable from sum down to 0.
+func reconstructPacks(sum int, prevIdx, prevSize []int) (map[int]int, error) {
+	out := make(map[int]int)
+	for cur := sum; cur > 0; {
 		s := prevSize[cur]
 		if s == 0 || prevIdx[cur] < 0 {
-			return nil, fmt.Errorf("failed to reconstruct solution for %d", exactSum)
+			return nil, fmt.Errorf("failed to reconstruct solution for %d", sum)
 		}
 		out[s]++
 		cur = prevIdx[cur]
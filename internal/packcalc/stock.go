@@ -0,0 +1,129 @@
+package packcalc
+
+import (
+	"errors"
+	"math"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+// ErrInsufficientStock is returned when the packs in stock cannot fulfil a quantity.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// maxStockChoiceBits bounds the memory CalculateInStock needs to reconstruct an allocation, one
+// bit per bundle of packs and sum (64 MiB). Larger problems fail with ErrQuantityTooLarge.
+const maxStockChoiceBits = 1 << 29
+
+// CalculateInStock works like Calculate, but only uses the packs in stock: stock maps a pack size
+// to the number of packs on hand. Among the allocations stock allows it minimizes:
+// 1) total items shipped and then
+// 2) number of packs.
+//
+// The returned list is sorted by Size descending and contains only allocations with Count > 0.
+// Besides the quantity, the work grows with the number of sizes and the log of their stock; see
+// maxStockChoiceBits.
+func CalculateInStock(quantity int, packSizes []models.PackSize, stock map[int]int) ([]models.PackAllocation, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if quantity > maxExactSumForDP {
+		return nil, ErrQuantityTooLarge
+	}
+
+	sizes := make([]int, 0, len(packSizes))
+	for _, p := range packSizes {
+		sizes = append(sizes, p.Size)
+	}
+	sizes, err := normalizePackSizes(sizes)
+	if err != nil {
+		return nil, err
+	}
+	if len(sizes) == 0 {
+		return nil, ErrNoPackSizes
+	}
+
+	var inStock []int
+	for _, s := range sizes {
+		if stock[s] > 0 {
+			inStock = append(inStock, s)
+		}
+	}
+	if len(inStock) == 0 {
+		return nil, ErrInsufficientStock
+	}
+
+	// A minimal allocation ships less than quantity plus the largest pack, as otherwise any of
+	// its packs could be left out. More packs of a size than fit below that bound are never used.
+	upper := quantity + inStock[len(inStock)-1] - 1
+	limits := make([]int, len(inStock))
+	available := 0
+	for i, s := range inStock {
+		limits[i] = min(stock[s], upper/s)
+		available += limits[i] * s
+	}
+	if available < quantity {
+		return nil, ErrInsufficientStock
+	}
+	upper = min(upper, available)
+	if upper > maxExactSumForDP {
+		return nil, ErrQuantityTooLarge
+	}
+
+	// Up to limit packs of a size are split into bundles of 1, 2, 4, ... packs, so that any count
+	// up to the limit is a choice of bundles and the allocation can be reconstructed from one bit
+	// per bundle and sum.
+	type bundle struct{ size, count int }
+	var bundles []bundle
+	for i, s := range inStock {
+		for k, rest := 1, limits[i]; rest > 0; k *= 2 {
+			c := min(k, rest)
+			bundles = append(bundles, bundle{s, c})
+			rest -= c
+		}
+	}
+	if len(bundles)*(upper+1) > maxStockChoiceBits {
+		return nil, ErrQuantityTooLarge
+	}
+
+	// dp[sum] is the minimum number of packs of the bundles processed so far that reach sum exactly;
+	// took[b] marks the sums whose minimum uses bundle b.
+	dp := make([]int32, upper+1)
+	for sum := 1; sum <= upper; sum++ {
+		dp[sum] = math.MaxInt32
+	}
+	took := make([][]uint64, len(bundles))
+	for b, bu := range bundles {
+		took[b] = make([]uint64, upper/64+1)
+		w := bu.size * bu.count
+		for sum := upper; sum >= w; sum-- {
+			if p := dp[sum-w]; p != math.MaxInt32 && p+int32(bu.count) < dp[sum] {
+				dp[sum] = p + int32(bu.count)
+				took[b][sum/64] |= 1 << (sum % 64)
+			}
+		}
+	}
+
+	sum := quantity
+	for sum <= upper && dp[sum] == math.MaxInt32 {
+		sum++
+	}
+	if sum > upper {
+		return nil, ErrInsufficientStock
+	}
+
+	counts := make(map[int]int, len(inStock))
+	for b := len(bundles) - 1; b >= 0; b-- {
+		if took[b][sum/64]&(1<<(sum%64)) != 0 {
+			counts[bundles[b].size] += bundles[b].count
+			sum -= bundles[b].size * bundles[b].count
+		}
+	}
+
+	out := make([]models.PackAllocation, 0, len(counts))
+	for i := len(inStock) - 1; i >= 0; i-- { // descending
+		if c := counts[inStock[i]]; c > 0 {
+			out = append(out, models.PackAllocation{Size: inStock[i], Count: c})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,150 @@
+package packcalc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func TestCalculateInStock_InvalidInputs(t *testing.T) {
+	packs := []models.PackSize{{Size: 250}, {Size: 500}}
+	stock := map[int]int{250: 10, 500: 10}
+
+	if _, err := CalculateInStock(0, packs, stock); err != ErrInvalidQuantity {
+		t.Fatalf("expected ErrInvalidQuantity, got %v", err)
+	}
+	if _, err := CalculateInStock(1, nil, stock); err != ErrNoPackSizes {
+		t.Fatalf("expected ErrNoPackSizes, got %v", err)
+	}
+	if _, err := CalculateInStock(1, packs, nil); err != ErrInsufficientStock {
+		t.Fatalf("expected ErrInsufficientStock without stock, got %v", err)
+	}
+	if _, err := CalculateInStock(7501, packs, stock); err != ErrInsufficientStock {
+		t.Fatalf("expected ErrInsufficientStock above stock, got %v", err)
+	}
+	if _, err := CalculateInStock(50_000_000, packs, stock); err != ErrQuantityTooLarge {
+		t.Fatalf("expected ErrQuantityTooLarge, got %v", err)
+	}
+
+	// Hundreds of well stocked sizes would need gigabytes to reconstruct the allocation.
+	var many []models.PackSize
+	manyStock := map[int]int{}
+	for size := 1; size <= 300; size++ {
+		many = append(many, models.PackSize{Size: size})
+		manyStock[size] = 1_000_000
+	}
+	if _, err := CalculateInStock(1_999_000, many, manyStock); err != ErrQuantityTooLarge {
+		t.Fatalf("expected ErrQuantityTooLarge for many sizes, got %v", err)
+	}
+}
+
+func TestCalculateInStock_SpecificCases(t *testing.T) {
+	packs := []models.PackSize{{Size: 250}, {Size: 500}, {Size: 1000}, {Size: 2000}, {Size: 5000}}
+
+	tests := []struct {
+		name     string
+		quantity int
+		stock    map[int]int
+		want     []models.PackAllocation
+	}{
+		{
+			name:     "plenty of stock matches Calculate",
+			quantity: 12001,
+			stock:    map[int]int{250: 100, 500: 100, 1000: 100, 2000: 100, 5000: 100},
+			want:     []models.PackAllocation{{Size: 5000, Count: 2}, {Size: 2000, Count: 1}, {Size: 250, Count: 1}},
+		},
+		{
+			name:     "out of the best pack",
+			quantity: 12001,
+			stock:    map[int]int{250: 100, 500: 100, 1000: 100, 2000: 100},
+			want:     []models.PackAllocation{{Size: 2000, Count: 6}, {Size: 250, Count: 1}},
+		},
+		{
+			name:     "small packs running out ships more",
+			quantity: 251,
+			stock:    map[int]int{250: 1, 1000: 1},
+			want:     []models.PackAllocation{{Size: 1000, Count: 1}},
+		},
+		{
+			name:     "everything in stock",
+			quantity: 1000,
+			stock:    map[int]int{250: 2, 500: 1},
+			want:     []models.PackAllocation{{Size: 500, Count: 1}, {Size: 250, Count: 2}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CalculateInStock(tc.quantity, packs, tc.stock)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !allocationsEqual(got, tc.want) {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// bruteForceInStock enumerates every allocation stock allows and returns the minimal shipped
+// total and pack count, or ok=false when none reaches quantity.
+func bruteForceInStock(quantity int, sizes []int, stock map[int]int) (shipped, packs int, ok bool) {
+	var walk func(i, sum, count int)
+	walk = func(i, sum, count int) {
+		if i == len(sizes) {
+			if sum >= quantity && (!ok || sum < shipped || sum == shipped && count < packs) {
+				shipped, packs, ok = sum, count, true
+			}
+			return
+		}
+		for c := 0; c <= stock[sizes[i]]; c++ {
+			walk(i+1, sum+c*sizes[i], count+c)
+		}
+	}
+	walk(0, 0, 0)
+	return shipped, packs, ok
+}
+
+func TestCalculateInStock_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(50))
+	for i := 0; i < 300; i++ {
+		var packs []models.PackSize
+		stock := map[int]int{}
+		for n := 1 + rng.Intn(3); len(packs) < n; {
+			size := 1 + rng.Intn(40)
+			if _, dup := stock[size]; dup {
+				continue
+			}
+			packs = append(packs, models.PackSize{Size: size})
+			stock[size] = rng.Intn(5)
+		}
+		sizes := make([]int, len(packs))
+		for j, p := range packs {
+			sizes[j] = p.Size
+		}
+		quantity := 1 + rng.Intn(150)
+
+		got, err := CalculateInStock(quantity, packs, stock)
+		shipped, count, ok := bruteForceInStock(quantity, sizes, stock)
+		if !ok {
+			if err != ErrInsufficientStock {
+				t.Fatalf("q=%d sizes=%v stock=%v: expected ErrInsufficientStock, got %+v, %v", quantity, sizes, stock, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("q=%d sizes=%v stock=%v: unexpected error: %v", quantity, sizes, stock, err)
+		}
+		gotPacks := 0
+		for _, a := range got {
+			if a.Count > stock[a.Size] {
+				t.Fatalf("q=%d stock=%v: %+v uses more packs than in stock", quantity, stock, got)
+			}
+			gotPacks += a.Count
+		}
+		if sumAllocation(got) != shipped || gotPacks != count {
+			t.Fatalf("q=%d sizes=%v stock=%v: got %+v (%d items, %d packs), want %d items, %d packs",
+				quantity, sizes, stock, got, sumAllocation(got), gotPacks, shipped, count)
+		}
+	}
+}
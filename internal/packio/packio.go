@@ -0,0 +1,305 @@
+// Package packio reads and writes pack sizes in the file formats used for import and export.
+//
+// CSV files have a header row with a "size" column (other columns are ignored). JSON and YAML
+// files hold a "packs" list of objects with a "size" field, e.g. {"packs":[{"size":250}]}.
+package packio
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// InvalidError is returned by Decode when the input cannot be imported. Rows lists the rejected
+// rows, if the problem is with individual rows rather than the whole file.
+type InvalidError struct {
+	Message string
+	Rows    []models.ImportRowError
+}
+
+func (e *InvalidError) Error() string {
+	return e.Message
+}
+
+type document struct {
+	Packs []packRow `json:"packs" yaml:"packs"`
+}
+
+type packRow struct {
+	Size int `json:"size" yaml:"size"`
+}
+
+// ParseFormat normalizes a format name; it returns "" for unsupported formats.
+func ParseFormat(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case FormatCSV:
+		return FormatCSV
+	case FormatJSON:
+		return FormatJSON
+	case FormatYAML, "yml":
+		return FormatYAML
+	default:
+		return ""
+	}
+}
+
+// FormatFromContentType maps a Content-Type header to a format; it returns "" if unknown.
+func FormatFromContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV
+	case "application/json":
+		return FormatJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML
+	default:
+		return ""
+	}
+}
+
+// ContentType returns the Content-Type used when exporting format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatYAML:
+		return "application/yaml"
+	default:
+		return "application/json"
+	}
+}
+
+// Encode writes packs in the given format. Only sizes are written: IDs are local to a database,
+// and metadata and effective periods are not part of the file format.
+func Encode(w io.Writer, format string, packs []models.PackSize) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"size"}); err != nil {
+			return fmt.Errorf("write csv header: %w", err)
+		}
+		for _, p := range packs {
+			if err := cw.Write([]string{strconv.Itoa(p.Size)}); err != nil {
+				return fmt.Errorf("write csv row: %w", err)
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("flush csv: %w", err)
+		}
+		return nil
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(toDocument(packs)); err != nil {
+			return fmt.Errorf("encode json: %w", err)
+		}
+		return nil
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(toDocument(packs)); err != nil {
+			return fmt.Errorf("encode yaml: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("encode yaml: %w", err)
+		}
+		return nil
+	default:
+		return ErrUnsupportedFormat
+	}
+}
+
+func toDocument(packs []models.PackSize) document {
+	doc := document{Packs: make([]packRow, 0, len(packs))}
+	for _, p := range packs {
+		doc.Packs = append(doc.Packs, packRow{Size: p.Size})
+	}
+	return doc
+}
+
+// Decode reads pack sizes in the given format. Every row is validated (size must be a positive
+// integer and unique) and all row errors are reported together in an *InvalidError.
+func Decode(data []byte, format string) ([]int, error) {
+	var (
+		rows []rawRow
+		err  error
+	)
+	switch format {
+	case FormatCSV:
+		rows, err = decodeCSV(data)
+	case FormatJSON:
+		rows, err = decodeJSON(data)
+	case FormatYAML:
+		rows, err = decodeYAML(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	return validateRows(rows)
+}
+
+// rawRow is a single imported row before validation.
+type rawRow struct {
+	row   int
+	value any
+}
+
+func decodeCSV(data []byte) ([]rawRow, error) {
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &InvalidError{Message: "no pack sizes to import"}
+		}
+		return nil, &InvalidError{Message: "invalid csv: " + err.Error()}
+	}
+	col := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")), "size") {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		return nil, &InvalidError{Message: "missing size column"}
+	}
+
+	var rows []rawRow
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &InvalidError{Message: "invalid csv: " + err.Error()}
+		}
+		line, _ := cr.FieldPos(0)
+		var value any
+		if col < len(record) && strings.TrimSpace(record[col]) != "" {
+			value = strings.TrimSpace(record[col])
+		}
+		rows = append(rows, rawRow{row: line, value: value})
+	}
+	return rows, nil
+}
+
+func decodeJSON(data []byte) ([]rawRow, error) {
+	var doc struct {
+		Packs []map[string]any `json:"packs"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, &InvalidError{Message: "invalid json"}
+	}
+	return documentRows(doc.Packs), nil
+}
+
+func decodeYAML(data []byte) ([]rawRow, error) {
+	var doc struct {
+		Packs []map[string]any `yaml:"packs"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &InvalidError{Message: "invalid yaml"}
+	}
+	return documentRows(doc.Packs), nil
+}
+
+func documentRows(packs []map[string]any) []rawRow {
+	rows := make([]rawRow, 0, len(packs))
+	for i, p := range packs {
+		rows = append(rows, rawRow{row: i + 1, value: p["size"]})
+	}
+	return rows
+}
+
+func validateRows(rows []rawRow) ([]int, error) {
+	if len(rows) == 0 {
+		return nil, &InvalidError{Message: "no pack sizes to import"}
+	}
+
+	var rowErrs []models.ImportRowError
+	sizes := make([]int, 0, len(rows))
+	firstRow := make(map[int]int, len(rows))
+	for _, r := range rows {
+		size, msg := parseSize(r.value)
+		if msg == "" && firstRow[size] > 0 {
+			msg = fmt.Sprintf("duplicate size %d (first in row %d)", size, firstRow[size])
+		}
+		if msg != "" {
+			rowErrs = append(rowErrs, models.ImportRowError{Row: r.row, Message: msg})
+			continue
+		}
+		firstRow[size] = r.row
+		sizes = append(sizes, size)
+	}
+	if len(rowErrs) > 0 {
+		return nil, &InvalidError{Message: "invalid rows", Rows: rowErrs}
+	}
+	return sizes, nil
+}
+
+// parseSize converts a decoded size value; it returns a validation message when it is invalid.
+func parseSize(v any) (int, string) {
+	var n int64
+	switch x := v.(type) {
+	case nil:
+		return 0, "size is required"
+	case json.Number:
+		i, err := x.Int64()
+		if err != nil {
+			return 0, "size must be an integer"
+		}
+		n = i
+	case string:
+		i, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return 0, "size must be an integer"
+		}
+		n = i
+	case int:
+		n = int64(x)
+	case float64:
+		if x != math.Trunc(x) || math.Abs(x) > math.MaxInt32 {
+			return 0, "size must be an integer"
+		}
+		n = int64(x)
+	default:
+		return 0, "size must be an integer"
+	}
+	if n <= 0 {
+		return 0, "size must be > 0"
+	}
+	if n > math.MaxInt32 {
+		return 0, "size is too large"
+	}
+	return int(n), ""
+}
@@ -0,0 +1,136 @@
+package packio
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	packs := []models.PackSize{{ID: 1, Size: 250}, {ID: 2, Size: 500}, {ID: 7, Size: 1000}}
+
+	for _, format := range []string{FormatCSV, FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, format, packs); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			sizes, err := Decode(buf.Bytes(), format)
+			if err != nil {
+				t.Fatalf("decode: %v\n%s", err, buf.String())
+			}
+			if want := []int{250, 500, 1000}; !reflect.DeepEqual(sizes, want) {
+				t.Fatalf("unexpected sizes; got=%v expected=%v", sizes, want)
+			}
+		})
+	}
+}
+
+func TestEncodeFormats(t *testing.T) {
+	packs := []models.PackSize{{ID: 1, Size: 250}, {ID: 2, Size: 500}}
+
+	cases := map[string]string{
+		FormatCSV:  "size\n250\n500\n",
+		FormatJSON: "{\n  \"packs\": [\n    {\n      \"size\": 250\n    },\n    {\n      \"size\": 500\n    }\n  ]\n}\n",
+		FormatYAML: "packs:\n  - size: 250\n  - size: 500\n",
+	}
+	for format, want := range cases {
+		var buf bytes.Buffer
+		if err := Encode(&buf, format, packs); err != nil {
+			t.Fatalf("%s: encode: %v", format, err)
+		}
+		if buf.String() != want {
+			t.Fatalf("%s: unexpected output; got=%q expected=%q", format, buf.String(), want)
+		}
+	}
+}
+
+func TestDecodeRowErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   string
+		input    string
+		wantMsg  string
+		wantRows []models.ImportRowError
+	}{
+		{
+			name:    "csv rows",
+			format:  FormatCSV,
+			input:   "label,size\nsmall,250\nbad,abc\nzero,0\nagain,250\nmissing,\n",
+			wantMsg: "invalid rows",
+			wantRows: []models.ImportRowError{
+				{Row: 3, Message: "size must be an integer"},
+				{Row: 4, Message: "size must be > 0"},
+				{Row: 5, Message: "duplicate size 250 (first in row 2)"},
+				{Row: 6, Message: "size is required"},
+			},
+		},
+		{
+			name:    "json rows",
+			format:  FormatJSON,
+			input:   `{"packs":[{"size":250},{"size":2.5},{},{"size":-1}]}`,
+			wantMsg: "invalid rows",
+			wantRows: []models.ImportRowError{
+				{Row: 2, Message: "size must be an integer"},
+				{Row: 3, Message: "size is required"},
+				{Row: 4, Message: "size must be > 0"},
+			},
+		},
+		{
+			name:    "yaml rows",
+			format:  FormatYAML,
+			input:   "packs:\n  - size: 250\n  - size: 250\n  - size: [1]\n",
+			wantMsg: "invalid rows",
+			wantRows: []models.ImportRowError{
+				{Row: 2, Message: "duplicate size 250 (first in row 1)"},
+				{Row: 3, Message: "size must be an integer"},
+			},
+		},
+		{name: "csv without size column", format: FormatCSV, input: "label\nsmall\n", wantMsg: "missing size column"},
+		{name: "empty csv", format: FormatCSV, input: "", wantMsg: "no pack sizes to import"},
+		{name: "csv header only", format: FormatCSV, input: "size\n", wantMsg: "no pack sizes to import"},
+		{name: "malformed json", format: FormatJSON, input: `{"packs":`, wantMsg: "invalid json"},
+		{name: "empty json list", format: FormatJSON, input: `{"packs":[]}`, wantMsg: "no pack sizes to import"},
+		{name: "malformed yaml", format: FormatYAML, input: "packs: [", wantMsg: "invalid yaml"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode([]byte(tc.input), tc.format)
+			var invalid *InvalidError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected *InvalidError, got %v", err)
+			}
+			if invalid.Message != tc.wantMsg {
+				t.Fatalf("unexpected message; got=%q expected=%q", invalid.Message, tc.wantMsg)
+			}
+			if !reflect.DeepEqual(invalid.Rows, tc.wantRows) {
+				t.Fatalf("unexpected rows; got=%+v expected=%+v", invalid.Rows, tc.wantRows)
+			}
+		})
+	}
+}
+
+func TestDecodeUnsupportedFormat(t *testing.T) {
+	if _, err := Decode([]byte("x"), "xml"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestFormatFromContentType(t *testing.T) {
+	cases := map[string]string{
+		"text/csv":                        FormatCSV,
+		"application/json; charset=utf-8": FormatJSON,
+		"application/x-yaml":              FormatYAML,
+		"text/plain":                      "",
+		"":                                "",
+	}
+	for ct, want := range cases {
+		if got := FormatFromContentType(ct); got != want {
+			t.Fatalf("FormatFromContentType(%q)=%q expected %q", ct, got, want)
+		}
+	}
+}
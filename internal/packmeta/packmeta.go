@@ -0,0 +1,42 @@
+// Package packmeta validates the optional metadata of pack sizes.
+package packmeta
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/gtin"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+const (
+	MaxLabelLen       = 100
+	MaxSupplierSKULen = 64
+)
+
+// Normalize trims the text fields of meta and validates it.
+func Normalize(meta models.PackMetadata) (models.PackMetadata, error) {
+	meta.Label = strings.TrimSpace(meta.Label)
+	meta.SupplierSKU = strings.TrimSpace(meta.SupplierSKU)
+	meta.GTIN = strings.TrimSpace(meta.GTIN)
+
+	if utf8.RuneCountInString(meta.Label) > MaxLabelLen {
+		return meta, fmt.Errorf("label must be at most %d characters", MaxLabelLen)
+	}
+	if utf8.RuneCountInString(meta.SupplierSKU) > MaxSupplierSKULen {
+		return meta, fmt.Errorf("supplier_sku must be at most %d characters", MaxSupplierSKULen)
+	}
+	if meta.GTIN != "" {
+		if err := gtin.Validate(meta.GTIN); err != nil {
+			return meta, err
+		}
+	}
+	if d := meta.Dimensions; d != nil && (d.LengthMM <= 0 || d.WidthMM <= 0 || d.HeightMM <= 0) {
+		return meta, fmt.Errorf("dimensions must all be > 0")
+	}
+	if meta.WeightGrams < 0 {
+		return meta, fmt.Errorf("weight_grams must be >= 0")
+	}
+	return meta, nil
+}
@@ -0,0 +1,132 @@
+// Package packpolicy defines the validation policy pack sizes must satisfy on top of being
+// positive, e.g. to keep anybody from creating a pack of a single unit.
+package packpolicy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+// ErrViolation is wrapped by every *Error.
+var ErrViolation = errors.New("pack size policy violation")
+
+// Policy restricts the pack sizes that can be stored. Zero values impose no restriction.
+type Policy struct {
+	MinSize int
+	MaxSize int
+	// MaxSizesPerSet limits how many live pack sizes a pack set may hold.
+	MaxSizesPerSet int
+	// Multiples require every size to be a multiple of at least one of them.
+	Multiples []int
+	Forbidden []int
+}
+
+// Error lists the request fields that break the policy and why.
+type Error struct {
+	Fields []models.FieldError
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	return ErrViolation
+}
+
+// Validate reports whether the policy itself is consistent.
+func (p Policy) Validate() error {
+	var errs []error
+	if p.MinSize < 0 || p.MaxSize < 0 || p.MaxSizesPerSet < 0 {
+		errs = append(errs, errors.New("limits must be >= 0"))
+	}
+	if p.MinSize > 0 && p.MaxSize > 0 && p.MinSize > p.MaxSize {
+		errs = append(errs, fmt.Errorf("minimum size %d is greater than maximum size %d", p.MinSize, p.MaxSize))
+	}
+	for _, m := range p.Multiples {
+		if m <= 0 {
+			errs = append(errs, fmt.Errorf("multiples must be > 0, provided: %d", m))
+		}
+	}
+	for _, s := range p.Forbidden {
+		if s <= 0 {
+			errs = append(errs, fmt.Errorf("forbidden sizes must be > 0, provided: %d", s))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckSize validates a single size sent in the "size" field. count is the number of live pack
+// sizes the set holds after the change, or 0 when the change does not add a pack size.
+func (p Policy) CheckSize(size, count int) error {
+	var fields []models.FieldError
+	for _, msg := range p.sizeProblems("size", size) {
+		fields = append(fields, models.FieldError{Field: "size", Message: msg})
+	}
+	if p.tooMany(count) {
+		fields = append(fields, models.FieldError{Field: "size",
+			Message: fmt.Sprintf("a pack set can have at most %d pack sizes", p.MaxSizesPerSet)})
+	}
+	return newError(fields)
+}
+
+// CheckSizes validates the sizes a bulk change adds, sent in the "sizes" field. count is the
+// number of live pack sizes the set holds after the change.
+func (p Policy) CheckSizes(sizes []int, count int) error {
+	var fields []models.FieldError
+	for _, s := range sizes {
+		for _, msg := range p.sizeProblems("size "+strconv.Itoa(s), s) {
+			fields = append(fields, models.FieldError{Field: "sizes", Message: msg})
+		}
+	}
+	if len(sizes) > 0 && p.tooMany(count) {
+		fields = append(fields, models.FieldError{Field: "sizes",
+			Message: fmt.Sprintf("a pack set can have at most %d pack sizes, this change leaves %d", p.MaxSizesPerSet, count)})
+	}
+	return newError(fields)
+}
+
+func (p Policy) sizeProblems(subject string, size int) []string {
+	var out []string
+	if p.MinSize > 0 && size < p.MinSize {
+		out = append(out, fmt.Sprintf("%s must be at least %d", subject, p.MinSize))
+	}
+	if p.MaxSize > 0 && size > p.MaxSize {
+		out = append(out, fmt.Sprintf("%s must be at most %d", subject, p.MaxSize))
+	}
+	if len(p.Multiples) > 0 && !slices.ContainsFunc(p.Multiples, func(m int) bool { return size%m == 0 }) {
+		out = append(out, fmt.Sprintf("%s must be a multiple of %s", subject, joinInts(p.Multiples, " or ")))
+	}
+	if slices.Contains(p.Forbidden, size) {
+		out = append(out, fmt.Sprintf("%s is not allowed", subject))
+	}
+	return out
+}
+
+func (p Policy) tooMany(count int) bool {
+	return p.MaxSizesPerSet > 0 && count > p.MaxSizesPerSet
+}
+
+func newError(fields []models.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &Error{Fields: fields}
+}
+
+func joinInts(values []int, sep string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,78 @@
+package packpolicy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func TestCheckSize(t *testing.T) {
+	policy := Policy{MinSize: 10, MaxSize: 5000, MaxSizesPerSet: 3, Multiples: []int{25, 40}, Forbidden: []int{1000}}
+
+	tests := []struct {
+		name  string
+		size  int
+		count int
+		want  []string
+	}{
+		{"allowed", 250, 3, nil},
+		{"multiple of the second", 120, 1, nil},
+		{"count is not checked without new sizes", 250, 0, nil},
+		{"too small and not a multiple", 1, 1, []string{"size must be at least 10", "size must be a multiple of 25 or 40"}},
+		{"too large", 5025, 1, []string{"size must be at most 5000"}},
+		{"forbidden", 1000, 1, []string{"size is not allowed"}},
+		{"too many", 250, 4, []string{"a pack set can have at most 3 pack sizes"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.CheckSize(tc.size, tc.count)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var violation *Error
+			if !errors.As(err, &violation) || !errors.Is(err, ErrViolation) {
+				t.Fatalf("expected a policy violation, got %v", err)
+			}
+			var got []string
+			for _, f := range violation.Fields {
+				if f.Field != "size" {
+					t.Fatalf("field = %q", f.Field)
+				}
+				got = append(got, f.Message)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("messages = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckSizes(t *testing.T) {
+	policy := Policy{MinSize: 10, MaxSizesPerSet: 2}
+
+	err := policy.CheckSizes([]int{5, 250}, 3)
+	var violation *Error
+	if !errors.As(err, &violation) {
+		t.Fatalf("expected a policy violation, got %v", err)
+	}
+	want := []models.FieldError{
+		{Field: "sizes", Message: "size 5 must be at least 10"},
+		{Field: "sizes", Message: "a pack set can have at most 2 pack sizes, this change leaves 3"},
+	}
+	if !reflect.DeepEqual(violation.Fields, want) {
+		t.Fatalf("fields = %+v, want %+v", violation.Fields, want)
+	}
+
+	// Shrinking a set that is over the limit is allowed.
+	if err := policy.CheckSizes(nil, 3); err != nil {
+		t.Fatalf("expected no error without new sizes, got %v", err)
+	}
+	if err := (Policy{}).CheckSizes([]int{1}, 100); err != nil {
+		t.Fatalf("expected the zero policy to allow everything, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+// DefaultAuditLimit caps the number of audit entries returned when the filter sets no limit.
+const DefaultAuditLimit = 100
+
+// AuditRepository reads the audit log of the tenant in ctx. Entries are written by
+// PackSizesRepository in the same transaction as the mutation they describe.
+type AuditRepository interface {
+	List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+type sqlAuditRepository struct{}
+
+var auditRepo AuditRepository = &sqlAuditRepository{}
+
+func Audit() AuditRepository {
+	return auditRepo
+}
+
+// SetAuditRepository swaps the repository implementation (primarily for tests).
+func SetAuditRepository(repo AuditRepository) {
+	if repo == nil {
+		panic("AuditRepository must not be nil")
+	}
+	auditRepo = repo
+}
+
+func (r *sqlAuditRepository) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	where := []string{"tenant = ?"}
+	args := []any{requestinfo.Tenant(ctx)}
+	if filter.PackSet != "" {
+		where = append(where, "pack_set = ?")
+		args = append(args, filter.PackSet)
+	}
+	if filter.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.RequestID != "" {
+		where = append(where, "request_id = ?")
+		args = append(args, filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, formatTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, formatTime(filter.To))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultAuditLimit
+	}
+
+	query := `SELECT id, created_at, actor, request_id, action, pack_set, pack_size_id, before_value, after_value
+	FROM audit_log WHERE ` + strings.Join(where, " AND ")
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := conn.QueryContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit entries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []models.AuditEntry{}
+	for rows.Next() {
+		var (
+			e             models.AuditEntry
+			createdAt     string
+			packSizeID    sql.NullInt64
+			before, after sql.NullString
+		)
+		if err := rows.Scan(&e.ID, &createdAt, &e.Actor, &e.RequestID, &e.Action, &e.PackSet, &packSizeID, &before, &after); err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		if e.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, fmt.Errorf("parse audit entry time: %w", err)
+		}
+		if packSizeID.Valid {
+			id := packSizeID.Int64
+			e.PackSizeID = &id
+		}
+		e.Before = rawJSONOrNull(before)
+		e.After = rawJSONOrNull(after)
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate audit entries: %w", err)
+	}
+	return out, nil
+}
+
+// recordAudit writes an audit entry for a pack size mutation. It must run in the same transaction
+// as the mutation. before/after are encoded as JSON; nil values are stored as NULL.
+func recordAudit(ctx context.Context, tx *sql.Tx, action, set string, packSizeID *int64, before, after any) error {
+	beforeJSON, err := nullableJSON(before)
+	if err != nil {
+		return fmt.Errorf("encode audit before value: %w", err)
+	}
+	afterJSON, err := nullableJSON(after)
+	if err != nil {
+		return fmt.Errorf("encode audit after value: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, db.Rebind(`
+	INSERT INTO audit_log(tenant, created_at, actor, request_id, action, pack_set, pack_size_id, before_value, after_value)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		requestinfo.Tenant(ctx), formatTime(now()), requestinfo.Actor(ctx), requestinfo.RequestID(ctx), action, set, packSizeID, beforeJSON, afterJSON)
+	if err != nil {
+		return fmt.Errorf("insert audit entry: %w", err)
+	}
+	return nil
+}
+
+func nullableJSON(v any) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func rawJSONOrNull(s sql.NullString) json.RawMessage {
+	if !s.Valid {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(s.String)
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+// CachedPackSizesRepository is a read-through cache around a PackSizesRepository. List results
+// are kept in memory per tenant and pack set; every mutation made through the cache invalidates the set it
+// touched, and entries expire after the TTL so that changes made by another process (or directly
+// in the database) are picked up.
+type CachedPackSizesRepository struct {
+	next PackSizesRepository
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[packSetKey]cacheEntry
+	// generation is bumped on every invalidation, so that a List that raced with a mutation
+	// does not store what it read.
+	generation uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type cacheEntry struct {
+	packs     []models.PackSize
+	expiresAt time.Time
+}
+
+// NewCachedPackSizesRepository wraps next with a List cache whose entries live for ttl.
+func NewCachedPackSizesRepository(next PackSizesRepository, ttl time.Duration) *CachedPackSizesRepository {
+	return &CachedPackSizesRepository{
+		next:    next,
+		ttl:     ttl,
+		entries: make(map[packSetKey]cacheEntry),
+	}
+}
+
+// Stats returns the hit/miss counters and the number of cached pack sets.
+func (c *CachedPackSizesRepository) Stats() models.CacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return models.CacheStats{
+		Enabled:    true,
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Entries:    entries,
+		TTLSeconds: c.ttl.Seconds(),
+	}
+}
+
+// Invalidate drops the cached pack sizes of set in every tenant, or of every set when set is
+// empty.
+func (c *CachedPackSizesRepository) Invalidate(set string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for k := range c.entries {
+		if set == "" || k.set == set {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// invalidate drops the cached pack sizes of set for the tenant in ctx.
+func (c *CachedPackSizesRepository) invalidate(ctx context.Context, set string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	delete(c.entries, keyOf(ctx, set))
+}
+
+func (c *CachedPackSizesRepository) List(ctx context.Context, set string) ([]models.PackSize, error) {
+	k := keyOf(ctx, set)
+	c.mu.Lock()
+	e, ok := c.entries[k]
+	if ok && now().Before(e.expiresAt) {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return clonePackSizes(e.packs), nil
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	packs, err := c.next.List(ctx, set)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.entries[k] = cacheEntry{packs: clonePackSizes(packs), expiresAt: now().Add(c.ttl)}
+	}
+	c.mu.Unlock()
+	return packs, nil
+}
+
+func (c *CachedPackSizesRepository) ListSets(ctx context.Context) ([]string, error) {
+	return c.next.ListSets(ctx)
+}
+
+func (c *CachedPackSizesRepository) ListAll(ctx context.Context, set string) ([]models.PackSize, error) {
+	return c.next.ListAll(ctx, set)
+}
+
+func (c *CachedPackSizesRepository) Get(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+	return c.next.Get(ctx, set, id)
+}
+
+func (c *CachedPackSizesRepository) ResetToDefault(ctx context.Context, set string) ([]int, error) {
+	defer c.invalidate(ctx, set)
+	return c.next.ResetToDefault(ctx, set)
+}
+
+func (c *CachedPackSizesRepository) Replace(ctx context.Context, set string, sizes []int) (*models.PackSizesDiff, error) {
+	defer c.invalidate(ctx, set)
+	return c.next.Replace(ctx, set, sizes)
+}
+
+func (c *CachedPackSizesRepository) Import(ctx context.Context, set string, sizes []int, mode string, dryRun bool) (*models.PackSizesDiff, error) {
+	if !dryRun {
+		defer c.invalidate(ctx, set)
+	}
+	return c.next.Import(ctx, set, sizes, mode, dryRun)
+}
+
+func (c *CachedPackSizesRepository) Create(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+	defer c.invalidate(ctx, set)
+	return c.next.Create(ctx, set, size, meta, period)
+}
+
+func (c *CachedPackSizesRepository) Update(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+	defer c.invalidate(ctx, set)
+	return c.next.Update(ctx, set, id, size, meta, period, ifVersion)
+}
+
+func (c *CachedPackSizesRepository) Delete(ctx context.Context, set string, id int64, ifVersion int64) error {
+	defer c.invalidate(ctx, set)
+	return c.next.Delete(ctx, set, id, ifVersion)
+}
+
+func (c *CachedPackSizesRepository) Restore(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+	defer c.invalidate(ctx, set)
+	return c.next.Restore(ctx, set, id)
+}
+
+// clonePackSizes copies packs so that callers cannot modify cached data.
+func clonePackSizes(packs []models.PackSize) []models.PackSize {
+	if packs == nil {
+		return nil
+	}
+	out := make([]models.PackSize, len(packs))
+	copy(out, packs)
+	return out
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+// countingPackSizes counts the List calls that reach the wrapped repository.
+type countingPackSizes struct {
+	PackSizesRepository
+	lists   int
+	listErr error
+}
+
+func (c *countingPackSizes) List(ctx context.Context, set string) ([]models.PackSize, error) {
+	c.lists++
+	if c.listErr != nil {
+		return nil, c.listErr
+	}
+	return c.PackSizesRepository.List(ctx, set)
+}
+
+func newCachedTestRepo(t *testing.T, ttl time.Duration) (*CachedPackSizesRepository, *countingPackSizes) {
+	t.Helper()
+
+	next := &countingPackSizes{PackSizesRepository: openMemoryBackend(t).packSizes}
+	return NewCachedPackSizesRepository(next, ttl), next
+}
+
+func TestCachedPackSizesServesListFromMemory(t *testing.T) {
+	ctx := context.Background()
+	cache, next := newCachedTestRepo(t, time.Minute)
+
+	if _, err := cache.Create(ctx, DefaultPackSet, 250, models.PackMetadata{}, models.EffectivePeriod{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		packs, err := cache.List(ctx, DefaultPackSet)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(packs) != 1 || packs[0].Size != 250 {
+			t.Fatalf("packs = %+v", packs)
+		}
+		// Callers get their own copy.
+		packs[0].Size = 1
+	}
+
+	if next.lists != 1 {
+		t.Fatalf("expected 1 repository list, got %d", next.lists)
+	}
+	if s := cache.Stats(); s.Hits != 2 || s.Misses != 1 || s.Entries != 1 || !s.Enabled {
+		t.Fatalf("stats = %+v", s)
+	}
+}
+
+func TestCachedPackSizesInvalidatesOnMutation(t *testing.T) {
+	ctx := context.Background()
+	cache, next := newCachedTestRepo(t, time.Minute)
+
+	mustSizes := func(set string, want ...int) {
+		t.Helper()
+		packs, err := cache.List(ctx, set)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if got := sizesOf(packs); !slices.Equal(got, want) {
+			t.Fatalf("%s sizes = %v, want %v", set, got, want)
+		}
+	}
+
+	mustSizes(DefaultPackSet)
+	p, err := cache.Create(ctx, DefaultPackSet, 250, models.PackMetadata{}, models.EffectivePeriod{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	mustSizes(DefaultPackSet, 250)
+
+	if _, err := cache.Update(ctx, DefaultPackSet, p.ID, 300, models.PackMetadata{}, models.EffectivePeriod{}, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	mustSizes(DefaultPackSet, 300)
+
+	if err := cache.Delete(ctx, DefaultPackSet, p.ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	mustSizes(DefaultPackSet)
+
+	if _, err := cache.Restore(ctx, DefaultPackSet, p.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	mustSizes(DefaultPackSet, 300)
+
+	if _, err := cache.Replace(ctx, DefaultPackSet, []int{10, 20}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	mustSizes(DefaultPackSet, 10, 20)
+
+	// A dry run changes nothing and keeps the cached listing.
+	lists := next.lists
+	if _, err := cache.Import(ctx, DefaultPackSet, []int{30}, models.ImportModeMerge, true); err != nil {
+		t.Fatalf("dry run import: %v", err)
+	}
+	mustSizes(DefaultPackSet, 10, 20)
+	if next.lists != lists {
+		t.Fatalf("dry run invalidated the cache")
+	}
+
+	if _, err := cache.Import(ctx, DefaultPackSet, []int{30}, models.ImportModeMerge, false); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	mustSizes(DefaultPackSet, 10, 20, 30)
+
+	// Other sets are cached independently.
+	mustSizes("retail")
+	if _, err := cache.ResetToDefault(ctx, "retail"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	mustSizes("retail", DefaultPackSizes()...)
+	lists = next.lists
+	mustSizes(DefaultPackSet, 10, 20, 30)
+	if next.lists != lists {
+		t.Fatalf("resetting retail invalidated the default set")
+	}
+}
+
+func TestCachedPackSizesRevalidatesAfterTTL(t *testing.T) {
+	ctx := context.Background()
+	cache, next := newCachedTestRepo(t, 5*time.Second)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := start
+	origNow := now
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = origNow })
+
+	if _, err := cache.List(ctx, DefaultPackSet); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	// A change made behind the cache's back, e.g. by another process.
+	if _, err := next.Create(ctx, DefaultPackSet, 250, models.PackMetadata{}, models.EffectivePeriod{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	clock = start.Add(4 * time.Second)
+	if packs, _ := cache.List(ctx, DefaultPackSet); len(packs) != 0 {
+		t.Fatalf("expected the cached (empty) listing before the TTL, got %+v", packs)
+	}
+	clock = start.Add(5 * time.Second)
+	if packs, _ := cache.List(ctx, DefaultPackSet); len(packs) != 1 {
+		t.Fatalf("expected the listing to be revalidated after the TTL, got %+v", packs)
+	}
+	if s := cache.Stats(); s.Hits != 1 || s.Misses != 2 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
+
+func TestCachedPackSizesDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	cache, next := newCachedTestRepo(t, time.Minute)
+
+	next.listErr = errors.New("boom")
+	if _, err := cache.List(ctx, DefaultPackSet); err == nil {
+		t.Fatalf("expected error")
+	}
+	next.listErr = nil
+	if _, err := cache.List(ctx, DefaultPackSet); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if next.lists != 2 {
+		t.Fatalf("expected the error not to be cached, got %d lists", next.lists)
+	}
+}
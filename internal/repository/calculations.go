@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+// DefaultCalculationsLimit caps the number of calculations returned when the filter sets no limit.
+const DefaultCalculationsLimit = 100
+
+// CalculationsRepository stores the calculation history of the tenant in ctx.
+type CalculationsRepository interface {
+	// Record stores c, filling in its ID, creation time and the request ID from ctx.
+	Record(ctx context.Context, c models.Calculation) (*models.Calculation, error)
+	// List returns the newest calculations matching filter first.
+	List(ctx context.Context, filter models.CalculationFilter) ([]models.Calculation, error)
+	// Each calls fn for every calculation matching filter, oldest first, ignoring filter.Limit.
+	// It stops at the first error fn returns. fn must not use the repositories.
+	Each(ctx context.Context, filter models.CalculationFilter, fn func(models.Calculation) error) error
+	Get(ctx context.Context, id int64) (*models.Calculation, error)
+}
+
+type sqlCalculationsRepository struct{}
+
+var calculationsRepo CalculationsRepository = &sqlCalculationsRepository{}
+
+func Calculations() CalculationsRepository {
+	return calculationsRepo
+}
+
+// SetCalculationsRepository swaps the repository implementation (primarily for tests).
+func SetCalculationsRepository(repo CalculationsRepository) {
+	if repo == nil {
+		panic("CalculationsRepository must not be nil")
+	}
+	calculationsRepo = repo
+}
+
+const calculationColumns = `id, created_at, request_id, reference, pack_set, pack_set_version, quantity, request, packs,
+	pack_sizes, shipped, overage, duration_us`
+
+func (r *sqlCalculationsRepository) Record(ctx context.Context, c models.Calculation) (*models.Calculation, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+	return recordCalculation(ctx, conn, c)
+}
+
+func recordCalculation(ctx context.Context, q querier, c models.Calculation) (*models.Calculation, error) {
+	packs, err := json.Marshal(c.Packs)
+	if err != nil {
+		return nil, fmt.Errorf("encode calculation packs: %w", err)
+	}
+	sizes, err := json.Marshal(c.PackSizes)
+	if err != nil {
+		return nil, fmt.Errorf("encode calculation pack sizes: %w", err)
+	}
+	c.CreatedAt = storedTime(now())
+	c.RequestID = requestinfo.RequestID(ctx)
+	c.Request = requestOrNull(c.Request)
+
+	err = q.QueryRowContext(ctx, db.Rebind(`
+	INSERT INTO calculations(tenant, created_at, request_id, reference, pack_set, pack_set_version, quantity, request, packs,
+		pack_sizes, shipped, overage, duration_us)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		requestinfo.Tenant(ctx), formatTime(c.CreatedAt), c.RequestID, c.Reference, c.PackSet, c.Version, c.Quantity,
+		string(c.Request), string(packs), string(sizes), c.Shipped, c.Overage, c.DurationMicros).Scan(&c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("insert calculation: %w", err)
+	}
+	return &c, nil
+}
+
+func (r *sqlCalculationsRepository) List(ctx context.Context, filter models.CalculationFilter) ([]models.Calculation, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	where, args := calculationsWhere(ctx, filter)
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultCalculationsLimit
+	}
+
+	query := `SELECT ` + calculationColumns + ` FROM calculations WHERE ` + where
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := conn.QueryContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list calculations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []models.Calculation{}
+	for rows.Next() {
+		c, err := scanCalculation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate calculations: %w", err)
+	}
+	return out, nil
+}
+
+func (r *sqlCalculationsRepository) Each(ctx context.Context, filter models.CalculationFilter, fn func(models.Calculation) error) error {
+	conn, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	where, args := calculationsWhere(ctx, filter)
+	rows, err := conn.QueryContext(ctx, db.Rebind(`SELECT `+calculationColumns+` FROM calculations WHERE `+where+` ORDER BY id ASC`), args...)
+	if err != nil {
+		return fmt.Errorf("list calculations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		c, err := scanCalculation(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(*c); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate calculations: %w", err)
+	}
+	return nil
+}
+
+// calculationsWhere returns the WHERE clause selecting the calculations of the tenant in ctx that
+// match filter, and its arguments.
+func calculationsWhere(ctx context.Context, filter models.CalculationFilter) (string, []any) {
+	where := []string{"tenant = ?"}
+	args := []any{requestinfo.Tenant(ctx)}
+	if filter.Reference != "" {
+		where = append(where, "reference = ?")
+		args = append(args, filter.Reference)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, formatTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, formatTime(filter.To))
+	}
+	return strings.Join(where, " AND "), args
+}
+
+func (r *sqlCalculationsRepository) Get(ctx context.Context, id int64) (*models.Calculation, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	row := conn.QueryRowContext(ctx, db.Rebind(`SELECT `+calculationColumns+` FROM calculations WHERE tenant = ? AND id = ?`),
+		requestinfo.Tenant(ctx), id)
+	c, err := scanCalculation(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w", ErrNotFound)
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func scanCalculation(row rowScanner) (*models.Calculation, error) {
+	var (
+		c                     models.Calculation
+		createdAt             string
+		request, packs, sizes string
+	)
+	err := row.Scan(&c.ID, &createdAt, &c.RequestID, &c.Reference, &c.PackSet, &c.Version, &c.Quantity, &request, &packs,
+		&sizes, &c.Shipped, &c.Overage, &c.DurationMicros)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan calculation: %w", err)
+	}
+	if c.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, fmt.Errorf("parse calculation time: %w", err)
+	}
+	c.Request = json.RawMessage(request)
+	if err := json.Unmarshal([]byte(packs), &c.Packs); err != nil {
+		return nil, fmt.Errorf("decode calculation packs: %w", err)
+	}
+	if err := json.Unmarshal([]byte(sizes), &c.PackSizes); err != nil {
+		return nil, fmt.Errorf("decode calculation pack sizes: %w", err)
+	}
+	return &c, nil
+}
+
+// requestOrNull stores a missing request body as JSON null.
+func requestOrNull(request json.RawMessage) json.RawMessage {
+	if len(request) == 0 {
+		return json.RawMessage("null")
+	}
+	return request
+}
@@ -0,0 +1,1395 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packpolicy"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+// backendRepos are the repositories of one storage backend, sharing the same data.
+type backendRepos struct {
+	packSizes    PackSizesRepository
+	audit        AuditRepository
+	versions     PackSetVersionsRepository
+	products     ProductsRepository
+	calculations CalculationsRepository
+	orders       OrdersRepository
+	idempotency  IdempotencyRepository
+	inventory    InventoryRepository
+}
+
+// storageBackend opens a fresh, empty backend for a single test.
+type storageBackend struct {
+	name string
+	open func(t *testing.T) backendRepos
+}
+
+var storageBackends = []storageBackend{
+	{name: "sqlite", open: openSQLiteBackend},
+	{name: "postgres", open: openPostgresBackend},
+	{name: "memory", open: openMemoryBackend},
+	{name: "cached-sqlite", open: openCachedSQLiteBackend},
+}
+
+// TestConformance runs the same behavioural suite against every backend.
+func TestConformance(t *testing.T) {
+	for _, b := range storageBackends {
+		t.Run(b.name, func(t *testing.T) {
+			runConformance(t, b.open)
+		})
+	}
+}
+
+func sqlBackendRepos() backendRepos {
+	return backendRepos{
+		packSizes:    &sqlPackSizesRepository{},
+		audit:        &sqlAuditRepository{},
+		versions:     &sqlPackSetVersionsRepository{},
+		products:     &sqlProductsRepository{},
+		calculations: &sqlCalculationsRepository{},
+		orders:       &sqlOrdersRepository{},
+		idempotency:  &sqlIdempotencyRepository{},
+		inventory:    &sqlInventoryRepository{},
+	}
+}
+
+func openMemoryBackend(t *testing.T) backendRepos {
+	t.Helper()
+
+	s := newMemoryStore()
+	return backendRepos{
+		packSizes:    &memoryPackSizesRepository{store: s},
+		audit:        &memoryAuditRepository{store: s},
+		versions:     &memoryPackSetVersionsRepository{store: s},
+		products:     &memoryProductsRepository{store: s},
+		calculations: &memoryCalculationsRepository{store: s},
+		orders:       &memoryOrdersRepository{store: s},
+		idempotency:  &memoryIdempotencyRepository{store: s},
+		inventory:    &memoryInventoryRepository{store: s},
+	}
+}
+
+// openCachedSQLiteBackend checks that the List cache is transparent to callers.
+func openCachedSQLiteBackend(t *testing.T) backendRepos {
+	t.Helper()
+
+	repos := openSQLiteBackend(t)
+	repos.packSizes = NewCachedPackSizesRepository(repos.packSizes, time.Minute)
+	return repos
+}
+
+func openSQLiteBackend(t *testing.T) backendRepos {
+	t.Helper()
+
+	ctx := context.Background()
+	if err := db.InitSQLite(ctx, filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("init sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	migrateTestDB(t)
+	return sqlBackendRepos()
+}
+
+// openPostgresBackend runs against TEST_POSTGRES_DSN, in a schema of its own that is dropped
+// afterwards. The test is skipped when the variable is not set.
+func openPostgresBackend(t *testing.T) backendRepos {
+	t.Helper()
+
+	openPostgresSchema(t)
+	migrateTestDB(t)
+	return sqlBackendRepos()
+}
+
+// openPostgresSchema creates an empty schema, installs a connection to it as the global one and
+// returns its DSN.
+func openPostgresSchema(t *testing.T) string {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	ctx := context.Background()
+	admin, err := db.OpenPostgres(ctx, dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { _ = admin.Close() })
+
+	schema := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { _, _ = admin.ExecContext(context.Background(), `DROP SCHEMA `+schema+` CASCADE`) })
+
+	dsn = withSearchPath(dsn, schema)
+	if err := db.InitPostgres(ctx, dsn); err != nil {
+		t.Fatalf("init postgres: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return dsn
+}
+
+// TestPostgresConcurrentMigrations starts several replicas against the same empty database at once.
+func TestPostgresConcurrentMigrations(t *testing.T) {
+	dsn := openPostgresSchema(t)
+	ctx := context.Background()
+	latest, err := db.LatestSchemaVersion()
+	if err != nil {
+		t.Fatalf("latest schema version: %v", err)
+	}
+
+	const replicas = 4
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		applied int
+		errs    []error
+	)
+	for i := 0; i < replicas; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := db.OpenPostgres(ctx, dsn)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				return
+			}
+			defer func() { _ = conn.Close() }()
+			done, err := db.Migrate(ctx, conn)
+			mu.Lock()
+			defer mu.Unlock()
+			applied += len(done)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		t.Fatalf("migrate: %v", errs)
+	}
+	if applied != latest {
+		t.Fatalf("applied %d migrations in total, want each of the %d once", applied, latest)
+	}
+}
+
+func migrateTestDB(t *testing.T) {
+	t.Helper()
+
+	conn, err := db.DB()
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	if _, err := db.Migrate(context.Background(), conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+}
+
+// withSearchPath adds a search_path runtime parameter to a URL or keyword/value DSN.
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(dsn) + " search_path=" + schema
+}
+
+func runConformance(t *testing.T, open func(t *testing.T) backendRepos) {
+	ctx := context.Background()
+
+	mustCreate := func(t *testing.T, repo PackSizesRepository, set string, size int) models.PackSize {
+		t.Helper()
+		p, err := repo.Create(ctx, set, size, models.PackMetadata{}, models.EffectivePeriod{})
+		if err != nil {
+			t.Fatalf("create %s/%d: %v", set, size, err)
+		}
+		return *p
+	}
+	mustList := func(t *testing.T, repo PackSizesRepository, set string) []models.PackSize {
+		t.Helper()
+		packs, err := repo.List(ctx, set)
+		if err != nil {
+			t.Fatalf("list %s: %v", set, err)
+		}
+		return packs
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		if packs := mustList(t, repo, DefaultPackSet); len(packs) != 0 {
+			t.Fatalf("expected no pack sizes, got %v", packs)
+		}
+		sets, err := repo.ListSets(ctx)
+		if err != nil {
+			t.Fatalf("list sets: %v", err)
+		}
+		if !reflect.DeepEqual(sets, []string{DefaultPackSet}) {
+			t.Fatalf("sets = %v", sets)
+		}
+	})
+
+	t.Run("create and list ordered by size", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		first := mustCreate(t, repo, DefaultPackSet, 500)
+		second := mustCreate(t, repo, DefaultPackSet, 250)
+		if first.ID <= 0 || second.ID <= first.ID {
+			t.Fatalf("expected increasing IDs, got %d then %d", first.ID, second.ID)
+		}
+		if first.Version != 1 {
+			t.Fatalf("expected version 1, got %d", first.Version)
+		}
+		mustCreate(t, repo, DefaultPackSet, 1000)
+
+		if got := sizesOf(mustList(t, repo, DefaultPackSet)); !reflect.DeepEqual(got, []int{250, 500, 1000}) {
+			t.Fatalf("sizes = %v", got)
+		}
+		got, err := repo.Get(ctx, DefaultPackSet, first.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if *got != first {
+			t.Fatalf("get = %+v, want %+v", *got, first)
+		}
+	})
+
+	t.Run("conflicts are per set", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		mustCreate(t, repo, DefaultPackSet, 250)
+		if _, err := repo.Create(ctx, DefaultPackSet, 250, models.PackMetadata{}, models.EffectivePeriod{}); !errors.Is(err, ErrConflict) {
+			t.Fatalf("expected ErrConflict, got %v", err)
+		}
+		mustCreate(t, repo, "retail", 250)
+		mustCreate(t, repo, "bulk", 250)
+
+		sets, err := repo.ListSets(ctx)
+		if err != nil {
+			t.Fatalf("list sets: %v", err)
+		}
+		if !reflect.DeepEqual(sets, []string{DefaultPackSet, "bulk", "retail"}) {
+			t.Fatalf("sets = %v", sets)
+		}
+
+		other := mustCreate(t, repo, DefaultPackSet, 500)
+		if _, err := repo.Update(ctx, DefaultPackSet, other.ID, 250, models.PackMetadata{}, models.EffectivePeriod{}, 0); !errors.Is(err, ErrConflict) {
+			t.Fatalf("expected ErrConflict on update, got %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		p := mustCreate(t, repo, "retail", 250)
+		if _, err := repo.Get(ctx, DefaultPackSet, p.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get from other set: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.Get(ctx, "retail", p.ID+100); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.Update(ctx, "retail", p.ID+100, 300, models.PackMetadata{}, models.EffectivePeriod{}, 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("update: expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Delete(ctx, "retail", p.ID+100, 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("delete: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.Restore(ctx, "retail", p.ID+100); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("restore: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		p := mustCreate(t, repo, DefaultPackSet, 250)
+		updated, err := repo.Update(ctx, DefaultPackSet, p.ID, 300, models.PackMetadata{}, models.EffectivePeriod{}, p.Version)
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if updated.Size != 300 || updated.Version != 2 {
+			t.Fatalf("updated = %+v", *updated)
+		}
+		if _, err := repo.Update(ctx, DefaultPackSet, p.ID, 350, models.PackMetadata{}, models.EffectivePeriod{}, p.Version); !errors.Is(err, ErrVersionMismatch) {
+			t.Fatalf("stale update: expected ErrVersionMismatch, got %v", err)
+		}
+		if err := repo.Delete(ctx, DefaultPackSet, p.ID, p.Version); !errors.Is(err, ErrVersionMismatch) {
+			t.Fatalf("stale delete: expected ErrVersionMismatch, got %v", err)
+		}
+		if err := repo.Delete(ctx, DefaultPackSet, p.ID, updated.Version); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+	})
+
+	// Against PostgreSQL these writers really race; SQLite and the memory backend serialize them.
+	t.Run("concurrent writes", func(t *testing.T) {
+		repos := open(t)
+		repo := repos.packSizes
+		p := mustCreate(t, repo, DefaultPackSet, 250)
+
+		const writers = 8
+		run := func(write func(i int) error) []error {
+			errs := make([]error, writers)
+			var wg sync.WaitGroup
+			for i := 0; i < writers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs[i] = write(i)
+				}()
+			}
+			wg.Wait()
+			return errs
+		}
+
+		succeeded := 0
+		for _, err := range run(func(i int) error {
+			_, err := repo.Update(ctx, DefaultPackSet, p.ID, 300+i, models.PackMetadata{}, models.EffectivePeriod{}, p.Version)
+			return err
+		}) {
+			switch {
+			case err == nil:
+				succeeded++
+			case !errors.Is(err, ErrVersionMismatch):
+				t.Fatalf("update: expected ErrVersionMismatch, got %v", err)
+			}
+		}
+		if succeeded != 1 {
+			t.Fatalf("%d updates with the same version succeeded, want 1", succeeded)
+		}
+
+		for _, err := range run(func(i int) error {
+			_, err := repo.Create(ctx, DefaultPackSet, 1000+i, models.PackMetadata{}, models.EffectivePeriod{})
+			return err
+		}) {
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+		}
+		versions, err := repos.versions.List(ctx, DefaultPackSet)
+		if err != nil {
+			t.Fatalf("list versions: %v", err)
+		}
+		numbers := make([]int, len(versions))
+		for i, v := range versions {
+			numbers[i] = int(v.Version)
+		}
+		sort.Ints(numbers)
+		if len(numbers) != writers+2 || numbers[0] != 1 || numbers[len(numbers)-1] != writers+2 {
+			t.Fatalf("versions = %v, want 1 to %d", numbers, writers+2)
+		}
+		if got := len(mustList(t, repo, DefaultPackSet)); got != writers+1 {
+			t.Fatalf("pack sizes = %d, want %d", got, writers+1)
+		}
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		meta := models.PackMetadata{
+			Label:       "Carton",
+			SupplierSKU: "SUP-250",
+			GTIN:        "4006381333931",
+			Dimensions:  &models.PackDimensions{LengthMM: 400, WidthMM: 300, HeightMM: 200},
+			WeightGrams: 1500,
+		}
+		p, err := repo.Create(ctx, DefaultPackSet, 250, meta, models.EffectivePeriod{})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		got, err := repo.Get(ctx, DefaultPackSet, p.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if !reflect.DeepEqual(got.PackMetadata, meta) {
+			t.Fatalf("metadata = %+v, want %+v", got.PackMetadata, meta)
+		}
+
+		// An update replaces the metadata; what is left out is cleared.
+		if _, err := repo.Update(ctx, DefaultPackSet, p.ID, 250, models.PackMetadata{Label: "Box"}, models.EffectivePeriod{}, 0); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		packs, err := repo.List(ctx, DefaultPackSet)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(packs) != 1 || !reflect.DeepEqual(packs[0].PackMetadata, models.PackMetadata{Label: "Box"}) {
+			t.Fatalf("packs = %+v", packs)
+		}
+
+		// Bulk changes keep the metadata of the pack sizes they keep.
+		if _, err := repo.Replace(ctx, DefaultPackSet, []int{250, 500}); err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+		packs, _ = repo.List(ctx, DefaultPackSet)
+		if len(packs) != 2 || packs[0].Label != "Box" || packs[1].Label != "" {
+			t.Fatalf("packs after replace = %+v", packs)
+		}
+	})
+
+	t.Run("effective period", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		from := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 6, 0)
+		period := models.EffectivePeriod{EffectiveFrom: &from, EffectiveTo: &to}
+		p, err := repo.Create(ctx, DefaultPackSet, 2500, models.PackMetadata{}, period)
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if !reflect.DeepEqual(p.EffectivePeriod, period) {
+			t.Fatalf("created period = %+v, want %+v", p.EffectivePeriod, period)
+		}
+		got, err := repo.Get(ctx, DefaultPackSet, p.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if !reflect.DeepEqual(got.EffectivePeriod, period) {
+			t.Fatalf("period = %+v, want %+v", got.EffectivePeriod, period)
+		}
+
+		// Scheduled pack sizes are live, whether or not they are in effect yet.
+		packs, err := repo.List(ctx, DefaultPackSet)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(packs) != 1 || !reflect.DeepEqual(packs[0].EffectivePeriod, period) {
+			t.Fatalf("packs = %+v", packs)
+		}
+
+		// An update replaces the period; bounds that are left out are cleared.
+		updated, err := repo.Update(ctx, DefaultPackSet, p.ID, 2500, models.PackMetadata{}, models.EffectivePeriod{EffectiveTo: &to}, 0)
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		got, _ = repo.Get(ctx, DefaultPackSet, p.ID)
+		if got.EffectiveFrom != nil || got.EffectiveTo == nil || !got.EffectiveTo.Equal(to) || !reflect.DeepEqual(got, updated) {
+			t.Fatalf("after update = %+v, returned %+v", got, updated)
+		}
+	})
+
+	t.Run("soft delete and restore", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		p := mustCreate(t, repo, DefaultPackSet, 250)
+		mustCreate(t, repo, DefaultPackSet, 500)
+		if err := repo.Delete(ctx, DefaultPackSet, p.ID, 0); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if got := sizesOf(mustList(t, repo, DefaultPackSet)); !reflect.DeepEqual(got, []int{500}) {
+			t.Fatalf("live sizes = %v", got)
+		}
+		all, err := repo.ListAll(ctx, DefaultPackSet)
+		if err != nil {
+			t.Fatalf("list all: %v", err)
+		}
+		if len(all) != 2 || all[0].ID != p.ID || all[0].DeletedAt == nil || all[1].DeletedAt != nil {
+			t.Fatalf("all = %+v", all)
+		}
+
+		restored, err := repo.Restore(ctx, DefaultPackSet, p.ID)
+		if err != nil {
+			t.Fatalf("restore: %v", err)
+		}
+		if restored.Size != 250 || restored.Version != 3 {
+			t.Fatalf("restored = %+v", *restored)
+		}
+
+		// Once the size has been created again, the deleted row can no longer come back.
+		if err := repo.Delete(ctx, DefaultPackSet, p.ID, 0); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		mustCreate(t, repo, DefaultPackSet, 250)
+		if _, err := repo.Restore(ctx, DefaultPackSet, p.ID); !errors.Is(err, ErrConflict) {
+			t.Fatalf("expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("reset restarts IDs", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		for _, s := range []int{10, 20, 30} {
+			mustCreate(t, repo, DefaultPackSet, s)
+		}
+		sizes, err := repo.ResetToDefault(ctx, DefaultPackSet)
+		if err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+		if !reflect.DeepEqual(sizes, DefaultPackSizes()) {
+			t.Fatalf("reset sizes = %v", sizes)
+		}
+
+		packs := mustList(t, repo, DefaultPackSet)
+		if got := sizesOf(packs); !reflect.DeepEqual(got, DefaultPackSizes()) {
+			t.Fatalf("sizes = %v", got)
+		}
+		for i, p := range packs {
+			if p.ID != int64(i+1) {
+				t.Fatalf("expected IDs to restart from 1, got %+v", packs)
+			}
+		}
+		if p := mustCreate(t, repo, DefaultPackSet, 42); p.ID != int64(len(packs)+1) {
+			t.Fatalf("expected next ID %d, got %d", len(packs)+1, p.ID)
+		}
+
+		// IDs keep counting while another set still holds rows.
+		if _, err := repo.ResetToDefault(ctx, "retail"); err != nil {
+			t.Fatalf("reset retail: %v", err)
+		}
+		if retail := mustList(t, repo, "retail"); retail[0].ID <= int64(len(packs)+1) {
+			t.Fatalf("expected retail IDs after %d, got %+v", len(packs)+1, retail)
+		}
+	})
+
+	t.Run("reset uses configured defaults", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		SetDefaultPackSizes([]int{48, 12, 24})
+		t.Cleanup(func() { SetDefaultPackSizes(nil) })
+
+		sizes, err := repo.ResetToDefault(ctx, DefaultPackSet)
+		if err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+		if !reflect.DeepEqual(sizes, []int{12, 24, 48}) {
+			t.Fatalf("reset sizes = %v", sizes)
+		}
+		if got := sizesOf(mustList(t, repo, DefaultPackSet)); !reflect.DeepEqual(got, []int{12, 24, 48}) {
+			t.Fatalf("sizes = %v", got)
+		}
+	})
+
+	t.Run("validation policy", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		legacy := mustCreate(t, repo, DefaultPackSet, 1)
+		deleted := mustCreate(t, repo, DefaultPackSet, 7)
+		if err := repo.Delete(ctx, DefaultPackSet, deleted.ID, 0); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		SetPackSizePolicy(packpolicy.Policy{MinSize: 10, MaxSizesPerSet: 3, Multiples: []int{10}, Forbidden: []int{666}})
+		t.Cleanup(func() { SetPackSizePolicy(packpolicy.Policy{}) })
+
+		wantViolation := func(t *testing.T, err error, field string) {
+			t.Helper()
+			var violation *packpolicy.Error
+			if !errors.As(err, &violation) || violation.Fields[0].Field != field {
+				t.Fatalf("expected a policy violation of %s, got %v", field, err)
+			}
+		}
+
+		_, err := repo.Create(ctx, DefaultPackSet, 5, models.PackMetadata{}, models.EffectivePeriod{})
+		wantViolation(t, err, "size")
+		mustCreate(t, repo, DefaultPackSet, 250)
+		mustCreate(t, repo, DefaultPackSet, 500)
+		_, err = repo.Create(ctx, DefaultPackSet, 1000, models.PackMetadata{}, models.EffectivePeriod{})
+		wantViolation(t, err, "size")
+
+		// Pack sizes stored before the policy keep working until their size changes.
+		if _, err := repo.Update(ctx, DefaultPackSet, legacy.ID, 1, models.PackMetadata{Label: "Single"}, models.EffectivePeriod{}, 0); err != nil {
+			t.Fatalf("update metadata: %v", err)
+		}
+		_, err = repo.Update(ctx, DefaultPackSet, legacy.ID, 666, models.PackMetadata{}, models.EffectivePeriod{}, 0)
+		wantViolation(t, err, "size")
+		_, err = repo.Restore(ctx, DefaultPackSet, deleted.ID)
+		wantViolation(t, err, "size")
+
+		_, err = repo.Replace(ctx, DefaultPackSet, []int{250, 500, 1000, 2000})
+		wantViolation(t, err, "sizes")
+		_, err = repo.Import(ctx, DefaultPackSet, []int{25}, models.ImportModeMerge, true)
+		wantViolation(t, err, "sizes")
+		if got := sizesOf(mustList(t, repo, DefaultPackSet)); !reflect.DeepEqual(got, []int{1, 250, 500}) {
+			t.Fatalf("rejected changes were applied: %v", got)
+		}
+
+		// Removing the legacy size is fine even though it no longer satisfies the policy.
+		if _, err := repo.Replace(ctx, DefaultPackSet, []int{250, 500, 1000}); err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+
+		SetDefaultPackSizes([]int{1, 250})
+		t.Cleanup(func() { SetDefaultPackSizes(nil) })
+		_, err = repo.ResetToDefault(ctx, DefaultPackSet)
+		wantViolation(t, err, "sizes")
+		if got := sizesOf(mustList(t, repo, DefaultPackSet)); !reflect.DeepEqual(got, []int{250, 500, 1000}) {
+			t.Fatalf("sizes after rejected reset = %v", got)
+		}
+	})
+
+	t.Run("replace and import", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		mustCreate(t, repo, DefaultPackSet, 250)
+		mustCreate(t, repo, DefaultPackSet, 500)
+
+		diff, err := repo.Replace(ctx, DefaultPackSet, []int{500, 1000})
+		if err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+		if got := [3][]int{sizesOf(diff.Added), sizesOf(diff.Removed), sizesOf(diff.Kept)}; !reflect.DeepEqual(got, [3][]int{{1000}, {250}, {500}}) {
+			t.Fatalf("replace diff (added, removed, kept) = %v", got)
+		}
+		if diff.Added[0].ID == 0 {
+			t.Fatalf("expected added pack size to have an ID")
+		}
+
+		diff, err = repo.Import(ctx, DefaultPackSet, []int{250, 1000}, models.ImportModeMerge, true)
+		if err != nil {
+			t.Fatalf("dry run import: %v", err)
+		}
+		if got := sizesOf(diff.Added); !reflect.DeepEqual(got, []int{250}) || diff.Added[0].ID != 0 {
+			t.Fatalf("dry run added = %+v", diff.Added)
+		}
+		if got := sizesOf(mustList(t, repo, DefaultPackSet)); !reflect.DeepEqual(got, []int{500, 1000}) {
+			t.Fatalf("dry run changed sizes: %v", got)
+		}
+
+		if _, err := repo.Import(ctx, DefaultPackSet, []int{250}, models.ImportModeMerge, false); err != nil {
+			t.Fatalf("import: %v", err)
+		}
+		if got := sizesOf(mustList(t, repo, DefaultPackSet)); !reflect.DeepEqual(got, []int{250, 500, 1000}) {
+			t.Fatalf("sizes after import = %v", got)
+		}
+	})
+	runHistoryConformance(t, open)
+	runTenantConformance(t, open)
+}
+
+func runHistoryConformance(t *testing.T, open func(t *testing.T) backendRepos) {
+	ctx := context.Background()
+
+	t.Run("audit and versions are recorded", func(t *testing.T) {
+		repos := open(t)
+
+		p, err := repos.packSizes.Create(ctx, "retail", 250, models.PackMetadata{}, models.EffectivePeriod{})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if _, err := repos.packSizes.Update(ctx, "retail", p.ID, 300, models.PackMetadata{}, models.EffectivePeriod{}, 0); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if err := repos.packSizes.Delete(ctx, "retail", p.ID, 0); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		// A failed mutation leaves no trace.
+		if err := repos.packSizes.Delete(ctx, "retail", p.ID, 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+
+		entries, err := repos.audit.List(ctx, models.AuditFilter{PackSet: "retail"})
+		if err != nil {
+			t.Fatalf("list audit: %v", err)
+		}
+		var actions []string
+		for _, e := range entries {
+			actions = append(actions, e.Action)
+			if e.PackSizeID == nil || *e.PackSizeID != p.ID {
+				t.Fatalf("entry %+v: expected pack size id %d", e, p.ID)
+			}
+		}
+		want := []string{models.AuditActionDelete, models.AuditActionUpdate, models.AuditActionCreate}
+		if !reflect.DeepEqual(actions, want) {
+			t.Fatalf("actions = %v, want %v", actions, want)
+		}
+		if string(entries[2].Before) != "null" || string(entries[0].After) != "null" {
+			t.Fatalf("expected null before on create and after on delete, got %s and %s", entries[2].Before, entries[0].After)
+		}
+		limited, err := repos.audit.List(ctx, models.AuditFilter{Action: models.AuditActionUpdate, Limit: 1})
+		if err != nil || len(limited) != 1 || limited[0].ID != entries[1].ID {
+			t.Fatalf("filtered audit = %+v, %v", limited, err)
+		}
+
+		versions, err := repos.versions.List(ctx, "retail")
+		if err != nil {
+			t.Fatalf("list versions: %v", err)
+		}
+		if len(versions) != 3 {
+			t.Fatalf("expected 3 versions, got %+v", versions)
+		}
+		if got := sizesOf(versions[1].Packs); !reflect.DeepEqual(got, []int{300}) || versions[2].Packs == nil || len(versions[2].Packs) != 0 {
+			t.Fatalf("versions = %+v", versions)
+		}
+		v, err := repos.versions.Get(ctx, "retail", 2)
+		if err != nil || v.Version != 2 {
+			t.Fatalf("get version 2 = %+v, %v", v, err)
+		}
+		if _, err := repos.versions.Get(ctx, "retail", 4); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+		v, err = repos.versions.AsOf(ctx, "retail", versions[2].CreatedAt)
+		if err != nil || v.Version != 3 {
+			t.Fatalf("as of latest = %+v, %v", v, err)
+		}
+		if _, err := repos.versions.AsOf(ctx, "retail", versions[0].CreatedAt.Add(-time.Second)); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound before the first version, got %v", err)
+		}
+	})
+
+	t.Run("products", func(t *testing.T) {
+		repo := open(t).products
+
+		first, err := repo.Create(ctx, models.Product{SKU: "B-1", Name: "Bolts", PackSet: DefaultPackSet})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		second, err := repo.Create(ctx, models.Product{SKU: "A-1", Name: "Anchors", PackSet: "retail"})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if first.ID <= 0 || second.ID <= first.ID {
+			t.Fatalf("expected increasing IDs, got %d then %d", first.ID, second.ID)
+		}
+		if _, err := repo.Create(ctx, models.Product{SKU: "A-1", Name: "Again"}); !errors.Is(err, ErrConflict) {
+			t.Fatalf("expected ErrConflict, got %v", err)
+		}
+
+		products, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(products) != 2 || products[0].SKU != "A-1" || products[1].SKU != "B-1" {
+			t.Fatalf("products = %+v", products)
+		}
+
+		updated, err := repo.Update(ctx, "B-1", models.Product{Name: "Bolts M8", Description: "zinc", PackSet: "retail"})
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if *updated != (models.Product{ID: first.ID, SKU: "B-1", Name: "Bolts M8", Description: "zinc", PackSet: "retail"}) {
+			t.Fatalf("updated = %+v", *updated)
+		}
+		if _, err := repo.Update(ctx, "C-1", models.Product{Name: "x"}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("update: expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Delete(ctx, "B-1"); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := repo.Get(ctx, "B-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get: expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Delete(ctx, "B-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("delete: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("calculations", func(t *testing.T) {
+		repo := open(t).calculations
+
+		start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		clock := start
+		origNow := now
+		now = func() time.Time { return clock }
+		t.Cleanup(func() { now = origNow })
+
+		reqCtx := requestinfo.WithRequestID(ctx, "host/abc-000001")
+		first, err := repo.Record(reqCtx, models.Calculation{
+			Reference: "PO-1", PackSet: DefaultPackSet, Quantity: 251,
+			Request:   json.RawMessage(`{"quantity":251,"reference":"PO-1"}`),
+			Packs:     []models.PackAllocation{{Size: 500, Count: 1, PackMetadata: models.PackMetadata{Label: "Medium"}}},
+			PackSizes: []int{250, 500, 1000}, Shipped: 500, Overage: 249, DurationMicros: 42,
+		})
+		if err != nil {
+			t.Fatalf("record: %v", err)
+		}
+		if first.ID <= 0 || !first.CreatedAt.Equal(start) || first.RequestID != "host/abc-000001" {
+			t.Fatalf("recorded = %+v", first)
+		}
+		clock = start.Add(time.Hour)
+		second, err := repo.Record(ctx, models.Calculation{PackSet: "retail", Version: 3, Quantity: 1, Packs: []models.PackAllocation{{Size: 250, Count: 1}}, PackSizes: []int{250}, Shipped: 250, Overage: 249})
+		if err != nil {
+			t.Fatalf("record: %v", err)
+		}
+		if second.ID <= first.ID {
+			t.Fatalf("expected increasing IDs, got %d then %d", first.ID, second.ID)
+		}
+
+		got, err := repo.Get(ctx, first.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if !reflect.DeepEqual(got, first) {
+			t.Fatalf("get = %+v, want %+v", got, first)
+		}
+		if got, err := repo.Get(ctx, second.ID); err != nil || string(got.Request) != "null" || got.Version != 3 {
+			t.Fatalf("get = %+v, %v", got, err)
+		}
+		if _, err := repo.Get(ctx, second.ID+1); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get: expected ErrNotFound, got %v", err)
+		}
+
+		ids := func(filter models.CalculationFilter) []int64 {
+			t.Helper()
+			calcs, err := repo.List(ctx, filter)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			out := []int64{}
+			for _, c := range calcs {
+				out = append(out, c.ID)
+			}
+			return out
+		}
+		for _, tc := range []struct {
+			name   string
+			filter models.CalculationFilter
+			want   []int64
+		}{
+			{"all, newest first", models.CalculationFilter{}, []int64{second.ID, first.ID}},
+			{"reference", models.CalculationFilter{Reference: "PO-1"}, []int64{first.ID}},
+			{"from is inclusive", models.CalculationFilter{From: start.Add(time.Hour)}, []int64{second.ID}},
+			{"to is exclusive", models.CalculationFilter{To: start.Add(time.Hour)}, []int64{first.ID}},
+			{"limit", models.CalculationFilter{Limit: 1}, []int64{second.ID}},
+		} {
+			if got := ids(tc.filter); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("%s: ids = %v, want %v", tc.name, got, tc.want)
+			}
+		}
+
+		var each []int64
+		err = repo.Each(ctx, models.CalculationFilter{From: start, Limit: 1}, func(c models.Calculation) error {
+			each = append(each, c.ID)
+			return nil
+		})
+		if err != nil || !reflect.DeepEqual(each, []int64{first.ID, second.ID}) {
+			t.Fatalf("each = %v, %v; want oldest first without limit", each, err)
+		}
+		stop := errors.New("stop")
+		each = nil
+		err = repo.Each(ctx, models.CalculationFilter{}, func(c models.Calculation) error {
+			each = append(each, c.ID)
+			return stop
+		})
+		if !errors.Is(err, stop) || len(each) != 1 {
+			t.Fatalf("each = %v, %v; want to stop at the first error", each, err)
+		}
+	})
+
+	t.Run("orders", func(t *testing.T) {
+		repos := open(t)
+		repo := repos.orders
+
+		o, err := repo.Create(ctx, models.Order{
+			Reference: "PO-1", PackSet: DefaultPackSet, Quantity: 251,
+			Packs:   []models.PackAllocation{{Size: 500, Count: 1}},
+			Shipped: 500, Overage: 249,
+		}, &models.Calculation{
+			Reference: "PO-1", PackSet: DefaultPackSet, Quantity: 251,
+			Packs:     []models.PackAllocation{{Size: 500, Count: 1}},
+			PackSizes: []int{250, 500}, Shipped: 500, Overage: 249,
+		})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if o.ID <= 0 || o.Status != models.OrderStatusDraft || o.CreatedAt.IsZero() || !o.UpdatedAt.Equal(o.CreatedAt) {
+			t.Fatalf("created = %+v", o)
+		}
+		if c, err := repos.calculations.Get(ctx, o.CalculationID); err != nil || c.Reference != "PO-1" || c.Shipped != 500 {
+			t.Fatalf("calculation of the order = %+v, %v", c, err)
+		}
+		other, err := repo.Create(ctx, models.Order{PackSet: "retail", Quantity: 1, Packs: []models.PackAllocation{{Size: 250, Count: 1}}}, nil)
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if got, err := repo.Get(ctx, o.ID); err != nil || !reflect.DeepEqual(got, o) {
+			t.Fatalf("get = %+v, %v; want %+v", got, err, o)
+		}
+
+		for _, status := range []string{models.OrderStatusConfirmed, models.OrderStatusPicked, models.OrderStatusShipped} {
+			got, err := repo.Transition(ctx, o.ID, status)
+			if err != nil {
+				t.Fatalf("transition to %s: %v", status, err)
+			}
+			if got.Status != status {
+				t.Fatalf("status = %s, want %s", got.Status, status)
+			}
+		}
+		if _, err := repo.Transition(ctx, o.ID, models.OrderStatusCancelled); !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("cancel shipped order: expected ErrInvalidTransition, got %v", err)
+		}
+		if _, err := repo.Transition(ctx, other.ID, models.OrderStatusPicked); !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("skip confirmed: expected ErrInvalidTransition, got %v", err)
+		}
+		if _, err := repo.Transition(ctx, other.ID, models.OrderStatusCancelled); err != nil {
+			t.Fatalf("cancel draft: %v", err)
+		}
+		if _, err := repo.Transition(ctx, other.ID+1, models.OrderStatusConfirmed); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("transition: expected ErrNotFound, got %v", err)
+		}
+
+		// Against PostgreSQL these transitions really race; only one of them may apply.
+		racy, err := repo.Create(ctx, models.Order{PackSet: DefaultPackSet, Quantity: 1}, nil)
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		errs := make([]error, 8)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = repo.Transition(ctx, racy.ID, models.OrderStatusCancelled)
+			}()
+		}
+		wg.Wait()
+		cancelled := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				cancelled++
+			case !errors.Is(err, ErrInvalidTransition):
+				t.Fatalf("concurrent cancel: expected ErrInvalidTransition, got %v", err)
+			}
+		}
+		if cancelled != 1 {
+			t.Fatalf("%d concurrent cancels succeeded, want 1", cancelled)
+		}
+
+		shipped, err := repo.List(ctx, models.OrderFilter{Status: models.OrderStatusShipped})
+		if err != nil || len(shipped) != 1 || shipped[0].ID != o.ID || shipped[0].Reference != "PO-1" {
+			t.Fatalf("list shipped = %+v, %v", shipped, err)
+		}
+		all, err := repo.List(ctx, models.OrderFilter{})
+		if err != nil || len(all) != 3 || all[1].ID != other.ID {
+			t.Fatalf("list = %+v, %v", all, err)
+		}
+	})
+
+	t.Run("idempotency keys", func(t *testing.T) {
+		repo := open(t).idempotency
+
+		start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		clock := start
+		origNow := now
+		now = func() time.Time { return clock }
+		t.Cleanup(func() { now = origNow })
+		expiredBefore, staleBefore := start.Add(-time.Hour), start.Add(-time.Minute)
+
+		if rec, err := repo.Begin(ctx, "k1", "fp1", expiredBefore, staleBefore); err != nil || rec != nil {
+			t.Fatalf("begin = %+v, %v", rec, err)
+		}
+		if _, err := repo.Begin(ctx, "k1", "fp1", expiredBefore, staleBefore); !errors.Is(err, ErrRequestInProgress) {
+			t.Fatalf("begin while in progress: expected ErrRequestInProgress, got %v", err)
+		}
+		if err := repo.Complete(ctx, "k1", 201, "application/json", `"1-1"`, []byte(`{"data":1}`)); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		rec, err := repo.Begin(ctx, "k1", "fp1", expiredBefore, staleBefore)
+		if err != nil || rec == nil || rec.Status != 201 || rec.ContentType != "application/json" || rec.ETag != `"1-1"` || string(rec.Body) != `{"data":1}` {
+			t.Fatalf("replay = %+v, %v", rec, err)
+		}
+		if _, err := repo.Begin(ctx, "k1", "fp2", expiredBefore, staleBefore); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+			t.Fatalf("begin with another request: expected ErrIdempotencyKeyMismatch, got %v", err)
+		}
+
+		if rec, err := repo.Begin(ctx, "k2", "fp1", expiredBefore, staleBefore); err != nil || rec != nil {
+			t.Fatalf("begin = %+v, %v", rec, err)
+		}
+		if err := repo.Release(ctx, "k2"); err != nil {
+			t.Fatalf("release: %v", err)
+		}
+		if rec, err := repo.Begin(ctx, "k2", "fp2", expiredBefore, staleBefore); err != nil || rec != nil {
+			t.Fatalf("begin after release = %+v, %v", rec, err)
+		}
+
+		// k2 was claimed at start and never completed.
+		clock = start.Add(2 * time.Minute)
+		staleBefore = clock.Add(-time.Minute)
+		if _, err := repo.Begin(ctx, "k2", "fp1", expiredBefore, staleBefore); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+			t.Fatalf("take over by another request: expected ErrIdempotencyKeyMismatch, got %v", err)
+		}
+		if rec, err := repo.Begin(ctx, "k2", "fp2", expiredBefore, staleBefore); err != nil || rec != nil {
+			t.Fatalf("take over a stale claim = %+v, %v", rec, err)
+		}
+		if _, err := repo.Begin(ctx, "k2", "fp2", expiredBefore, staleBefore); !errors.Is(err, ErrRequestInProgress) {
+			t.Fatalf("begin after take over: expected ErrRequestInProgress, got %v", err)
+		}
+		if rec, err := repo.Begin(ctx, "k1", "fp1", expiredBefore, staleBefore); err != nil || rec == nil || rec.Status != 201 {
+			t.Fatalf("completed keys never go stale = %+v, %v", rec, err)
+		}
+
+		clock = start.Add(2 * time.Hour)
+		if rec, err := repo.Begin(ctx, "k1", "fp2", start.Add(time.Minute), clock.Add(-time.Minute)); err != nil || rec != nil {
+			t.Fatalf("begin after expiry = %+v, %v", rec, err)
+		}
+	})
+
+	t.Run("inventory", func(t *testing.T) {
+		repos := open(t)
+		repo := repos.inventory
+
+		if stock, err := repo.Stock(ctx, DefaultPackSet); err != nil || len(stock) != 0 {
+			t.Fatalf("empty stock = %+v, %v", stock, err)
+		}
+		e, err := repo.Move(ctx, DefaultPackSet, 500, models.LedgerKindReceipt, 3, "delivery 1")
+		if err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if e.ID <= 0 || e.Quantity != 3 || e.Balance != 3 || e.Note != "delivery 1" || e.OrderID != nil || e.CreatedAt.IsZero() {
+			t.Fatalf("receipt = %+v", e)
+		}
+		if _, err := repo.Move(ctx, DefaultPackSet, 250, models.LedgerKindReceipt, 1, ""); err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if _, err := repo.Move(ctx, DefaultPackSet, 500, models.LedgerKindAdjustment, -4, ""); !errors.Is(err, ErrInsufficientStock) {
+			t.Fatalf("take out too many: expected ErrInsufficientStock, got %v", err)
+		}
+		if _, err := repo.Move(ctx, DefaultPackSet, 1000, models.LedgerKindAdjustment, -1, ""); !errors.Is(err, ErrInsufficientStock) {
+			t.Fatalf("take out never stocked: expected ErrInsufficientStock, got %v", err)
+		}
+		if e, err := repo.Move(ctx, DefaultPackSet, 500, models.LedgerKindAdjustment, -1, "damaged"); err != nil || e.Balance != 2 {
+			t.Fatalf("adjust = %+v, %v", e, err)
+		}
+
+		// The order needs more 250 packs than are on hand, so confirming it deducts nothing.
+		o, err := repos.orders.Create(ctx, models.Order{PackSet: DefaultPackSet, Quantity: 1000,
+			Packs: []models.PackAllocation{{Size: 500, Count: 1}, {Size: 250, Count: 2}}}, nil)
+		if err != nil {
+			t.Fatalf("create order: %v", err)
+		}
+		if _, err := repos.orders.Transition(ctx, o.ID, models.OrderStatusConfirmed); !errors.Is(err, ErrInsufficientStock) {
+			t.Fatalf("confirm: expected ErrInsufficientStock, got %v", err)
+		}
+		if got, err := repos.orders.Get(ctx, o.ID); err != nil || got.Status != models.OrderStatusDraft {
+			t.Fatalf("order after failed confirm = %+v, %v", got, err)
+		}
+		if _, err := repo.Move(ctx, DefaultPackSet, 250, models.LedgerKindReceipt, 1, ""); err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if _, err := repos.orders.Transition(ctx, o.ID, models.OrderStatusConfirmed); err != nil {
+			t.Fatalf("confirm: %v", err)
+		}
+
+		levels := func() map[int]int {
+			t.Helper()
+			stock, err := repo.Stock(ctx, DefaultPackSet)
+			if err != nil {
+				t.Fatalf("stock: %v", err)
+			}
+			out := map[int]int{}
+			for _, l := range stock {
+				if l.PackSet != DefaultPackSet || l.UpdatedAt == nil {
+					t.Fatalf("stock level = %+v", l)
+				}
+				out[l.Size] = l.OnHand
+			}
+			return out
+		}
+		if got, want := levels(), map[int]int{250: 0, 500: 1}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("stock after confirm = %v, want %v", got, want)
+		}
+		if _, err := repos.orders.Transition(ctx, o.ID, models.OrderStatusCancelled); err != nil {
+			t.Fatalf("cancel: %v", err)
+		}
+		if got, want := levels(), map[int]int{250: 2, 500: 2}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("stock after cancel = %v, want %v", got, want)
+		}
+
+		// Sizes that were never stocked are not tracked: confirming skips them, and cancelling only
+		// puts back what was deducted even if they were stocked in the meantime.
+		untracked, err := repos.orders.Create(ctx, models.Order{PackSet: DefaultPackSet, Quantity: 2250,
+			Packs: []models.PackAllocation{{Size: 2000, Count: 1}, {Size: 250, Count: 1}}}, nil)
+		if err != nil {
+			t.Fatalf("create order: %v", err)
+		}
+		if _, err := repos.orders.Transition(ctx, untracked.ID, models.OrderStatusConfirmed); err != nil {
+			t.Fatalf("confirm with an untracked size: %v", err)
+		}
+		if _, err := repo.Move(ctx, DefaultPackSet, 2000, models.LedgerKindReceipt, 1, ""); err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if _, err := repos.orders.Transition(ctx, untracked.ID, models.OrderStatusCancelled); err != nil {
+			t.Fatalf("cancel: %v", err)
+		}
+		if got, want := levels(), map[int]int{250: 2, 500: 2, 2000: 1}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("stock after cancelling an order with an untracked size = %v, want %v", got, want)
+		}
+		if entries, err := repo.Ledger(ctx, models.LedgerFilter{OrderID: untracked.ID}); err != nil || len(entries) != 2 ||
+			entries[0].Size != 250 || entries[0].Kind != models.LedgerKindRelease || entries[1].Kind != models.LedgerKindDeduction {
+			t.Fatalf("ledger of order with an untracked size = %+v, %v", entries, err)
+		}
+
+		byOrder, err := repo.Ledger(ctx, models.LedgerFilter{OrderID: o.ID})
+		if err != nil || len(byOrder) != 4 {
+			t.Fatalf("ledger of order = %+v, %v", byOrder, err)
+		}
+		if byOrder[0].Kind != models.LedgerKindRelease || byOrder[3].Kind != models.LedgerKindDeduction || byOrder[3].Quantity >= 0 ||
+			byOrder[3].OrderID == nil || *byOrder[3].OrderID != o.ID {
+			t.Fatalf("ledger of order = %+v", byOrder)
+		}
+		if entries, err := repo.Ledger(ctx, models.LedgerFilter{Size: 500, Kind: models.LedgerKindAdjustment}); err != nil ||
+			len(entries) != 1 || entries[0].Quantity != -1 || entries[0].Note != "damaged" {
+			t.Fatalf("ledger of adjustments = %+v, %v", entries, err)
+		}
+		if entries, err := repo.Ledger(ctx, models.LedgerFilter{Limit: 2}); err != nil || len(entries) != 2 || entries[0].ID <= entries[1].ID {
+			t.Fatalf("limited ledger = %+v, %v", entries, err)
+		}
+		if entries, err := repo.Ledger(ctx, models.LedgerFilter{PackSet: "retail"}); err != nil || len(entries) != 0 {
+			t.Fatalf("ledger of another set = %+v, %v", entries, err)
+		}
+
+		// Concurrent confirms of one order deduct its packs once.
+		racy, err := repos.orders.Create(ctx, models.Order{PackSet: DefaultPackSet, Quantity: 500,
+			Packs: []models.PackAllocation{{Size: 500, Count: 1}}}, nil)
+		if err != nil {
+			t.Fatalf("create order: %v", err)
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = repos.orders.Transition(ctx, racy.ID, models.OrderStatusConfirmed)
+			}()
+		}
+		wg.Wait()
+		if got := levels()[500]; got != 1 {
+			t.Fatalf("500 packs on hand after concurrent confirms = %d, want 1", got)
+		}
+	})
+}
+
+// runTenantConformance checks that a tenant can never read or modify the data of another one.
+func runTenantConformance(t *testing.T, open func(t *testing.T) backendRepos) {
+	acme := requestinfo.WithTenant(context.Background(), "acme")
+	globex := requestinfo.WithTenant(context.Background(), "globex")
+
+	t.Run("pack sizes are isolated per tenant", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		p, err := repo.Create(acme, DefaultPackSet, 250, models.PackMetadata{Label: "Acme"}, models.EffectivePeriod{})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		deleted, err := repo.Create(acme, "retail", 6, models.PackMetadata{}, models.EffectivePeriod{})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if err := repo.Delete(acme, "retail", deleted.ID, 0); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		// Listed first so that a cache keyed by pack set alone would leak it.
+		if packs, err := repo.List(acme, DefaultPackSet); err != nil || len(packs) != 1 {
+			t.Fatalf("acme list = %+v, %v", packs, err)
+		}
+
+		for _, ctx := range []context.Context{globex, context.Background()} {
+			tenant := requestinfo.Tenant(ctx)
+			if packs, err := repo.List(ctx, DefaultPackSet); err != nil || len(packs) != 0 {
+				t.Fatalf("%s: list = %+v, %v", tenant, packs, err)
+			}
+			if packs, err := repo.ListAll(ctx, "retail"); err != nil || len(packs) != 0 {
+				t.Fatalf("%s: list all = %+v, %v", tenant, packs, err)
+			}
+			if sets, err := repo.ListSets(ctx); err != nil || !reflect.DeepEqual(sets, []string{DefaultPackSet}) {
+				t.Fatalf("%s: sets = %v, %v", tenant, sets, err)
+			}
+			if _, err := repo.Get(ctx, DefaultPackSet, p.ID); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("%s: get: expected ErrNotFound, got %v", tenant, err)
+			}
+			if _, err := repo.Update(ctx, DefaultPackSet, p.ID, 300, models.PackMetadata{}, models.EffectivePeriod{}, 0); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("%s: update: expected ErrNotFound, got %v", tenant, err)
+			}
+			if err := repo.Delete(ctx, DefaultPackSet, p.ID, 0); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("%s: delete: expected ErrNotFound, got %v", tenant, err)
+			}
+			if _, err := repo.Restore(ctx, "retail", deleted.ID); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("%s: restore: expected ErrNotFound, got %v", tenant, err)
+			}
+		}
+
+		// Sizes are unique per tenant, and bulk changes stay within the tenant.
+		own, err := repo.Create(globex, DefaultPackSet, 250, models.PackMetadata{}, models.EffectivePeriod{})
+		if err != nil {
+			t.Fatalf("create the same size for another tenant: %v", err)
+		}
+		if own.ID == p.ID {
+			t.Fatalf("expected a new ID, got %d", own.ID)
+		}
+		if _, err := repo.Replace(globex, DefaultPackSet, []int{1000}); err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+		if _, err := repo.Import(globex, "retail", []int{6}, models.ImportModeReplace, false); err != nil {
+			t.Fatalf("import: %v", err)
+		}
+		if _, err := repo.ResetToDefault(globex, "bulk"); err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+
+		got, err := repo.Get(acme, DefaultPackSet, p.ID)
+		if err != nil || *got != *p {
+			t.Fatalf("acme pack size = %+v, %v; want %+v", got, err, *p)
+		}
+		if all, err := repo.ListAll(acme, "retail"); err != nil || len(all) != 1 || all[0].DeletedAt == nil {
+			t.Fatalf("acme retail = %+v, %v", all, err)
+		}
+		if sets, err := repo.ListSets(acme); err != nil || !reflect.DeepEqual(sets, []string{DefaultPackSet}) {
+			t.Fatalf("acme sets = %v, %v", sets, err)
+		}
+	})
+
+	t.Run("history is isolated per tenant", func(t *testing.T) {
+		repos := open(t)
+
+		if _, err := repos.packSizes.Create(acme, "retail", 6, models.PackMetadata{}, models.EffectivePeriod{}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if _, err := repos.packSizes.Create(globex, "retail", 12, models.PackMetadata{}, models.EffectivePeriod{}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		entries, err := repos.audit.List(globex, models.AuditFilter{})
+		if err != nil || len(entries) != 1 || !strings.Contains(string(entries[0].After), `"size":12`) {
+			t.Fatalf("globex audit = %+v, %v", entries, err)
+		}
+		if entries, err := repos.audit.List(context.Background(), models.AuditFilter{}); err != nil || len(entries) != 0 {
+			t.Fatalf("default tenant audit = %+v, %v", entries, err)
+		}
+
+		versions, err := repos.versions.List(globex, "retail")
+		if err != nil || len(versions) != 1 || !reflect.DeepEqual(sizesOf(versions[0].Packs), []int{12}) {
+			t.Fatalf("globex versions = %+v, %v", versions, err)
+		}
+		if _, err := repos.versions.Get(context.Background(), "retail", 1); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get version: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repos.versions.AsOf(context.Background(), "retail", now()); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("as of: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("products are isolated per tenant", func(t *testing.T) {
+		repo := open(t).products
+
+		if _, err := repo.Create(acme, models.Product{SKU: "P-1", Name: "Acme bolts"}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if _, err := repo.Get(globex, "P-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.Update(globex, "P-1", models.Product{Name: "Stolen"}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("update: expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Delete(globex, "P-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("delete: expected ErrNotFound, got %v", err)
+		}
+		if products, err := repo.List(globex); err != nil || len(products) != 0 {
+			t.Fatalf("list = %+v, %v", products, err)
+		}
+		if _, err := repo.Create(globex, models.Product{SKU: "P-1", Name: "Globex bolts"}); err != nil {
+			t.Fatalf("create the same SKU for another tenant: %v", err)
+		}
+
+		p, err := repo.Get(acme, "P-1")
+		if err != nil || p.Name != "Acme bolts" {
+			t.Fatalf("acme product = %+v, %v", p, err)
+		}
+	})
+
+	t.Run("calculations are isolated per tenant", func(t *testing.T) {
+		repo := open(t).calculations
+
+		c, err := repo.Record(acme, models.Calculation{Reference: "PO-1", PackSet: DefaultPackSet, Quantity: 1})
+		if err != nil {
+			t.Fatalf("record: %v", err)
+		}
+		if _, err := repo.Get(globex, c.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get: expected ErrNotFound, got %v", err)
+		}
+		if calcs, err := repo.List(globex, models.CalculationFilter{Reference: "PO-1"}); err != nil || len(calcs) != 0 {
+			t.Fatalf("list = %+v, %v", calcs, err)
+		}
+	})
+
+	t.Run("orders are isolated per tenant", func(t *testing.T) {
+		repo := open(t).orders
+
+		o, err := repo.Create(acme, models.Order{Reference: "PO-1", PackSet: DefaultPackSet, Quantity: 1}, nil)
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if _, err := repo.Get(globex, o.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.Transition(globex, o.ID, models.OrderStatusCancelled); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("transition: expected ErrNotFound, got %v", err)
+		}
+		if orders, err := repo.List(globex, models.OrderFilter{}); err != nil || len(orders) != 0 {
+			t.Fatalf("list = %+v, %v", orders, err)
+		}
+	})
+
+	t.Run("inventory is isolated per tenant", func(t *testing.T) {
+		repo := open(t).inventory
+
+		if _, err := repo.Move(acme, DefaultPackSet, 250, models.LedgerKindReceipt, 5, ""); err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if _, err := repo.Move(globex, DefaultPackSet, 250, models.LedgerKindAdjustment, -1, ""); !errors.Is(err, ErrInsufficientStock) {
+			t.Fatalf("take out: expected ErrInsufficientStock, got %v", err)
+		}
+		if stock, err := repo.Stock(globex, DefaultPackSet); err != nil || len(stock) != 0 {
+			t.Fatalf("stock = %+v, %v", stock, err)
+		}
+		if entries, err := repo.Ledger(globex, models.LedgerFilter{}); err != nil || len(entries) != 0 {
+			t.Fatalf("ledger = %+v, %v", entries, err)
+		}
+	})
+
+	t.Run("idempotency keys are isolated per tenant", func(t *testing.T) {
+		repo := open(t).idempotency
+
+		if _, err := repo.Begin(acme, "k1", "fp1", time.Time{}, time.Time{}); err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		if rec, err := repo.Begin(globex, "k1", "fp2", time.Time{}, time.Time{}); err != nil || rec != nil {
+			t.Fatalf("begin the same key for another tenant = %+v, %v", rec, err)
+		}
+	})
+
+	t.Run("reset uses per-tenant defaults", func(t *testing.T) {
+		repo := open(t).packSizes
+
+		SetTenantDefaultPackSizes(map[string][]int{"acme": {12, 6}})
+		t.Cleanup(func() { SetTenantDefaultPackSizes(nil) })
+
+		if sizes, err := repo.ResetToDefault(acme, DefaultPackSet); err != nil || !reflect.DeepEqual(sizes, []int{6, 12}) {
+			t.Fatalf("acme reset = %v, %v", sizes, err)
+		}
+		if sizes, err := repo.ResetToDefault(globex, DefaultPackSet); err != nil || !reflect.DeepEqual(sizes, DefaultPackSizes()) {
+			t.Fatalf("globex reset = %v, %v", sizes, err)
+		}
+		if packs, err := repo.List(acme, DefaultPackSet); err != nil || !reflect.DeepEqual(sizesOf(packs), []int{6, 12}) {
+			t.Fatalf("acme sizes = %v, %v", sizesOf(packs), err)
+		}
+	})
+}
+
+func sizesOf(packs []models.PackSize) []int {
+	out := make([]int, 0, len(packs))
+	for _, p := range packs {
+		out = append(out, p.Size)
+	}
+	return out
+}
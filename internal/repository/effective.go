@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+// InEffect reports whether a pack size with the given effective period is used at t.
+func InEffect(period models.EffectivePeriod, t time.Time) bool {
+	if period.EffectiveFrom != nil && t.Before(*period.EffectiveFrom) {
+		return false
+	}
+	return period.EffectiveTo == nil || t.Before(*period.EffectiveTo)
+}
+
+// EffectivePackSizes returns the pack sizes of packs that are in effect at t, keeping their order.
+func EffectivePackSizes(packs []models.PackSize, t time.Time) []models.PackSize {
+	var out []models.PackSize
+	for _, p := range packs {
+		if InEffect(p.EffectivePeriod, t) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// UpcomingChanges lists the changes scheduled on packs after t, ordered by when they take effect
+// and then by size. A zero until includes every change, otherwise only those before until.
+func UpcomingChanges(packs []models.PackSize, t, until time.Time) []models.PackSizeChange {
+	out := []models.PackSizeChange{}
+	add := func(at *time.Time, change string, p models.PackSize) {
+		if at != nil && at.After(t) && (until.IsZero() || at.Before(until)) {
+			out = append(out, models.PackSizeChange{EffectiveAt: *at, Change: change, Pack: p})
+		}
+	}
+	for _, p := range packs {
+		add(p.EffectiveFrom, models.PackSizeChangeStart, p)
+		add(p.EffectiveTo, models.PackSizeChangeEnd, p)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].EffectiveAt.Equal(out[j].EffectiveAt) {
+			return out[i].EffectiveAt.Before(out[j].EffectiveAt)
+		}
+		return out[i].Pack.Size < out[j].Pack.Size
+	})
+	return out
+}
+
+// storedPeriod returns period with its own copies of the bounds, rounded the way they are stored.
+func storedPeriod(period models.EffectivePeriod) models.EffectivePeriod {
+	if period.EffectiveFrom != nil {
+		t := storedTime(*period.EffectiveFrom)
+		period.EffectiveFrom = &t
+	}
+	if period.EffectiveTo != nil {
+		t := storedTime(*period.EffectiveTo)
+		period.EffectiveTo = &t
+	}
+	return period
+}
+
+// periodColumns returns the values stored for period; NULL leaves a bound open.
+func periodColumns(period models.EffectivePeriod) (from, to any) {
+	if period.EffectiveFrom != nil {
+		from = formatTime(*period.EffectiveFrom)
+	}
+	if period.EffectiveTo != nil {
+		to = formatTime(*period.EffectiveTo)
+	}
+	return from, to
+}
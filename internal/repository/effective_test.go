@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func TestEffectivePackSizes(t *testing.T) {
+	march := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+	packs := []models.PackSize{
+		{ID: 1, Size: 250},
+		{ID: 2, Size: 2000, EffectivePeriod: models.EffectivePeriod{EffectiveTo: &march}},
+		{ID: 3, Size: 2500, EffectivePeriod: models.EffectivePeriod{EffectiveFrom: &march}},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want []int
+	}{
+		{"before the change", march.Add(-time.Second), []int{250, 2000}},
+		{"from is inclusive and to exclusive", march, []int{250, 2500}},
+		{"after the change", march.AddDate(1, 0, 0), []int{250, 2500}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sizesOf(EffectivePackSizes(packs, tc.at)); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("sizes = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpcomingChanges(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	past, march, june := now.AddDate(0, -1, 0), now.AddDate(0, 2, 0), now.AddDate(0, 5, 0)
+	packs := []models.PackSize{
+		{ID: 1, Size: 250, EffectivePeriod: models.EffectivePeriod{EffectiveFrom: &past, EffectiveTo: &june}},
+		{ID: 2, Size: 2000, EffectivePeriod: models.EffectivePeriod{EffectiveTo: &march}},
+		{ID: 3, Size: 2500, EffectivePeriod: models.EffectivePeriod{EffectiveFrom: &march}},
+	}
+
+	type change struct {
+		at     time.Time
+		change string
+		size   int
+	}
+	summarize := func(changes []models.PackSizeChange) []change {
+		out := []change{}
+		for _, c := range changes {
+			out = append(out, change{c.EffectiveAt, c.Change, c.Pack.Size})
+		}
+		return out
+	}
+
+	got := summarize(UpcomingChanges(packs, now, time.Time{}))
+	want := []change{
+		{march, models.PackSizeChangeEnd, 2000},
+		{march, models.PackSizeChangeStart, 2500},
+		{june, models.PackSizeChangeEnd, 250},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes = %+v, want %+v", got, want)
+	}
+
+	if got := summarize(UpcomingChanges(packs, now, june)); len(got) != 2 {
+		t.Fatalf("changes before june = %+v", got)
+	}
+	if got := UpcomingChanges(packs, june, time.Time{}); len(got) != 0 {
+		t.Fatalf("changes after june = %+v", got)
+	}
+}
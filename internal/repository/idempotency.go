@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+var (
+	// ErrIdempotencyKeyMismatch is returned when an idempotency key is reused for a different request.
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key was used for a different request")
+	// ErrRequestInProgress is returned while the first request sent with an idempotency key is
+	// still being processed.
+	ErrRequestInProgress = errors.New("request with this idempotency key is in progress")
+
+	// errStaleClaim means the request that claimed a key never completed it and the claim can be
+	// taken over.
+	errStaleClaim = errors.New("stale idempotency key claim")
+)
+
+// IdempotencyRepository stores the responses of requests sent with an Idempotency-Key header, per
+// tenant in ctx.
+type IdempotencyRepository interface {
+	// Begin claims key for the request identified by fingerprint. Keys created before
+	// expiredBefore are forgotten first. When the key was already used for the same request, the
+	// stored record is returned and nothing is claimed; a different request gets
+	// ErrIdempotencyKeyMismatch and a request that has not completed yet ErrRequestInProgress.
+	// A claim made before staleBefore that never completed (e.g. the process died meanwhile) is
+	// taken over by the same request.
+	Begin(ctx context.Context, key, fingerprint string, expiredBefore, staleBefore time.Time) (*models.IdempotencyRecord, error)
+	// Complete stores the response of the request that claimed key, with its Content-Type and ETag
+	// headers.
+	Complete(ctx context.Context, key string, status int, contentType, etag string, body []byte) error
+	// Release forgets a claimed key, e.g. after a failure the request should be retried for.
+	Release(ctx context.Context, key string) error
+}
+
+type sqlIdempotencyRepository struct{}
+
+var idempotencyRepo IdempotencyRepository = &sqlIdempotencyRepository{}
+
+func IdempotencyKeys() IdempotencyRepository {
+	return idempotencyRepo
+}
+
+// SetIdempotencyRepository swaps the repository implementation (primarily for tests).
+func SetIdempotencyRepository(repo IdempotencyRepository) {
+	if repo == nil {
+		panic("IdempotencyRepository must not be nil")
+	}
+	idempotencyRepo = repo
+}
+
+// checkIdempotencyRecord decides what to do with the existing record of a key.
+func checkIdempotencyRecord(rec *models.IdempotencyRecord, fingerprint string, staleBefore time.Time) (*models.IdempotencyRecord, error) {
+	switch {
+	case rec.Fingerprint != fingerprint:
+		return nil, fmt.Errorf("%w", ErrIdempotencyKeyMismatch)
+	case rec.Status == 0 && rec.CreatedAt.Before(staleBefore):
+		return nil, errStaleClaim
+	case rec.Status == 0:
+		return nil, fmt.Errorf("%w", ErrRequestInProgress)
+	}
+	return rec, nil
+}
+
+func (r *sqlIdempotencyRepository) Begin(ctx context.Context, key, fingerprint string, expiredBefore, staleBefore time.Time) (*models.IdempotencyRecord, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction at idempotency key: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, db.Rebind(`DELETE FROM idempotency_keys WHERE created_at < ?`), formatTime(expiredBefore)); err != nil {
+		return nil, fmt.Errorf("delete expired idempotency keys: %w", err)
+	}
+
+	var (
+		rec       = models.IdempotencyRecord{Key: key}
+		body      string
+		createdAt string
+	)
+	err = tx.QueryRowContext(ctx, db.Rebind(`
+	SELECT fingerprint, status, content_type, etag, body, created_at FROM idempotency_keys WHERE tenant = ? AND idempotency_key = ?`),
+		requestinfo.Tenant(ctx), key).Scan(&rec.Fingerprint, &rec.Status, &rec.ContentType, &rec.ETag, &body, &createdAt)
+	switch {
+	case err == nil:
+		if rec.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, fmt.Errorf("parse idempotency key time: %w", err)
+		}
+		rec.Body = []byte(body)
+		stored, err := checkIdempotencyRecord(&rec, fingerprint, staleBefore)
+		if !errors.Is(err, errStaleClaim) {
+			return stored, err
+		}
+		// Only one of several retries may take the claim over.
+		res, err := tx.ExecContext(ctx, db.Rebind(`
+		UPDATE idempotency_keys SET created_at = ?
+		WHERE tenant = ? AND idempotency_key = ? AND status = 0 AND created_at = ?`),
+			formatTime(now()), requestinfo.Tenant(ctx), key, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("take over idempotency key: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("take over idempotency key: %w", err)
+		} else if n == 0 {
+			return nil, fmt.Errorf("%w", ErrRequestInProgress)
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	default:
+		_, err = tx.ExecContext(ctx, db.Rebind(`
+		INSERT INTO idempotency_keys(tenant, idempotency_key, fingerprint, created_at) VALUES(?, ?, ?, ?)`),
+			requestinfo.Tenant(ctx), key, fingerprint, formatTime(now()))
+		if err != nil {
+			if isUniqueViolation(err) {
+				return nil, fmt.Errorf("%w", ErrRequestInProgress)
+			}
+			return nil, fmt.Errorf("insert idempotency key: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing idempotency key: %w", err)
+	}
+	return nil, nil
+}
+
+func (r *sqlIdempotencyRepository) Complete(ctx context.Context, key string, status int, contentType, etag string, body []byte) error {
+	conn, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	_, err = conn.ExecContext(ctx, db.Rebind(`
+	UPDATE idempotency_keys SET status = ?, content_type = ?, etag = ?, body = ? WHERE tenant = ? AND idempotency_key = ?`),
+		status, contentType, etag, string(body), requestinfo.Tenant(ctx), key)
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlIdempotencyRepository) Release(ctx context.Context, key string) error {
+	conn, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	_, err = conn.ExecContext(ctx, db.Rebind(`DELETE FROM idempotency_keys WHERE tenant = ? AND idempotency_key = ?`),
+		requestinfo.Tenant(ctx), key)
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
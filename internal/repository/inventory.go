@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+// DefaultLedgerLimit caps the number of ledger entries returned when the filter sets no limit.
+const DefaultLedgerLimit = 100
+
+// ErrInsufficientStock is returned when a change would leave fewer than zero packs on hand.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// InventoryRepository tracks the packs on hand of the tenant in ctx. Every change of a stock level
+// is written to the ledger in the same transaction.
+type InventoryRepository interface {
+	// Stock returns the stock levels of set ordered by size. Sizes that were never stocked are
+	// left out.
+	Stock(ctx context.Context, set string) ([]models.StockLevel, error)
+	// Move changes the packs on hand of a size by quantity, which is negative to take packs out,
+	// and returns the ledger entry it recorded. It returns ErrInsufficientStock instead of leaving
+	// fewer than zero packs on hand.
+	Move(ctx context.Context, set string, size int, kind string, quantity int, note string) (*models.LedgerEntry, error)
+	// Ledger returns the newest ledger entries matching filter first.
+	Ledger(ctx context.Context, filter models.LedgerFilter) ([]models.LedgerEntry, error)
+}
+
+type sqlInventoryRepository struct{}
+
+var inventoryRepo InventoryRepository = &sqlInventoryRepository{}
+
+func Inventory() InventoryRepository {
+	return inventoryRepo
+}
+
+// SetInventoryRepository swaps the repository implementation (primarily for tests).
+func SetInventoryRepository(repo InventoryRepository) {
+	if repo == nil {
+		panic("InventoryRepository must not be nil")
+	}
+	inventoryRepo = repo
+}
+
+// orderStockKind returns the kind of ledger entries an order status change records for the packs
+// of the order, or "" when stock is not affected: confirming an order takes its packs out of
+// stock and cancelling it afterwards puts them back.
+func orderStockKind(from, to string) string {
+	switch {
+	case to == models.OrderStatusConfirmed:
+		return models.LedgerKindDeduction
+	case to == models.OrderStatusCancelled && (from == models.OrderStatusConfirmed || from == models.OrderStatusPicked):
+		return models.LedgerKindRelease
+	}
+	return ""
+}
+
+func (r *sqlInventoryRepository) Stock(ctx context.Context, set string) ([]models.StockLevel, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, db.Rebind(`
+	SELECT size, on_hand, updated_at FROM inventory_stock WHERE tenant = ? AND pack_set = ? ORDER BY size ASC`),
+		requestinfo.Tenant(ctx), set)
+	if err != nil {
+		return nil, fmt.Errorf("list stock levels: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []models.StockLevel{}
+	for rows.Next() {
+		var (
+			l         = models.StockLevel{PackSet: set}
+			updatedAt string
+		)
+		if err := rows.Scan(&l.Size, &l.OnHand, &updatedAt); err != nil {
+			return nil, fmt.Errorf("scan stock level: %w", err)
+		}
+		t, err := parseTime(updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("parse stock level time: %w", err)
+		}
+		l.UpdatedAt = &t
+		out = append(out, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate stock levels: %w", err)
+	}
+	return out, nil
+}
+
+func (r *sqlInventoryRepository) Move(ctx context.Context, set string, size int, kind string, quantity int, note string) (*models.LedgerEntry, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction at stock move: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	e, err := moveStock(ctx, tx, set, size, kind, quantity, note, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing stock move: %w", err)
+	}
+	return e, nil
+}
+
+func (r *sqlInventoryRepository) Ledger(ctx context.Context, filter models.LedgerFilter) ([]models.LedgerEntry, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	where := []string{"tenant = ?"}
+	args := []any{requestinfo.Tenant(ctx)}
+	if filter.PackSet != "" {
+		where = append(where, "pack_set = ?")
+		args = append(args, filter.PackSet)
+	}
+	if filter.Size != 0 {
+		where = append(where, "size = ?")
+		args = append(args, filter.Size)
+	}
+	if filter.Kind != "" {
+		where = append(where, "kind = ?")
+		args = append(args, filter.Kind)
+	}
+	if filter.OrderID != 0 {
+		where = append(where, "order_id = ?")
+		args = append(args, filter.OrderID)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, formatTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, formatTime(filter.To))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLedgerLimit
+	}
+
+	query := `SELECT id, created_at, actor, request_id, pack_set, size, kind, quantity, balance, order_id, note
+	FROM inventory_ledger WHERE ` + strings.Join(where, " AND ")
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := conn.QueryContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list ledger entries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []models.LedgerEntry{}
+	for rows.Next() {
+		var (
+			e         models.LedgerEntry
+			createdAt string
+			orderID   sql.NullInt64
+		)
+		if err := rows.Scan(&e.ID, &createdAt, &e.Actor, &e.RequestID, &e.PackSet, &e.Size, &e.Kind, &e.Quantity,
+			&e.Balance, &orderID, &e.Note); err != nil {
+			return nil, fmt.Errorf("scan ledger entry: %w", err)
+		}
+		if e.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, fmt.Errorf("parse ledger entry time: %w", err)
+		}
+		if orderID.Valid {
+			id := orderID.Int64
+			e.OrderID = &id
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate ledger entries: %w", err)
+	}
+	return out, nil
+}
+
+// moveStock changes the packs on hand of a size and writes the ledger entry for it. It must run in
+// a transaction, so a failed move of an order leaves no other move behind.
+func moveStock(ctx context.Context, tx *sql.Tx, set string, size int, kind string, quantity int, note string, orderID *int64) (*models.LedgerEntry, error) {
+	tenant := requestinfo.Tenant(ctx)
+	ts := storedTime(now())
+
+	var (
+		balance int
+		err     error
+	)
+	if quantity >= 0 {
+		err = tx.QueryRowContext(ctx, db.Rebind(`
+		INSERT INTO inventory_stock(tenant, pack_set, size, on_hand, updated_at) VALUES(?, ?, ?, ?, ?)
+		ON CONFLICT (tenant, pack_set, size)
+		DO UPDATE SET on_hand = inventory_stock.on_hand + excluded.on_hand, updated_at = excluded.updated_at
+		RETURNING on_hand`), tenant, set, size, quantity, formatTime(ts)).Scan(&balance)
+	} else {
+		// The row is only updated while enough packs are on hand; a missing row means none are.
+		err = tx.QueryRowContext(ctx, db.Rebind(`
+		UPDATE inventory_stock SET on_hand = on_hand - ?, updated_at = ?
+		WHERE tenant = ? AND pack_set = ? AND size = ? AND on_hand >= ?
+		RETURNING on_hand`), -quantity, formatTime(ts), tenant, set, size, -quantity).Scan(&balance)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w of size %d", ErrInsufficientStock, size)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update stock level: %w", err)
+	}
+
+	e := models.LedgerEntry{
+		CreatedAt: ts,
+		Actor:     requestinfo.Actor(ctx),
+		RequestID: requestinfo.RequestID(ctx),
+		PackSet:   set,
+		Size:      size,
+		Kind:      kind,
+		Quantity:  quantity,
+		Balance:   balance,
+		OrderID:   orderID,
+		Note:      note,
+	}
+	err = tx.QueryRowContext(ctx, db.Rebind(`
+	INSERT INTO inventory_ledger(tenant, created_at, actor, request_id, pack_set, size, kind, quantity, balance, order_id, note)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		tenant, formatTime(e.CreatedAt), e.Actor, e.RequestID, set, size, kind, quantity, balance, orderID, note).Scan(&e.ID)
+	if err != nil {
+		return nil, fmt.Errorf("insert ledger entry: %w", err)
+	}
+	return &e, nil
+}
+
+// moveOrderStock records the stock change of an order moving from one status to another, if any.
+// Confirming deducts the packs of the sizes whose stock is tracked, i.e. that were ever stocked;
+// other sizes are skipped. Cancelling puts back exactly what the ledger shows was deducted for
+// the order.
+func moveOrderStock(ctx context.Context, tx *sql.Tx, o *models.Order, from, to string) error {
+	switch orderStockKind(from, to) {
+	case models.LedgerKindDeduction:
+		for _, p := range o.Packs {
+			var onHand int
+			err := tx.QueryRowContext(ctx, db.Rebind(`
+			SELECT on_hand FROM inventory_stock WHERE tenant = ? AND pack_set = ? AND size = ?`),
+				requestinfo.Tenant(ctx), o.PackSet, p.Size).Scan(&onHand)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("get stock level: %w", err)
+			}
+			if _, err := moveStock(ctx, tx, o.PackSet, p.Size, models.LedgerKindDeduction, -p.Count, "", &o.ID); err != nil {
+				return err
+			}
+		}
+	case models.LedgerKindRelease:
+		deducted, err := orderDeductions(ctx, tx, o)
+		if err != nil {
+			return err
+		}
+		for _, p := range deducted {
+			if _, err := moveStock(ctx, tx, o.PackSet, p.Size, models.LedgerKindRelease, p.Count, "", &o.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// orderDeductions returns the packs per size the ledger shows as taken out of stock for o and not
+// released yet, ordered by size.
+func orderDeductions(ctx context.Context, tx *sql.Tx, o *models.Order) ([]models.PackAllocation, error) {
+	rows, err := tx.QueryContext(ctx, db.Rebind(`
+	SELECT size, SUM(quantity) FROM inventory_ledger WHERE tenant = ? AND pack_set = ? AND order_id = ?
+	GROUP BY size ORDER BY size ASC`), requestinfo.Tenant(ctx), o.PackSet, o.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list order deductions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []models.PackAllocation
+	for rows.Next() {
+		var size, net int
+		if err := rows.Scan(&size, &net); err != nil {
+			return nil, fmt.Errorf("scan order deduction: %w", err)
+		}
+		if net < 0 {
+			out = append(out, models.PackAllocation{Size: size, Count: -net})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate order deductions: %w", err)
+	}
+	return out, nil
+}
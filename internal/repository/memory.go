@@ -0,0 +1,709 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+// memoryStore holds all data of the in-memory backend (DB_DRIVER=memory). A single mutex
+// serializes every operation, which gives them the same all-or-nothing behaviour as the
+// transactions of the SQL backend.
+type memoryStore struct {
+	mu sync.Mutex
+
+	// packs holds live and soft-deleted pack sizes in ID order.
+	packs      []memoryPackSize
+	lastPackID int64
+
+	audit       []memoryAuditEntry
+	lastAuditID int64
+
+	versions map[packSetKey][]models.PackSetVersion
+
+	products      map[productKey]models.Product
+	lastProductID int64
+
+	calculations      []memoryCalculation
+	lastCalculationID int64
+
+	orders      []memoryOrder
+	lastOrderID int64
+
+	idempotencyKeys map[idempotencyKey]models.IdempotencyRecord
+
+	stock        map[stockKey]models.StockLevel
+	ledger       []memoryLedgerEntry
+	lastLedgerID int64
+}
+
+// packSetKey identifies a pack set; the same name may be used by several tenants.
+type packSetKey struct {
+	tenant, set string
+}
+
+// keyOf returns the key of set for the tenant in ctx.
+func keyOf(ctx context.Context, set string) packSetKey {
+	return packSetKey{tenant: requestinfo.Tenant(ctx), set: set}
+}
+
+type productKey struct {
+	tenant, sku string
+}
+
+type idempotencyKey struct {
+	tenant, key string
+}
+
+type stockKey struct {
+	packSetKey
+	size int
+}
+
+type memoryPackSize struct {
+	packSetKey
+	models.PackSize
+}
+
+type memoryAuditEntry struct {
+	tenant string
+	models.AuditEntry
+}
+
+type memoryCalculation struct {
+	tenant string
+	models.Calculation
+}
+
+type memoryOrder struct {
+	tenant string
+	models.Order
+}
+
+type memoryLedgerEntry struct {
+	tenant string
+	models.LedgerEntry
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		versions:        make(map[packSetKey][]models.PackSetVersion),
+		products:        make(map[productKey]models.Product),
+		idempotencyKeys: make(map[idempotencyKey]models.IdempotencyRecord),
+		stock:           make(map[stockKey]models.StockLevel),
+	}
+}
+
+// UseMemoryBackend switches all repositories to a new, empty in-memory store. Nothing is written
+// to disk and all data is lost when the process exits.
+func UseMemoryBackend() {
+	s := newMemoryStore()
+	SetPackSizesRepository(&memoryPackSizesRepository{store: s})
+	SetAuditRepository(&memoryAuditRepository{store: s})
+	SetPackSetVersionsRepository(&memoryPackSetVersionsRepository{store: s})
+	SetProductsRepository(&memoryProductsRepository{store: s})
+	SetCalculationsRepository(&memoryCalculationsRepository{store: s})
+	SetOrdersRepository(&memoryOrdersRepository{store: s})
+	SetIdempotencyRepository(&memoryIdempotencyRepository{store: s})
+	SetInventoryRepository(&memoryInventoryRepository{store: s})
+}
+
+// storedTime rounds t the way the SQL backend does when it stores a timestamp as text.
+func storedTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// recordAudit is the in-memory counterpart of recordAudit; s.mu must be held.
+func (s *memoryStore) recordAudit(ctx context.Context, action, set string, packSizeID *int64, before, after any) error {
+	beforeJSON, err := nullableJSON(before)
+	if err != nil {
+		return fmt.Errorf("encode audit before value: %w", err)
+	}
+	afterJSON, err := nullableJSON(after)
+	if err != nil {
+		return fmt.Errorf("encode audit after value: %w", err)
+	}
+
+	var id *int64
+	if packSizeID != nil {
+		v := *packSizeID
+		id = &v
+	}
+	s.lastAuditID++
+	s.audit = append(s.audit, memoryAuditEntry{tenant: requestinfo.Tenant(ctx), AuditEntry: models.AuditEntry{
+		ID:         s.lastAuditID,
+		CreatedAt:  storedTime(now()),
+		Actor:      requestinfo.Actor(ctx),
+		RequestID:  requestinfo.RequestID(ctx),
+		Action:     action,
+		PackSet:    set,
+		PackSizeID: id,
+		Before:     rawJSONOrNull(beforeJSON),
+		After:      rawJSONOrNull(afterJSON),
+	}})
+	return nil
+}
+
+// recordPackSetVersion is the in-memory counterpart of recordPackSetVersion; s.mu must be held.
+func (s *memoryStore) recordPackSetVersion(k packSetKey, before []models.PackSize) {
+	history := s.versions[k]
+	if len(history) == 0 && len(before) > 0 {
+		history = append(history, models.PackSetVersion{
+			PackSet: k.set, Version: 1, CreatedAt: time.Time{}, Packs: before,
+		})
+	}
+	after := s.livePackSizes(k)
+	if after == nil {
+		after = []models.PackSize{}
+	}
+	history = append(history, models.PackSetVersion{
+		PackSet: k.set, Version: int64(len(history)) + 1, CreatedAt: storedTime(now()), Packs: after,
+	})
+	s.versions[k] = history
+}
+
+type memoryAuditRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryAuditRepository) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultAuditLimit
+	}
+	from, to := storedTime(filter.From), storedTime(filter.To)
+	tenant := requestinfo.Tenant(ctx)
+
+	out := []models.AuditEntry{}
+	for i := len(s.audit) - 1; i >= 0 && len(out) < limit; i-- {
+		if s.audit[i].tenant != tenant {
+			continue
+		}
+		e := s.audit[i].AuditEntry
+		switch {
+		case filter.PackSet != "" && e.PackSet != filter.PackSet,
+			filter.Action != "" && e.Action != filter.Action,
+			filter.Actor != "" && e.Actor != filter.Actor,
+			filter.RequestID != "" && e.RequestID != filter.RequestID,
+			!filter.From.IsZero() && e.CreatedAt.Before(from),
+			!filter.To.IsZero() && !e.CreatedAt.Before(to):
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+type memoryPackSetVersionsRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryPackSetVersionsRepository) List(ctx context.Context, set string) ([]models.PackSetVersion, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.versions[keyOf(ctx, set)]
+	out := make([]models.PackSetVersion, len(history))
+	copy(out, history)
+	return out, nil
+}
+
+func (r *memoryPackSetVersionsRepository) Get(ctx context.Context, set string, version int64) (*models.PackSetVersion, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.versions[keyOf(ctx, set)]
+	if version < 1 || version > int64(len(history)) {
+		return nil, fmt.Errorf("%w", ErrNotFound)
+	}
+	v := history[version-1]
+	return &v, nil
+}
+
+func (r *memoryPackSetVersionsRepository) AsOf(ctx context.Context, set string, t time.Time) (*models.PackSetVersion, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	at := storedTime(t)
+	history := s.versions[keyOf(ctx, set)]
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].CreatedAt.After(at) {
+			v := history[i]
+			return &v, nil
+		}
+	}
+	return nil, fmt.Errorf("%w", ErrNotFound)
+}
+
+type memoryProductsRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryProductsRepository) List(ctx context.Context) ([]models.Product, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenant := requestinfo.Tenant(ctx)
+	var out []models.Product
+	for k, p := range s.products {
+		if k.tenant == tenant {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SKU < out[j].SKU })
+	return out, nil
+}
+
+func (r *memoryProductsRepository) Get(ctx context.Context, sku string) (*models.Product, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[productKey{requestinfo.Tenant(ctx), sku}]
+	if !ok {
+		return nil, fmt.Errorf("%w", ErrNotFound)
+	}
+	return &p, nil
+}
+
+func (r *memoryProductsRepository) Create(ctx context.Context, p models.Product) (*models.Product, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := productKey{requestinfo.Tenant(ctx), p.SKU}
+	if _, ok := s.products[k]; ok {
+		return nil, fmt.Errorf("%w", ErrConflict)
+	}
+	s.lastProductID++
+	p.ID = s.lastProductID
+	s.products[k] = p
+	return &p, nil
+}
+
+func (r *memoryProductsRepository) Update(ctx context.Context, sku string, p models.Product) (*models.Product, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := productKey{requestinfo.Tenant(ctx), sku}
+	current, ok := s.products[k]
+	if !ok {
+		return nil, fmt.Errorf("%w", ErrNotFound)
+	}
+	current.Name = p.Name
+	current.Description = p.Description
+	current.PackSet = p.PackSet
+	s.products[k] = current
+	return &current, nil
+}
+
+func (r *memoryProductsRepository) Delete(ctx context.Context, sku string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := productKey{requestinfo.Tenant(ctx), sku}
+	if _, ok := s.products[k]; !ok {
+		return fmt.Errorf("%w", ErrNotFound)
+	}
+	delete(s.products, k)
+	return nil
+}
+
+type memoryCalculationsRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryCalculationsRepository) Record(ctx context.Context, c models.Calculation) (*models.Calculation, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recordCalculation(ctx, c), nil
+}
+
+// recordCalculation is the in-memory counterpart of recordCalculation; s.mu must be held.
+func (s *memoryStore) recordCalculation(ctx context.Context, c models.Calculation) *models.Calculation {
+	s.lastCalculationID++
+	c.ID = s.lastCalculationID
+	c.CreatedAt = storedTime(now())
+	c.RequestID = requestinfo.RequestID(ctx)
+	c.Request = requestOrNull(c.Request)
+	c.Packs = slices.Clone(c.Packs)
+	c.PackSizes = slices.Clone(c.PackSizes)
+	s.calculations = append(s.calculations, memoryCalculation{tenant: requestinfo.Tenant(ctx), Calculation: c})
+	return &c
+}
+
+func (r *memoryCalculationsRepository) List(ctx context.Context, filter models.CalculationFilter) ([]models.Calculation, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultCalculationsLimit
+	}
+	match := calculationMatcher(ctx, filter)
+
+	out := []models.Calculation{}
+	for i := len(s.calculations) - 1; i >= 0 && len(out) < limit; i-- {
+		if match(s.calculations[i]) {
+			out = append(out, s.calculations[i].Calculation)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryCalculationsRepository) Each(ctx context.Context, filter models.CalculationFilter, fn func(models.Calculation) error) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	match := calculationMatcher(ctx, filter)
+	for _, c := range s.calculations {
+		if !match(c) {
+			continue
+		}
+		if err := fn(c.Calculation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// calculationMatcher reports whether a calculation belongs to the tenant in ctx and matches filter.
+func calculationMatcher(ctx context.Context, filter models.CalculationFilter) func(memoryCalculation) bool {
+	from, to := storedTime(filter.From), storedTime(filter.To)
+	tenant := requestinfo.Tenant(ctx)
+	return func(c memoryCalculation) bool {
+		switch {
+		case c.tenant != tenant,
+			filter.Reference != "" && c.Reference != filter.Reference,
+			!filter.From.IsZero() && c.CreatedAt.Before(from),
+			!filter.To.IsZero() && !c.CreatedAt.Before(to):
+			return false
+		}
+		return true
+	}
+}
+
+func (r *memoryCalculationsRepository) Get(ctx context.Context, id int64) (*models.Calculation, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenant := requestinfo.Tenant(ctx)
+	for _, c := range s.calculations {
+		if c.tenant == tenant && c.ID == id {
+			calc := c.Calculation
+			return &calc, nil
+		}
+	}
+	return nil, fmt.Errorf("%w", ErrNotFound)
+}
+
+type memoryOrdersRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryOrdersRepository) List(ctx context.Context, filter models.OrderFilter) ([]models.Order, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultOrdersLimit
+	}
+	tenant := requestinfo.Tenant(ctx)
+
+	out := []models.Order{}
+	for i := len(s.orders) - 1; i >= 0 && len(out) < limit; i-- {
+		if s.orders[i].tenant != tenant {
+			continue
+		}
+		o := s.orders[i].Order
+		switch {
+		case filter.Status != "" && o.Status != filter.Status,
+			filter.Reference != "" && o.Reference != filter.Reference:
+			continue
+		}
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+func (r *memoryOrdersRepository) Get(ctx context.Context, id int64) (*models.Order, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.orderIndex(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	o := s.orders[i].Order
+	return &o, nil
+}
+
+func (r *memoryOrdersRepository) Create(ctx context.Context, o models.Order, calc *models.Calculation) (*models.Order, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if calc != nil {
+		o.CalculationID = s.recordCalculation(ctx, *calc).ID
+	}
+	s.lastOrderID++
+	o.ID = s.lastOrderID
+	o.Status = models.OrderStatusDraft
+	o.CreatedAt = storedTime(now())
+	o.UpdatedAt = o.CreatedAt
+	o.Packs = slices.Clone(o.Packs)
+	s.orders = append(s.orders, memoryOrder{tenant: requestinfo.Tenant(ctx), Order: o})
+	return &o, nil
+}
+
+func (r *memoryOrdersRepository) Transition(ctx context.Context, id int64, status string) (*models.Order, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.orderIndex(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	o := &s.orders[i].Order
+	if err := checkOrderTransition(o.Status, status); err != nil {
+		return nil, err
+	}
+	if err := s.moveOrderStock(ctx, o, o.Status, status); err != nil {
+		return nil, err
+	}
+	o.Status = status
+	o.UpdatedAt = storedTime(now())
+	out := *o
+	return &out, nil
+}
+
+// orderIndex returns the index of the order with id of the tenant in ctx; s.mu must be held.
+func (s *memoryStore) orderIndex(ctx context.Context, id int64) (int, error) {
+	tenant := requestinfo.Tenant(ctx)
+	for i, o := range s.orders {
+		if o.tenant == tenant && o.ID == id {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w", ErrNotFound)
+}
+
+type memoryIdempotencyRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryIdempotencyRepository) Begin(ctx context.Context, key, fingerprint string, expiredBefore, staleBefore time.Time) (*models.IdempotencyRecord, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := storedTime(expiredBefore)
+	for k, rec := range s.idempotencyKeys {
+		if rec.CreatedAt.Before(expired) {
+			delete(s.idempotencyKeys, k)
+		}
+	}
+
+	k := idempotencyKey{requestinfo.Tenant(ctx), key}
+	if rec, ok := s.idempotencyKeys[k]; ok {
+		rec.Body = slices.Clone(rec.Body)
+		stored, err := checkIdempotencyRecord(&rec, fingerprint, storedTime(staleBefore))
+		if !errors.Is(err, errStaleClaim) {
+			return stored, err
+		}
+	}
+	s.idempotencyKeys[k] = models.IdempotencyRecord{Key: key, Fingerprint: fingerprint, CreatedAt: storedTime(now())}
+	return nil, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, key string, status int, contentType, etag string, body []byte) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{requestinfo.Tenant(ctx), key}
+	rec, ok := s.idempotencyKeys[k]
+	if !ok {
+		return nil
+	}
+	rec.Status, rec.ContentType, rec.ETag, rec.Body = status, contentType, etag, slices.Clone(body)
+	s.idempotencyKeys[k] = rec
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, key string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.idempotencyKeys, idempotencyKey{requestinfo.Tenant(ctx), key})
+	return nil
+}
+
+type memoryInventoryRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryInventoryRepository) Stock(ctx context.Context, set string) ([]models.StockLevel, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	out := []models.StockLevel{}
+	for sk, l := range s.stock {
+		if sk.packSetKey == k {
+			t := *l.UpdatedAt
+			l.UpdatedAt = &t
+			out = append(out, l)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Size < out[j].Size })
+	return out, nil
+}
+
+func (r *memoryInventoryRepository) Move(ctx context.Context, set string, size int, kind string, quantity int, note string) (*models.LedgerEntry, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.moveStock(ctx, set, size, kind, quantity, note, nil)
+}
+
+func (r *memoryInventoryRepository) Ledger(ctx context.Context, filter models.LedgerFilter) ([]models.LedgerEntry, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLedgerLimit
+	}
+	from, to := storedTime(filter.From), storedTime(filter.To)
+	tenant := requestinfo.Tenant(ctx)
+
+	out := []models.LedgerEntry{}
+	for i := len(s.ledger) - 1; i >= 0 && len(out) < limit; i-- {
+		if s.ledger[i].tenant != tenant {
+			continue
+		}
+		e := s.ledger[i].LedgerEntry
+		switch {
+		case filter.PackSet != "" && e.PackSet != filter.PackSet,
+			filter.Size != 0 && e.Size != filter.Size,
+			filter.Kind != "" && e.Kind != filter.Kind,
+			filter.OrderID != 0 && (e.OrderID == nil || *e.OrderID != filter.OrderID),
+			!filter.From.IsZero() && e.CreatedAt.Before(from),
+			!filter.To.IsZero() && !e.CreatedAt.Before(to):
+			continue
+		}
+		if e.OrderID != nil {
+			id := *e.OrderID
+			e.OrderID = &id
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// moveStock is the in-memory counterpart of moveStock; s.mu must be held.
+func (s *memoryStore) moveStock(ctx context.Context, set string, size int, kind string, quantity int, note string, orderID *int64) (*models.LedgerEntry, error) {
+	k := stockKey{keyOf(ctx, set), size}
+	l := s.stock[k]
+	if l.OnHand+quantity < 0 {
+		return nil, fmt.Errorf("%w of size %d", ErrInsufficientStock, size)
+	}
+	ts := storedTime(now())
+	s.stock[k] = models.StockLevel{PackSet: set, Size: size, OnHand: l.OnHand + quantity, UpdatedAt: &ts}
+
+	if orderID != nil {
+		id := *orderID
+		orderID = &id
+	}
+	s.lastLedgerID++
+	e := models.LedgerEntry{
+		ID:        s.lastLedgerID,
+		CreatedAt: ts,
+		Actor:     requestinfo.Actor(ctx),
+		RequestID: requestinfo.RequestID(ctx),
+		PackSet:   set,
+		Size:      size,
+		Kind:      kind,
+		Quantity:  quantity,
+		Balance:   l.OnHand + quantity,
+		OrderID:   orderID,
+		Note:      note,
+	}
+	s.ledger = append(s.ledger, memoryLedgerEntry{tenant: requestinfo.Tenant(ctx), LedgerEntry: e})
+	return &e, nil
+}
+
+// moveOrderStock is the in-memory counterpart of moveOrderStock; s.mu must be held. Stock is
+// checked for every pack before any of them is deducted, so a failed move changes nothing.
+func (s *memoryStore) moveOrderStock(ctx context.Context, o *models.Order, from, to string) error {
+	switch orderStockKind(from, to) {
+	case models.LedgerKindDeduction:
+		var tracked []models.PackAllocation
+		for _, p := range o.Packs {
+			l, ok := s.stock[stockKey{keyOf(ctx, o.PackSet), p.Size}]
+			if !ok {
+				continue
+			}
+			if l.OnHand < p.Count {
+				return fmt.Errorf("%w of size %d", ErrInsufficientStock, p.Size)
+			}
+			tracked = append(tracked, p)
+		}
+		for _, p := range tracked {
+			if _, err := s.moveStock(ctx, o.PackSet, p.Size, models.LedgerKindDeduction, -p.Count, "", &o.ID); err != nil {
+				return err
+			}
+		}
+	case models.LedgerKindRelease:
+		tenant := requestinfo.Tenant(ctx)
+		net := map[int]int{}
+		for _, e := range s.ledger {
+			if e.tenant == tenant && e.PackSet == o.PackSet && e.OrderID != nil && *e.OrderID == o.ID {
+				net[e.Size] += e.Quantity
+			}
+		}
+		sizes := make([]int, 0, len(net))
+		for size, n := range net {
+			if n < 0 {
+				sizes = append(sizes, size)
+			}
+		}
+		slices.Sort(sizes)
+		for _, size := range sizes {
+			if _, err := s.moveStock(ctx, o.PackSet, size, models.LedgerKindRelease, -net[size], "", &o.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
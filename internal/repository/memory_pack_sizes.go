@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+// memoryPackSizesRepository is the in-memory PackSizesRepository. It mirrors the SQL
+// implementation, including ID sequencing and the audit and history it records.
+type memoryPackSizesRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryPackSizesRepository) ListSets(ctx context.Context) ([]string, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenant := requestinfo.Tenant(ctx)
+	seen := map[string]bool{DefaultPackSet: true}
+	var names []string
+	for _, p := range s.packs {
+		if p.tenant == tenant && p.DeletedAt == nil && !seen[p.set] {
+			seen[p.set] = true
+			names = append(names, p.set)
+		}
+	}
+	sort.Strings(names)
+
+	// The default set always exists, even when it has no pack sizes.
+	return append([]string{DefaultPackSet}, names...), nil
+}
+
+func (r *memoryPackSizesRepository) ResetToDefault(ctx context.Context, set string) ([]int, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	before := s.livePackSizes(k)
+	defaults := TenantDefaultPackSizes(k.tenant)
+	if err := packSizePolicy.CheckSizes(defaults, len(defaults)); err != nil {
+		return nil, err
+	}
+
+	// A reset starts the set from scratch, so soft-deleted rows are purged as well.
+	kept := s.packs[:0]
+	for _, p := range s.packs {
+		if p.packSetKey != k {
+			kept = append(kept, p)
+		}
+	}
+	s.packs = kept
+
+	// Like the SQL backend, IDs start from 1 again once no pack set holds rows.
+	if len(s.packs) == 0 {
+		s.lastPackID = 0
+	}
+
+	for _, size := range defaults {
+		s.insertPackSize(k, size, models.PackMetadata{}, models.EffectivePeriod{})
+	}
+
+	after := s.livePackSizes(k)
+	if err := s.recordAudit(ctx, models.AuditActionReset, set, nil, nonNilPackSizes(before), after); err != nil {
+		return nil, err
+	}
+	s.recordPackSetVersion(k, before)
+
+	return defaults, nil
+}
+
+func (r *memoryPackSizesRepository) Replace(ctx context.Context, set string, sizes []int) (*models.PackSizesDiff, error) {
+	return r.apply(ctx, set, sizes, models.AuditActionReplace, true, false)
+}
+
+func (r *memoryPackSizesRepository) Import(ctx context.Context, set string, sizes []int, mode string, dryRun bool) (*models.PackSizesDiff, error) {
+	switch mode {
+	case models.ImportModeMerge:
+		return r.apply(ctx, set, sizes, models.AuditActionImport, false, dryRun)
+	case models.ImportModeReplace:
+		return r.apply(ctx, set, sizes, models.AuditActionImport, true, dryRun)
+	default:
+		return nil, fmt.Errorf("unknown import mode %q", mode)
+	}
+}
+
+// apply is the in-memory counterpart of sqlPackSizesRepository.apply.
+func (r *memoryPackSizesRepository) apply(ctx context.Context, set string, sizes []int, action string, removeMissing, dryRun bool) (*models.PackSizesDiff, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	before := s.livePackSizes(k)
+
+	wanted := make(map[int]bool, len(sizes))
+	for _, size := range sizes {
+		wanted[size] = true
+	}
+
+	diff := &models.PackSizesDiff{
+		Added:   []models.PackSize{},
+		Removed: []models.PackSize{},
+		Kept:    []models.PackSize{},
+	}
+	existing := make(map[int]bool, len(before))
+	var removed []int64
+	for _, p := range before {
+		existing[p.Size] = true
+		if wanted[p.Size] || !removeMissing {
+			diff.Kept = append(diff.Kept, p)
+			continue
+		}
+		removed = append(removed, p.ID)
+		diff.Removed = append(diff.Removed, p)
+	}
+
+	added := make([]int, 0, len(sizes))
+	for _, size := range sizes {
+		if !existing[size] {
+			existing[size] = true
+			added = append(added, size)
+		}
+	}
+	sort.Ints(added)
+	if err := packSizePolicy.CheckSizes(added, len(before)-len(removed)+len(added)); err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		for _, size := range added {
+			diff.Added = append(diff.Added, models.PackSize{Size: size})
+		}
+		return diff, nil
+	}
+
+	deletedAt := storedTime(now())
+	for _, id := range removed {
+		p := s.findPackSize(k, id)
+		p.DeletedAt = &deletedAt
+		p.Version++
+	}
+	for _, size := range added {
+		diff.Added = append(diff.Added, s.insertPackSize(k, size, models.PackMetadata{}, models.EffectivePeriod{}))
+	}
+
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 {
+		return diff, nil
+	}
+
+	after := s.livePackSizes(k)
+	if err := s.recordAudit(ctx, action, set, nil, nonNilPackSizes(before), after); err != nil {
+		return nil, err
+	}
+	s.recordPackSetVersion(k, before)
+	return diff, nil
+}
+
+func (r *memoryPackSizesRepository) List(ctx context.Context, set string) ([]models.PackSize, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	return s.livePackSizes(k), nil
+}
+
+func (r *memoryPackSizesRepository) ListAll(ctx context.Context, set string) ([]models.PackSize, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	var out []models.PackSize
+	for _, p := range s.packs {
+		if p.packSetKey == k {
+			out = append(out, p.copy())
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Size < out[j].Size })
+	return out, nil
+}
+
+func (r *memoryPackSizesRepository) Get(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	p := s.findPackSize(k, id)
+	if p == nil || p.DeletedAt != nil {
+		return nil, fmt.Errorf("%w", ErrNotFound)
+	}
+	out := p.PackSize
+	return &out, nil
+}
+
+func (r *memoryPackSizesRepository) Create(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	before := s.livePackSizes(k)
+	if err := packSizePolicy.CheckSize(size, len(before)+1); err != nil {
+		return nil, err
+	}
+	if s.findLiveSize(k, size) != nil {
+		return nil, fmt.Errorf("%w", ErrConflict)
+	}
+
+	created := s.insertPackSize(k, size, meta, period)
+	id := created.ID
+	if err := s.recordAudit(ctx, models.AuditActionCreate, set, &id, nil, &created); err != nil {
+		return nil, err
+	}
+	s.recordPackSetVersion(k, before)
+	return &created, nil
+}
+
+func (r *memoryPackSizesRepository) Update(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	p := s.findPackSize(k, id)
+	if p == nil || p.DeletedAt != nil {
+		return nil, fmt.Errorf("%w", ErrNotFound)
+	}
+	if ifVersion != 0 && ifVersion != p.Version {
+		return nil, fmt.Errorf("%w", ErrVersionMismatch)
+	}
+	if size != p.Size {
+		if err := packSizePolicy.CheckSize(size, 0); err != nil {
+			return nil, err
+		}
+	}
+	if other := s.findLiveSize(k, size); other != nil && other.ID != id {
+		return nil, fmt.Errorf("%w", ErrConflict)
+	}
+	before := s.livePackSizes(k)
+	previous := p.PackSize
+
+	p.Size = size
+	p.PackMetadata = copyPackMetadata(meta)
+	p.EffectivePeriod = storedPeriod(period)
+	p.Version++
+	updated := p.PackSize
+
+	if err := s.recordAudit(ctx, models.AuditActionUpdate, set, &id, previous, &updated); err != nil {
+		return nil, err
+	}
+	s.recordPackSetVersion(k, before)
+	return &updated, nil
+}
+
+func (r *memoryPackSizesRepository) Delete(ctx context.Context, set string, id int64, ifVersion int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	p := s.findPackSize(k, id)
+	if p == nil || p.DeletedAt != nil {
+		return fmt.Errorf("%w", ErrNotFound)
+	}
+	if ifVersion != 0 && ifVersion != p.Version {
+		return fmt.Errorf("%w", ErrVersionMismatch)
+	}
+	before := s.livePackSizes(k)
+	previous := p.PackSize
+
+	deletedAt := storedTime(now())
+	p.DeletedAt = &deletedAt
+	p.Version++
+
+	if err := s.recordAudit(ctx, models.AuditActionDelete, set, &id, previous, nil); err != nil {
+		return err
+	}
+	s.recordPackSetVersion(k, before)
+	return nil
+}
+
+func (r *memoryPackSizesRepository) Restore(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := keyOf(ctx, set)
+	p := s.findPackSize(k, id)
+	if p == nil {
+		return nil, fmt.Errorf("%w", ErrNotFound)
+	}
+	if p.DeletedAt == nil {
+		out := p.PackSize
+		return &out, nil
+	}
+	before := s.livePackSizes(k)
+	if err := packSizePolicy.CheckSize(p.Size, len(before)+1); err != nil {
+		return nil, err
+	}
+	if s.findLiveSize(k, p.Size) != nil {
+		return nil, fmt.Errorf("%w", ErrConflict)
+	}
+
+	p.DeletedAt = nil
+	p.Version++
+	restored := p.PackSize
+
+	if err := s.recordAudit(ctx, models.AuditActionRestore, set, &id, nil, restored); err != nil {
+		return nil, err
+	}
+	s.recordPackSetVersion(k, before)
+	return &restored, nil
+}
+
+// insertPackSize adds a live pack size with the next ID; s.mu must be held.
+func (s *memoryStore) insertPackSize(k packSetKey, size int, meta models.PackMetadata, period models.EffectivePeriod) models.PackSize {
+	s.lastPackID++
+	p := models.PackSize{ID: s.lastPackID, Size: size, PackMetadata: copyPackMetadata(meta), EffectivePeriod: storedPeriod(period), Version: 1}
+	s.packs = append(s.packs, memoryPackSize{packSetKey: k, PackSize: p})
+	return p
+}
+
+// livePackSizes returns the live pack sizes of a pack set ordered by size (nil if there are none),
+// like listPackSizes; s.mu must be held.
+func (s *memoryStore) livePackSizes(k packSetKey) []models.PackSize {
+	var out []models.PackSize
+	for _, p := range s.packs {
+		if p.packSetKey == k && p.DeletedAt == nil {
+			out = append(out, p.PackSize)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Size < out[j].Size })
+	return out
+}
+
+// findPackSize returns the live or soft-deleted pack size with the given ID; s.mu must be held.
+func (s *memoryStore) findPackSize(k packSetKey, id int64) *memoryPackSize {
+	for i := range s.packs {
+		if s.packs[i].packSetKey == k && s.packs[i].ID == id {
+			return &s.packs[i]
+		}
+	}
+	return nil
+}
+
+// findLiveSize returns the live pack size of a pack set with the given size; s.mu must be held.
+func (s *memoryStore) findLiveSize(k packSetKey, size int) *memoryPackSize {
+	for i := range s.packs {
+		if s.packs[i].packSetKey == k && s.packs[i].DeletedAt == nil && s.packs[i].Size == size {
+			return &s.packs[i]
+		}
+	}
+	return nil
+}
+
+// copyPackMetadata returns meta with its own copy of Dimensions, stored the way the SQL backend
+// reads it back: all-zero dimensions are not set.
+func copyPackMetadata(meta models.PackMetadata) models.PackMetadata {
+	if meta.Dimensions != nil {
+		d := *meta.Dimensions
+		meta.Dimensions = nil
+		if d != (models.PackDimensions{}) {
+			meta.Dimensions = &d
+		}
+	}
+	return meta
+}
+
+// copy returns the pack size with its own copy of DeletedAt.
+func (p memoryPackSize) copy() models.PackSize {
+	out := p.PackSize
+	if p.DeletedAt != nil {
+		t := *p.DeletedAt
+		out.DeletedAt = &t
+	}
+	return out
+}
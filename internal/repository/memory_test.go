@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func TestMemoryBackendConcurrentMutations(t *testing.T) {
+	repo := openMemoryBackend(t).packSizes
+	ctx := context.Background()
+
+	const workers = 50
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ids = make(map[int64]bool)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(size int) {
+			defer wg.Done()
+			// Every worker races for the same size as well as creating its own.
+			_, _ = repo.Create(ctx, DefaultPackSet, 1, models.PackMetadata{}, models.EffectivePeriod{})
+			p, err := repo.Create(ctx, DefaultPackSet, size, models.PackMetadata{}, models.EffectivePeriod{})
+			if err != nil {
+				t.Errorf("create %d: %v", size, err)
+				return
+			}
+			mu.Lock()
+			ids[p.ID] = true
+			mu.Unlock()
+			_, _ = repo.List(ctx, DefaultPackSet)
+		}(i + 2)
+	}
+	wg.Wait()
+
+	if len(ids) != workers {
+		t.Fatalf("expected %d distinct IDs, got %d", workers, len(ids))
+	}
+	packs, err := repo.List(ctx, DefaultPackSet)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(packs) != workers+1 {
+		t.Fatalf("expected %d pack sizes, got %d", workers+1, len(packs))
+	}
+}
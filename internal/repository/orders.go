@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+// DefaultOrdersLimit caps the number of orders returned when the filter sets no limit.
+const DefaultOrdersLimit = 100
+
+// ErrInvalidTransition is returned when the order lifecycle does not allow a status change.
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+// orderTransitions lists the statuses each status may move to.
+var orderTransitions = map[string][]string{
+	models.OrderStatusDraft:     {models.OrderStatusConfirmed, models.OrderStatusCancelled},
+	models.OrderStatusConfirmed: {models.OrderStatusPicked, models.OrderStatusCancelled},
+	models.OrderStatusPicked:    {models.OrderStatusShipped, models.OrderStatusCancelled},
+	models.OrderStatusShipped:   nil,
+	models.OrderStatusCancelled: nil,
+}
+
+// IsOrderStatus reports whether status is a known order status.
+func IsOrderStatus(status string) bool {
+	_, ok := orderTransitions[status]
+	return ok
+}
+
+// CanTransitionOrder reports whether an order may move from one status to another.
+func CanTransitionOrder(from, to string) bool {
+	return slices.Contains(orderTransitions[from], to)
+}
+
+func checkOrderTransition(from, to string) error {
+	if !CanTransitionOrder(from, to) {
+		return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, from, to)
+	}
+	return nil
+}
+
+// OrdersRepository manages the orders of the tenant in ctx.
+type OrdersRepository interface {
+	// List returns the newest orders matching filter first.
+	List(ctx context.Context, filter models.OrderFilter) ([]models.Order, error)
+	Get(ctx context.Context, id int64) (*models.Order, error)
+	// Create stores o as a draft, filling in its ID and timestamps. A non-nil calc, the
+	// calculation the order was allocated by, is recorded in the same transaction and linked
+	// to the order, so neither is stored without the other.
+	Create(ctx context.Context, o models.Order, calc *models.Calculation) (*models.Order, error)
+	// Transition moves an order to status, or returns ErrInvalidTransition when the lifecycle
+	// does not allow it. Confirming an order deducts its packs from the inventory for the sizes
+	// whose stock is tracked, or returns ErrInsufficientStock when not enough are on hand;
+	// cancelling a confirmed order puts back what was deducted.
+	Transition(ctx context.Context, id int64, status string) (*models.Order, error)
+}
+
+type sqlOrdersRepository struct{}
+
+var ordersRepo OrdersRepository = &sqlOrdersRepository{}
+
+func Orders() OrdersRepository {
+	return ordersRepo
+}
+
+// SetOrdersRepository swaps the repository implementation (primarily for tests).
+func SetOrdersRepository(repo OrdersRepository) {
+	if repo == nil {
+		panic("OrdersRepository must not be nil")
+	}
+	ordersRepo = repo
+}
+
+const orderColumns = `id, reference, pack_set, sku, quantity, status, packs, shipped, overage, calculation_id, created_at, updated_at`
+
+func (r *sqlOrdersRepository) List(ctx context.Context, filter models.OrderFilter) ([]models.Order, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	where := []string{"tenant = ?"}
+	args := []any{requestinfo.Tenant(ctx)}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Reference != "" {
+		where = append(where, "reference = ?")
+		args = append(args, filter.Reference)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultOrdersLimit
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE ` + strings.Join(where, " AND ")
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := conn.QueryContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []models.Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate orders: %w", err)
+	}
+	return out, nil
+}
+
+func (r *sqlOrdersRepository) Get(ctx context.Context, id int64) (*models.Order, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+	return getOrder(ctx, conn, id)
+}
+
+func (r *sqlOrdersRepository) Create(ctx context.Context, o models.Order, calc *models.Calculation) (*models.Order, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	packs, err := json.Marshal(o.Packs)
+	if err != nil {
+		return nil, fmt.Errorf("encode order packs: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction at order create: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if calc != nil {
+		c, err := recordCalculation(ctx, tx, *calc)
+		if err != nil {
+			return nil, err
+		}
+		o.CalculationID = c.ID
+	}
+	o.Status = models.OrderStatusDraft
+	o.CreatedAt = storedTime(now())
+	o.UpdatedAt = o.CreatedAt
+
+	err = tx.QueryRowContext(ctx, db.Rebind(`
+	INSERT INTO orders(tenant, reference, pack_set, sku, quantity, status, packs, shipped, overage, calculation_id, created_at, updated_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		requestinfo.Tenant(ctx), o.Reference, o.PackSet, o.SKU, o.Quantity, o.Status, string(packs), o.Shipped, o.Overage,
+		o.CalculationID, formatTime(o.CreatedAt), formatTime(o.UpdatedAt)).Scan(&o.ID)
+	if err != nil {
+		return nil, fmt.Errorf("insert order: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction at order create: %w", err)
+	}
+	return &o, nil
+}
+
+func (r *sqlOrdersRepository) Transition(ctx context.Context, id int64, status string) (*models.Order, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction at order transition: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	o, err := getOrder(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOrderTransition(o.Status, status); err != nil {
+		return nil, err
+	}
+	// The status read above guards the update, so of two concurrent transitions only one applies.
+	from := o.Status
+	o.Status = status
+	o.UpdatedAt = storedTime(now())
+	res, err := tx.ExecContext(ctx, db.Rebind(`UPDATE orders SET status = ?, updated_at = ? WHERE tenant = ? AND id = ? AND status = ?`),
+		o.Status, formatTime(o.UpdatedAt), requestinfo.Tenant(ctx), id, from)
+	if err != nil {
+		return nil, fmt.Errorf("update order status: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("read affected rows: %w", err)
+	} else if n == 0 {
+		return nil, fmt.Errorf("%w from %s to %s: the order was changed concurrently", ErrInvalidTransition, from, status)
+	}
+	if err := moveOrderStock(ctx, tx, o, from, status); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing order transition: %w", err)
+	}
+	return o, nil
+}
+
+func getOrder(ctx context.Context, q querier, id int64) (*models.Order, error) {
+	row := q.QueryRowContext(ctx, db.Rebind(`SELECT `+orderColumns+` FROM orders WHERE tenant = ? AND id = ?`),
+		requestinfo.Tenant(ctx), id)
+	o, err := scanOrder(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w", ErrNotFound)
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+func scanOrder(row rowScanner) (*models.Order, error) {
+	var (
+		o                    models.Order
+		packs                string
+		createdAt, updatedAt string
+	)
+	err := row.Scan(&o.ID, &o.Reference, &o.PackSet, &o.SKU, &o.Quantity, &o.Status, &packs, &o.Shipped, &o.Overage,
+		&o.CalculationID, &createdAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan order: %w", err)
+	}
+	if o.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, fmt.Errorf("parse order time: %w", err)
+	}
+	if o.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, fmt.Errorf("parse order time: %w", err)
+	}
+	if err := json.Unmarshal([]byte(packs), &o.Packs); err != nil {
+		return nil, fmt.Errorf("decode order packs: %w", err)
+	}
+	return &o, nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+// PackSetVersionsRepository reads the immutable history of the pack sets of the tenant in ctx.
+// A new version is recorded by PackSizesRepository every time a pack set changes.
+type PackSetVersionsRepository interface {
+	List(ctx context.Context, set string) ([]models.PackSetVersion, error)
+	Get(ctx context.Context, set string, version int64) (*models.PackSetVersion, error)
+	// AsOf returns the version that was current at t.
+	AsOf(ctx context.Context, set string, t time.Time) (*models.PackSetVersion, error)
+}
+
+type sqlPackSetVersionsRepository struct{}
+
+var packSetVersionsRepo PackSetVersionsRepository = &sqlPackSetVersionsRepository{}
+
+func PackSetVersions() PackSetVersionsRepository {
+	return packSetVersionsRepo
+}
+
+// SetPackSetVersionsRepository swaps the repository implementation (primarily for tests).
+func SetPackSetVersionsRepository(repo PackSetVersionsRepository) {
+	if repo == nil {
+		panic("PackSetVersionsRepository must not be nil")
+	}
+	packSetVersionsRepo = repo
+}
+
+func (r *sqlPackSetVersionsRepository) List(ctx context.Context, set string) ([]models.PackSetVersion, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, db.Rebind(`
+	SELECT pack_set, version, created_at, packs FROM pack_set_versions
+	WHERE tenant = ? AND pack_set = ? ORDER BY version ASC`), requestinfo.Tenant(ctx), set)
+	if err != nil {
+		return nil, fmt.Errorf("list pack set versions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []models.PackSetVersion
+	for rows.Next() {
+		v, err := scanPackSetVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate pack set versions: %w", err)
+	}
+	return out, nil
+}
+
+func (r *sqlPackSetVersionsRepository) Get(ctx context.Context, set string, version int64) (*models.PackSetVersion, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	row := conn.QueryRowContext(ctx, db.Rebind(`
+	SELECT pack_set, version, created_at, packs FROM pack_set_versions
+	WHERE tenant = ? AND pack_set = ? AND version = ?`), requestinfo.Tenant(ctx), set, version)
+	v, err := scanPackSetVersion(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w", ErrNotFound)
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+func (r *sqlPackSetVersionsRepository) AsOf(ctx context.Context, set string, t time.Time) (*models.PackSetVersion, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	row := conn.QueryRowContext(ctx, db.Rebind(`
+	SELECT pack_set, version, created_at, packs FROM pack_set_versions
+	WHERE tenant = ? AND pack_set = ? AND created_at <= ? ORDER BY version DESC LIMIT 1`),
+		requestinfo.Tenant(ctx), set, formatTime(t))
+	v, err := scanPackSetVersion(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w", ErrNotFound)
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPackSetVersion(row rowScanner) (*models.PackSetVersion, error) {
+	var (
+		v         models.PackSetVersion
+		createdAt string
+		packs     string
+	)
+	if err := row.Scan(&v.PackSet, &v.Version, &createdAt, &packs); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan pack set version: %w", err)
+	}
+	t, err := parseTime(createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("parse pack set version time: %w", err)
+	}
+	v.CreatedAt = t
+	if err := json.Unmarshal([]byte(packs), &v.Packs); err != nil {
+		return nil, fmt.Errorf("decode pack set version packs: %w", err)
+	}
+	return &v, nil
+}
+
+// lockPackSet serializes the transactions that change set, so that they read what the previous
+// one wrote and number their versions consecutively. SQLite allows a single writer anyway; on
+// PostgreSQL an advisory lock is held until the transaction ends.
+func lockPackSet(ctx context.Context, tx *sql.Tx, set string) error {
+	if db.CurrentDialect() != db.DialectPostgres {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`,
+		requestinfo.Tenant(ctx)+"/"+set); err != nil {
+		return fmt.Errorf("lock pack set: %w", err)
+	}
+	return nil
+}
+
+// recordPackSetVersion snapshots the current contents of set as a new version. It must run in
+// the same transaction as the change, after lockPackSet, so that history and live data never
+// diverge.
+//
+// When a set has no history yet (e.g. data that predates versioning), the state before the change
+// is recorded first as its own version. It gets a zero timestamp because it is unknown since when
+// it was in effect.
+func recordPackSetVersion(ctx context.Context, tx *sql.Tx, set string, before []models.PackSize) error {
+	var latest int64
+	if err := tx.QueryRowContext(ctx, db.Rebind(`
+	SELECT COALESCE(MAX(version), 0) FROM pack_set_versions WHERE tenant = ? AND pack_set = ?`),
+		requestinfo.Tenant(ctx), set).Scan(&latest); err != nil {
+		return fmt.Errorf("read latest pack set version: %w", err)
+	}
+
+	if latest == 0 && len(before) > 0 {
+		latest++
+		if err := insertPackSetVersion(ctx, tx, set, latest, formatTime(time.Time{}), before); err != nil {
+			return err
+		}
+	}
+
+	after, err := listPackSizes(ctx, tx, set)
+	if err != nil {
+		return err
+	}
+	return insertPackSetVersion(ctx, tx, set, latest+1, formatTime(now()), after)
+}
+
+func insertPackSetVersion(ctx context.Context, tx *sql.Tx, set string, version int64, ts string, packs []models.PackSize) error {
+	if packs == nil {
+		packs = []models.PackSize{}
+	}
+	b, err := json.Marshal(packs)
+	if err != nil {
+		return fmt.Errorf("encode pack set version packs: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, db.Rebind(`
+	INSERT INTO pack_set_versions(tenant, pack_set, version, created_at, packs) VALUES(?, ?, ?, ?, ?)`),
+		requestinfo.Tenant(ctx), set, version, ts, string(b)); err != nil {
+		return fmt.Errorf("insert pack set version: %w", err)
+	}
+	return nil
+}
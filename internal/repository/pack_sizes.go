@@ -2,38 +2,140 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packpolicy"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 var (
 	ErrNotFound = errors.New("not found")
 	ErrConflict = errors.New("conflict")
+	// ErrVersionMismatch is returned when an optimistic concurrency check fails.
+	ErrVersionMismatch = errors.New("version mismatch")
 )
 
-var defaultPackSizes = []int{250, 500, 1000, 2000, 5000}
+// DefaultPackSet is the pack set used when a request does not name one.
+const DefaultPackSet = "default"
 
+var packSetNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// ValidPackSetName reports whether name can be used as a pack set name.
+func ValidPackSetName(name string) bool {
+	return packSetNameRe.MatchString(name)
+}
+
+// builtinDefaultPackSizes are used when no default pack sizes are configured.
+var builtinDefaultPackSizes = []int{250, 500, 1000, 2000, 5000}
+
+var defaultPackSizes = builtinDefaultPackSizes
+
+// DefaultPackSizes returns the sizes ResetToDefault restores, in ascending order.
 func DefaultPackSizes() []int {
 	out := make([]int, len(defaultPackSizes))
 	copy(out, defaultPackSizes)
 	return out
 }
 
+// SetDefaultPackSizes configures the sizes ResetToDefault restores; an empty list restores the
+// built-in defaults. The sizes must already be validated (positive and unique). It is meant to be
+// called once at startup.
+func SetDefaultPackSizes(sizes []int) {
+	if len(sizes) == 0 {
+		defaultPackSizes = builtinDefaultPackSizes
+		return
+	}
+	out := make([]int, len(sizes))
+	copy(out, sizes)
+	sort.Ints(out)
+	defaultPackSizes = out
+}
+
+// tenantDefaultPackSizes overrides defaultPackSizes for individual tenants.
+var tenantDefaultPackSizes map[string][]int
+
+// TenantDefaultPackSizes returns the sizes ResetToDefault restores for tenant, in ascending order:
+// its own defaults when configured, DefaultPackSizes otherwise.
+func TenantDefaultPackSizes(tenant string) []int {
+	sizes, ok := tenantDefaultPackSizes[tenant]
+	if !ok {
+		return DefaultPackSizes()
+	}
+	out := make([]int, len(sizes))
+	copy(out, sizes)
+	return out
+}
+
+// SetTenantDefaultPackSizes configures per-tenant default pack sizes, replacing earlier ones.
+// Like SetDefaultPackSizes, the sizes must already be validated and it is meant to be called once
+// at startup.
+func SetTenantDefaultPackSizes(sizes map[string][]int) {
+	out := make(map[string][]int, len(sizes))
+	for tenant, s := range sizes {
+		if len(s) == 0 {
+			continue
+		}
+		sorted := make([]int, len(s))
+		copy(sorted, s)
+		sort.Ints(sorted)
+		out[tenant] = sorted
+	}
+	tenantDefaultPackSizes = out
+}
+
+// packSizePolicy is enforced by every change to pack sizes.
+var packSizePolicy packpolicy.Policy
+
+// PackSizePolicy returns the validation policy pack sizes must satisfy.
+func PackSizePolicy() packpolicy.Policy {
+	return packSizePolicy
+}
+
+// SetPackSizePolicy configures the validation policy. The policy must already be validated; it is
+// meant to be called once at startup. Pack sizes that were stored before keep working, but are
+// checked again when their size is changed or they are restored.
+func SetPackSizePolicy(policy packpolicy.Policy) {
+	packSizePolicy = policy
+}
+
+// PackSizesRepository manages pack sizes grouped into named pack sets.
+// A pack set exists implicitly as soon as it has at least one pack size.
+// Every method only sees the pack sets of the tenant in ctx (see requestinfo.Tenant); pack set
+// names and sizes are unique per tenant, IDs across all tenants.
+// Changes that add sizes fail with a *packpolicy.Error when they break the PackSizePolicy.
 type PackSizesRepository interface {
-	ResetToDefault(ctx context.Context) ([]int, error)
-	List(ctx context.Context) ([]models.PackSize, error)
-	Create(ctx context.Context, size int) (*models.PackSize, error)
-	Update(ctx context.Context, id int64, size int) (*models.PackSize, error)
-	Delete(ctx context.Context, id int64) error
+	ListSets(ctx context.Context) ([]string, error)
+	ResetToDefault(ctx context.Context, set string) ([]int, error)
+	// Replace makes sizes the only live pack sizes of a set in a single transaction.
+	Replace(ctx context.Context, set string, sizes []int) (*models.PackSizesDiff, error)
+	Import(ctx context.Context, set string, sizes []int, mode string, dryRun bool) (*models.PackSizesDiff, error)
+	// List returns the live pack sizes of a set, including those that are not in effect yet or
+	// any more; ListAll includes soft-deleted ones as well.
+	List(ctx context.Context, set string) ([]models.PackSize, error)
+	ListAll(ctx context.Context, set string) ([]models.PackSize, error)
+	// Get returns a live pack size.
+	Get(ctx context.Context, set string, id int64) (*models.PackSize, error)
+	// Create adds a pack size that calculations use during period; see EffectivePackSizes.
+	Create(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error)
+	// Update replaces the size, metadata and effective period of a pack size.
+	// Update and Delete fail with ErrVersionMismatch unless ifVersion is 0 or the current version.
+	Update(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error)
+	// Delete soft-deletes a pack size; Restore brings it back.
+	Delete(ctx context.Context, set string, id int64, ifVersion int64) error
+	Restore(ctx context.Context, set string, id int64) (*models.PackSize, error)
 }
 
-type sqlitePackSizesRepository struct{}
+type sqlPackSizesRepository struct{}
 
-var packSizesRepo PackSizesRepository = &sqlitePackSizesRepository{}
+var packSizesRepo PackSizesRepository = &sqlPackSizesRepository{}
 
 func PackSizes() PackSizesRepository {
 	return packSizesRepo
@@ -47,28 +149,38 @@ func SetPackSizesRepository(repo PackSizesRepository) {
 	packSizesRepo = repo
 }
 
-func (r *sqlitePackSizesRepository) ensureTable(ctx context.Context) error {
+func (r *sqlPackSizesRepository) ListSets(ctx context.Context) ([]string, error) {
 	conn, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("error getting database connection: %w", err)
+		return nil, fmt.Errorf("error getting database connection: %w", err)
 	}
 
-	_, err = conn.ExecContext(ctx, `
-	CREATE TABLE IF NOT EXISTS pack_sizes (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	size INTEGER NOT NULL UNIQUE
-	);`)
+	rows, err := conn.QueryContext(ctx, db.Rebind(`
+	SELECT DISTINCT pack_set FROM pack_sizes WHERE tenant = ? AND deleted_at IS NULL ORDER BY pack_set ASC`),
+		requestinfo.Tenant(ctx))
 	if err != nil {
-		return fmt.Errorf("ensure pack_sizes table: %w", err)
+		return nil, fmt.Errorf("list pack sets: %w", err)
 	}
-	return nil
-}
+	defer func() { _ = rows.Close() }()
 
-func (r *sqlitePackSizesRepository) ResetToDefault(ctx context.Context) ([]int, error) {
-	if err := r.ensureTable(ctx); err != nil {
-		return nil, fmt.Errorf("error ensuring pack_sizes table: %w", err)
+	// The default set always exists, even when it has no pack sizes.
+	out := []string{DefaultPackSet}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan pack set: %w", err)
+		}
+		if name != DefaultPackSet {
+			out = append(out, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate pack sets: %w", err)
 	}
+	return out, nil
+}
 
+func (r *sqlPackSizesRepository) ResetToDefault(ctx context.Context, set string) ([]int, error) {
 	conn, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("error getting database connection: %w", err)
@@ -80,21 +192,46 @@ func (r *sqlitePackSizesRepository) ResetToDefault(ctx context.Context) ([]int,
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	if _, err := tx.ExecContext(ctx, `DELETE FROM pack_sizes`); err != nil {
+	if err := lockPackSet(ctx, tx, set); err != nil {
+		return nil, err
+	}
+
+	before, err := listPackSizes(ctx, tx, set)
+	if err != nil {
+		return nil, err
+	}
+	defaults := TenantDefaultPackSizes(requestinfo.Tenant(ctx))
+	if err := packSizePolicy.CheckSizes(defaults, len(defaults)); err != nil {
+		return nil, err
+	}
+
+	// A reset starts the set from scratch, so soft-deleted rows are purged as well.
+	if _, err := tx.ExecContext(ctx, db.Rebind(`DELETE FROM pack_sizes WHERE tenant = ? AND pack_set = ?`),
+		requestinfo.Tenant(ctx), set); err != nil {
 		return nil, fmt.Errorf("error deleting pack sizes: %w", err)
 	}
 
-	// Best-effort reset of AUTOINCREMENT counter so IDs start from 1 again.
-	// (sqlite_sequence may not exist in some configurations; ignore errors.)
-	_, _ = tx.ExecContext(ctx, `DELETE FROM sqlite_sequence WHERE name = 'pack_sizes'`)
+	if err := resetPackSizeIDs(ctx, tx); err != nil {
+		return nil, err
+	}
 
-	defaults := DefaultPackSizes()
 	for _, s := range defaults {
-		if _, err := tx.ExecContext(ctx, `INSERT INTO pack_sizes(size) VALUES(?)`, s); err != nil {
+		if _, err := insertPackSize(ctx, tx, set, s, models.PackMetadata{}, models.EffectivePeriod{}); err != nil {
 			return nil, fmt.Errorf("error inserting pack size %d: %w", s, err)
 		}
 	}
 
+	after, err := listPackSizes(ctx, tx, set)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, tx, models.AuditActionReset, set, nil, nonNilPackSizes(before), after); err != nil {
+		return nil, err
+	}
+	if err := recordPackSetVersion(ctx, tx, set, before); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("error committing transaction at ResetToDefault: %w", err)
 	}
@@ -102,17 +239,141 @@ func (r *sqlitePackSizesRepository) ResetToDefault(ctx context.Context) ([]int,
 	return defaults, nil
 }
 
-func (r *sqlitePackSizesRepository) List(ctx context.Context) ([]models.PackSize, error) {
-	if err := r.ensureTable(ctx); err != nil {
-		return nil, fmt.Errorf("error ensuring pack_sizes table: %w", err)
+// Replace soft-deletes the pack sizes that are not in sizes, keeps the ones that are and inserts
+// the missing ones. sizes must be positive and unique. Nothing is recorded when the set is unchanged.
+func (r *sqlPackSizesRepository) Replace(ctx context.Context, set string, sizes []int) (*models.PackSizesDiff, error) {
+	return r.apply(ctx, "Replace", set, sizes, models.AuditActionReplace, true, false)
+}
+
+// Import adds sizes to a set (models.ImportModeMerge) or replaces the set with them
+// (models.ImportModeReplace). With dryRun the diff is computed but nothing is written;
+// added pack sizes then have no ID yet.
+func (r *sqlPackSizesRepository) Import(ctx context.Context, set string, sizes []int, mode string, dryRun bool) (*models.PackSizesDiff, error) {
+	switch mode {
+	case models.ImportModeMerge:
+		return r.apply(ctx, "Import", set, sizes, models.AuditActionImport, false, dryRun)
+	case models.ImportModeReplace:
+		return r.apply(ctx, "Import", set, sizes, models.AuditActionImport, true, dryRun)
+	default:
+		return nil, fmt.Errorf("unknown import mode %q", mode)
 	}
+}
 
+// apply makes sure every size in sizes is live in set, soft-deleting the other live sizes when
+// removeMissing is set. All changes run in a single transaction that is only committed when
+// dryRun is false.
+func (r *sqlPackSizesRepository) apply(ctx context.Context, op, set string, sizes []int, action string, removeMissing, dryRun bool) (*models.PackSizesDiff, error) {
 	conn, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("error getting database connection: %w", err)
 	}
 
-	rows, err := conn.QueryContext(ctx, `SELECT id, size FROM pack_sizes ORDER BY size ASC`)
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction at %s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockPackSet(ctx, tx, set); err != nil {
+		return nil, err
+	}
+
+	before, err := listPackSizes(ctx, tx, set)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[int]bool, len(sizes))
+	for _, s := range sizes {
+		wanted[s] = true
+	}
+
+	diff := &models.PackSizesDiff{
+		Added:   []models.PackSize{},
+		Removed: []models.PackSize{},
+		Kept:    []models.PackSize{},
+	}
+	existing := make(map[int]bool, len(before))
+	deletedAt := formatTime(now())
+	for _, p := range before {
+		existing[p.Size] = true
+		if wanted[p.Size] || !removeMissing {
+			diff.Kept = append(diff.Kept, p)
+			continue
+		}
+		if !dryRun {
+			if _, err := tx.ExecContext(ctx, db.Rebind(`UPDATE pack_sizes SET deleted_at = ?, version = version + 1 WHERE id = ?`), deletedAt, p.ID); err != nil {
+				return nil, fmt.Errorf("delete pack size %d: %w", p.Size, err)
+			}
+		}
+		diff.Removed = append(diff.Removed, p)
+	}
+
+	added := make([]int, 0, len(sizes))
+	for _, s := range sizes {
+		if !existing[s] {
+			existing[s] = true
+			added = append(added, s)
+		}
+	}
+	sort.Ints(added)
+	if err := packSizePolicy.CheckSizes(added, len(before)-len(diff.Removed)+len(added)); err != nil {
+		return nil, err
+	}
+	for _, s := range added {
+		if dryRun {
+			diff.Added = append(diff.Added, models.PackSize{Size: s})
+			continue
+		}
+		id, err := insertPackSize(ctx, tx, set, s, models.PackMetadata{}, models.EffectivePeriod{})
+		if err != nil {
+			if isUniqueViolation(err) {
+				return nil, fmt.Errorf("%w", ErrConflict)
+			}
+			return nil, fmt.Errorf("error inserting pack size %d: %w", s, err)
+		}
+		diff.Added = append(diff.Added, models.PackSize{ID: id, Size: s, Version: 1})
+	}
+
+	if dryRun || (len(diff.Added) == 0 && len(diff.Removed) == 0) {
+		return diff, nil
+	}
+
+	after, err := listPackSizes(ctx, tx, set)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, tx, action, set, nil, nonNilPackSizes(before), after); err != nil {
+		return nil, err
+	}
+	if err := recordPackSetVersion(ctx, tx, set, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction at %s: %w", op, err)
+	}
+	return diff, nil
+}
+
+func (r *sqlPackSizesRepository) List(ctx context.Context, set string) ([]models.PackSize, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	return listPackSizes(ctx, conn, set)
+}
+
+func (r *sqlPackSizesRepository) ListAll(ctx context.Context, set string) ([]models.PackSize, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, db.Rebind(`
+	SELECT `+packSizeColumns+`, deleted_at FROM pack_sizes WHERE tenant = ? AND pack_set = ?
+	ORDER BY size ASC, id ASC`), requestinfo.Tenant(ctx), set)
 	if err != nil {
 		return nil, fmt.Errorf("list pack sizes: %w", err)
 	}
@@ -120,10 +381,20 @@ func (r *sqlitePackSizesRepository) List(ctx context.Context) ([]models.PackSize
 
 	var out []models.PackSize
 	for rows.Next() {
-		var p models.PackSize
-		if err := rows.Scan(&p.ID, &p.Size); err != nil {
+		var (
+			p         models.PackSize
+			deletedAt sql.NullString
+		)
+		if err := scanPackSize(rows, &p, &deletedAt); err != nil {
 			return nil, fmt.Errorf("scan pack size: %w", err)
 		}
+		if deletedAt.Valid {
+			t, err := parseTime(deletedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("parse pack size deleted_at: %w", err)
+			}
+			p.DeletedAt = &t
+		}
 		out = append(out, p)
 	}
 	if err := rows.Err(); err != nil {
@@ -132,75 +403,404 @@ func (r *sqlitePackSizesRepository) List(ctx context.Context) ([]models.PackSize
 	return out, nil
 }
 
-func (r *sqlitePackSizesRepository) Create(ctx context.Context, size int) (*models.PackSize, error) {
-	if err := r.ensureTable(ctx); err != nil {
-		return nil, fmt.Errorf("error ensuring pack_sizes table: %w", err)
+func (r *sqlPackSizesRepository) Get(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	var p models.PackSize
+	row := conn.QueryRowContext(ctx, db.Rebind(`
+	SELECT `+packSizeColumns+` FROM pack_sizes WHERE id = ? AND tenant = ? AND pack_set = ? AND deleted_at IS NULL`),
+		id, requestinfo.Tenant(ctx), set)
+	if err := scanPackSize(row, &p); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("get pack size: %w", err)
 	}
+	return &p, nil
+}
 
+func (r *sqlPackSizesRepository) Create(ctx context.Context, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (*models.PackSize, error) {
 	conn, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("error getting database connection: %w", err)
 	}
 
-	res, err := conn.ExecContext(ctx, `INSERT INTO pack_sizes(size) VALUES(?)`, size)
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction at Create: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockPackSet(ctx, tx, set); err != nil {
+		return nil, err
+	}
+
+	before, err := listPackSizes(ctx, tx, set)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := packSizePolicy.CheckSize(size, len(before)+1); err != nil {
+		return nil, err
+	}
+
+	period = storedPeriod(period)
+	id, err := insertPackSize(ctx, tx, set, size, meta, period)
 	if err != nil {
 		if isUniqueViolation(err) {
 			return nil, fmt.Errorf("%w", ErrConflict)
 		}
 		return nil, fmt.Errorf("insert pack size: %w", err)
 	}
-	id, err := res.LastInsertId()
-	if err != nil {
-		return nil, fmt.Errorf("read insert id: %w", err)
+
+	created := &models.PackSize{ID: id, Size: size, PackMetadata: meta, EffectivePeriod: period, Version: 1}
+	if err := recordAudit(ctx, tx, models.AuditActionCreate, set, &id, nil, created); err != nil {
+		return nil, err
+	}
+	if err := recordPackSetVersion(ctx, tx, set, before); err != nil {
+		return nil, err
 	}
-	return &models.PackSize{ID: id, Size: size}, nil
-}
 
-func (r *sqlitePackSizesRepository) Update(ctx context.Context, id int64, size int) (*models.PackSize, error) {
-	if err := r.ensureTable(ctx); err != nil {
-		return nil, fmt.Errorf("error ensuring pack_sizes table: %w", err)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction at Create: %w", err)
 	}
+	return created, nil
+}
 
+func (r *sqlPackSizesRepository) Update(ctx context.Context, set string, id int64, size int, meta models.PackMetadata, period models.EffectivePeriod, ifVersion int64) (*models.PackSize, error) {
 	conn, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("error getting database connection: %w", err)
 	}
 
-	res, err := conn.ExecContext(ctx, `UPDATE pack_sizes SET size = ? WHERE id = ?`, size, id)
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction at Update: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockPackSet(ctx, tx, set); err != nil {
+		return nil, err
+	}
+
+	before, err := listPackSizes(ctx, tx, set)
+	if err != nil {
+		return nil, err
+	}
+
+	current, ok := findPackSize(before, id)
+	if !ok {
+		return nil, fmt.Errorf("%w", ErrNotFound)
+	}
+	if ifVersion != 0 && ifVersion != current.Version {
+		return nil, fmt.Errorf("%w", ErrVersionMismatch)
+	}
+	if size != current.Size {
+		if err := packSizePolicy.CheckSize(size, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	period = storedPeriod(period)
+	length, width, height := dimensionColumns(meta.Dimensions)
+	from, to := periodColumns(period)
+	res, err := tx.ExecContext(ctx, db.Rebind(`
+	UPDATE pack_sizes SET size = ?, label = ?, supplier_sku = ?, gtin = ?, length_mm = ?, width_mm = ?, height_mm = ?,
+	weight_grams = ?, effective_from = ?, effective_to = ?, version = version + 1
+	WHERE id = ? AND tenant = ? AND pack_set = ? AND deleted_at IS NULL AND version = ?`),
+		size, meta.Label, meta.SupplierSKU, meta.GTIN, length, width, height, meta.WeightGrams, from, to,
+		id, requestinfo.Tenant(ctx), set, current.Version)
 	if err != nil {
 		if isUniqueViolation(err) {
 			return nil, fmt.Errorf("%w", ErrConflict)
 		}
 		return nil, fmt.Errorf("update pack size: %w", err)
 	}
-	ra, err := res.RowsAffected()
-	if err == nil && ra == 0 {
-		return nil, fmt.Errorf("%w", ErrNotFound)
+	if err := checkGuardedWrite(ctx, tx, res, set, id); err != nil {
+		return nil, err
+	}
+
+	updated := &models.PackSize{ID: id, Size: size, PackMetadata: meta, EffectivePeriod: period, Version: current.Version + 1}
+	if err := recordAudit(ctx, tx, models.AuditActionUpdate, set, &id, current, updated); err != nil {
+		return nil, err
+	}
+	if err := recordPackSetVersion(ctx, tx, set, before); err != nil {
+		return nil, err
 	}
-	return &models.PackSize{ID: id, Size: size}, nil
-}
 
-func (r *sqlitePackSizesRepository) Delete(ctx context.Context, id int64) error {
-	if err := r.ensureTable(ctx); err != nil {
-		return fmt.Errorf("error ensuring pack_sizes table: %w", err)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction at Update: %w", err)
 	}
+	return updated, nil
+}
 
+func (r *sqlPackSizesRepository) Delete(ctx context.Context, set string, id int64, ifVersion int64) error {
 	conn, err := db.DB()
 	if err != nil {
 		return fmt.Errorf("error getting database connection: %w", err)
 	}
 
-	res, err := conn.ExecContext(ctx, `DELETE FROM pack_sizes WHERE id = ?`, id)
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction at Delete: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockPackSet(ctx, tx, set); err != nil {
+		return err
+	}
+
+	before, err := listPackSizes(ctx, tx, set)
+	if err != nil {
+		return err
+	}
+
+	current, ok := findPackSize(before, id)
+	if !ok {
+		return fmt.Errorf("%w", ErrNotFound)
+	}
+	if ifVersion != 0 && ifVersion != current.Version {
+		return fmt.Errorf("%w", ErrVersionMismatch)
+	}
+
+	res, err := tx.ExecContext(ctx, db.Rebind(`
+	UPDATE pack_sizes SET deleted_at = ?, version = version + 1
+	WHERE id = ? AND tenant = ? AND pack_set = ? AND deleted_at IS NULL AND version = ?`),
+		formatTime(now()), id, requestinfo.Tenant(ctx), set, current.Version)
 	if err != nil {
 		return fmt.Errorf("delete pack size: %w", err)
 	}
-	ra, err := res.RowsAffected()
-	if err == nil && ra == 0 {
+	if err := checkGuardedWrite(ctx, tx, res, set, id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, models.AuditActionDelete, set, &id, current, nil); err != nil {
+		return err
+	}
+	if err := recordPackSetVersion(ctx, tx, set, before); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction at Delete: %w", err)
+	}
+	return nil
+}
+
+// Restore brings back a soft-deleted pack size. Restoring a live pack size is a no-op.
+// It returns ErrConflict if the same size was created again in the meantime.
+func (r *sqlPackSizesRepository) Restore(ctx context.Context, set string, id int64) (*models.PackSize, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction at Restore: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockPackSet(ctx, tx, set); err != nil {
+		return nil, err
+	}
+
+	var (
+		p         models.PackSize
+		deletedAt sql.NullString
+	)
+	row := tx.QueryRowContext(ctx, db.Rebind(`
+	SELECT `+packSizeColumns+`, deleted_at FROM pack_sizes WHERE id = ? AND tenant = ? AND pack_set = ?`),
+		id, requestinfo.Tenant(ctx), set)
+	if err := scanPackSize(row, &p, &deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("get pack size: %w", err)
+	}
+	if !deletedAt.Valid {
+		return &p, nil
+	}
+
+	before, err := listPackSizes(ctx, tx, set)
+	if err != nil {
+		return nil, err
+	}
+	if err := packSizePolicy.CheckSize(p.Size, len(before)+1); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, db.Rebind(`UPDATE pack_sizes SET deleted_at = NULL, version = version + 1 WHERE id = ?`), id); err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w", ErrConflict)
+		}
+		return nil, fmt.Errorf("restore pack size: %w", err)
+	}
+	p.Version++
+
+	if err := recordAudit(ctx, tx, models.AuditActionRestore, set, &id, nil, p); err != nil {
+		return nil, err
+	}
+	if err := recordPackSetVersion(ctx, tx, set, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction at Restore: %w", err)
+	}
+	return &p, nil
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func listPackSizes(ctx context.Context, q querier, set string) ([]models.PackSize, error) {
+	rows, err := q.QueryContext(ctx, db.Rebind(`
+	SELECT `+packSizeColumns+` FROM pack_sizes WHERE tenant = ? AND pack_set = ? AND deleted_at IS NULL
+	ORDER BY size ASC`), requestinfo.Tenant(ctx), set)
+	if err != nil {
+		return nil, fmt.Errorf("list pack sizes: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []models.PackSize
+	for rows.Next() {
+		var p models.PackSize
+		if err := scanPackSize(rows, &p); err != nil {
+			return nil, fmt.Errorf("scan pack size: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate pack sizes: %w", err)
+	}
+	return out, nil
+}
+
+// packSizeColumns are the pack_sizes columns read by scanPackSize, in order.
+const packSizeColumns = `id, size, version, label, supplier_sku, gtin, length_mm, width_mm, height_mm, weight_grams,
+	effective_from, effective_to`
+
+// scanPackSize scans packSizeColumns into p, followed by any extra columns.
+func scanPackSize(row rowScanner, p *models.PackSize, extra ...any) error {
+	var (
+		d        models.PackDimensions
+		from, to sql.NullString
+	)
+	dest := []any{&p.ID, &p.Size, &p.Version, &p.Label, &p.SupplierSKU, &p.GTIN,
+		&d.LengthMM, &d.WidthMM, &d.HeightMM, &p.WeightGrams, &from, &to}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	if d != (models.PackDimensions{}) {
+		p.Dimensions = &d
+	}
+	var err error
+	if p.EffectiveFrom, err = parseNullTime(from); err != nil {
+		return fmt.Errorf("parse effective_from: %w", err)
+	}
+	if p.EffectiveTo, err = parseNullTime(to); err != nil {
+		return fmt.Errorf("parse effective_to: %w", err)
+	}
+	return nil
+}
+
+// dimensionColumns returns the values stored for dims; zeros mean "not set".
+func dimensionColumns(dims *models.PackDimensions) (length, width, height int) {
+	if dims == nil {
+		return 0, 0, 0
+	}
+	return dims.LengthMM, dims.WidthMM, dims.HeightMM
+}
+
+// insertPackSize inserts a live pack size for the tenant in ctx and returns its ID.
+func insertPackSize(ctx context.Context, q querier, set string, size int, meta models.PackMetadata, period models.EffectivePeriod) (int64, error) {
+	length, width, height := dimensionColumns(meta.Dimensions)
+	from, to := periodColumns(period)
+	var id int64
+	err := q.QueryRowContext(ctx, db.Rebind(`
+	INSERT INTO pack_sizes(tenant, pack_set, size, label, supplier_sku, gtin, length_mm, width_mm, height_mm, weight_grams,
+	effective_from, effective_to)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		requestinfo.Tenant(ctx), set, size, meta.Label, meta.SupplierSKU, meta.GTIN, length, width, height, meta.WeightGrams,
+		from, to).Scan(&id)
+	return id, err
+}
+
+// checkGuardedWrite checks that a write guarded by the version read earlier in the transaction
+// changed the pack size. When it did not, a concurrent transaction deleted the pack size
+// (ErrNotFound) or changed it (ErrVersionMismatch) in the meantime.
+func checkGuardedWrite(ctx context.Context, q querier, res sql.Result, set string, id int64) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("read affected rows: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	var live bool
+	err = q.QueryRowContext(ctx, db.Rebind(`
+	SELECT deleted_at IS NULL FROM pack_sizes WHERE id = ? AND tenant = ? AND pack_set = ?`),
+		id, requestinfo.Tenant(ctx), set).Scan(&live)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && !live {
 		return fmt.Errorf("%w", ErrNotFound)
 	}
+	if err != nil {
+		return fmt.Errorf("get pack size: %w", err)
+	}
+	return fmt.Errorf("%w", ErrVersionMismatch)
+}
+
+// resetPackSizeIDs restarts pack size IDs from 1, which is only possible when no pack set
+// still holds rows.
+func resetPackSizeIDs(ctx context.Context, q querier) error {
+	switch db.CurrentDialect() {
+	case db.DialectPostgres:
+		if _, err := q.ExecContext(ctx, `SELECT setval(pg_get_serial_sequence('pack_sizes', 'id'), 1, false)
+		WHERE NOT EXISTS (SELECT 1 FROM pack_sizes)`); err != nil {
+			return fmt.Errorf("reset pack size ids: %w", err)
+		}
+	default:
+		// Best-effort reset of the AUTOINCREMENT counter.
+		// (sqlite_sequence may not exist in some configurations; ignore errors.)
+		_, _ = q.ExecContext(ctx, `DELETE FROM sqlite_sequence WHERE name = 'pack_sizes'
+		AND NOT EXISTS (SELECT 1 FROM pack_sizes)`)
+	}
 	return nil
 }
 
+// findPackSize returns the pack size with the given ID and whether it is in the list.
+func findPackSize(packs []models.PackSize, id int64) (models.PackSize, bool) {
+	for _, p := range packs {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return models.PackSize{}, false
+}
+
+func nonNilPackSizes(packs []models.PackSize) []models.PackSize {
+	if packs == nil {
+		return []models.PackSize{}
+	}
+	return packs
+}
+
+// pgUniqueViolation is the SQLSTATE PostgreSQL reports for unique_violation.
+const pgUniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a unique constraint violation in either dialect.
 func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
 	return strings.Contains(strings.ToLower(err.Error()), "unique constraint failed")
 }
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+// ProductsRepository manages the product catalog of the tenant in ctx. Each product points at the
+// pack set its pack sizes come from; SKUs are unique per tenant.
+type ProductsRepository interface {
+	List(ctx context.Context) ([]models.Product, error)
+	Get(ctx context.Context, sku string) (*models.Product, error)
+	Create(ctx context.Context, p models.Product) (*models.Product, error)
+	Update(ctx context.Context, sku string, p models.Product) (*models.Product, error)
+	Delete(ctx context.Context, sku string) error
+}
+
+type sqlProductsRepository struct{}
+
+var productsRepo ProductsRepository = &sqlProductsRepository{}
+
+func Products() ProductsRepository {
+	return productsRepo
+}
+
+// SetProductsRepository swaps the repository implementation (primarily for tests).
+func SetProductsRepository(repo ProductsRepository) {
+	if repo == nil {
+		panic("ProductsRepository must not be nil")
+	}
+	productsRepo = repo
+}
+
+func (r *sqlProductsRepository) List(ctx context.Context) ([]models.Product, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, db.Rebind(`
+	SELECT id, sku, name, description, pack_set FROM products WHERE tenant = ? ORDER BY sku ASC`), requestinfo.Tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list products: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []models.Product
+	for rows.Next() {
+		var p models.Product
+		if err := rows.Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.PackSet); err != nil {
+			return nil, fmt.Errorf("scan product: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate products: %w", err)
+	}
+	return out, nil
+}
+
+func (r *sqlProductsRepository) Get(ctx context.Context, sku string) (*models.Product, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	var p models.Product
+	err = conn.QueryRowContext(ctx, db.Rebind(`
+	SELECT id, sku, name, description, pack_set FROM products WHERE tenant = ? AND sku = ?`), requestinfo.Tenant(ctx), sku).
+		Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.PackSet)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("get product: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *sqlProductsRepository) Create(ctx context.Context, p models.Product) (*models.Product, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	err = conn.QueryRowContext(ctx, db.Rebind(`
+	INSERT INTO products(tenant, sku, name, description, pack_set) VALUES(?, ?, ?, ?, ?) RETURNING id`),
+		requestinfo.Tenant(ctx), p.SKU, p.Name, p.Description, p.PackSet).Scan(&p.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w", ErrConflict)
+		}
+		return nil, fmt.Errorf("insert product: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *sqlProductsRepository) Update(ctx context.Context, sku string, p models.Product) (*models.Product, error) {
+	conn, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	res, err := conn.ExecContext(ctx, db.Rebind(`
+	UPDATE products SET name = ?, description = ?, pack_set = ? WHERE tenant = ? AND sku = ?`),
+		p.Name, p.Description, p.PackSet, requestinfo.Tenant(ctx), sku)
+	if err != nil {
+		return nil, fmt.Errorf("update product: %w", err)
+	}
+	ra, err := res.RowsAffected()
+	if err == nil && ra == 0 {
+		return nil, fmt.Errorf("%w", ErrNotFound)
+	}
+	return r.Get(ctx, sku)
+}
+
+func (r *sqlProductsRepository) Delete(ctx context.Context, sku string) error {
+	conn, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("error getting database connection: %w", err)
+	}
+
+	res, err := conn.ExecContext(ctx, db.Rebind(`DELETE FROM products WHERE tenant = ? AND sku = ?`), requestinfo.Tenant(ctx), sku)
+	if err != nil {
+		return fmt.Errorf("delete product: %w", err)
+	}
+	ra, err := res.RowsAffected()
+	if err == nil && ra == 0 {
+		return fmt.Errorf("%w", ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// timeLayout is a fixed-width UTC layout, so stored timestamps sort correctly as text.
+const timeLayout = "2006-01-02T15:04:05.000000Z"
+
+// now is the repository clock (swappable in tests).
+var now = time.Now
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(timeLayout, s)
+}
+
+// parseNullTime parses an optional timestamp; NULL yields nil.
+func parseNullTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := parseTime(s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
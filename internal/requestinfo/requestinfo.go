@@ -0,0 +1,53 @@
+package requestinfo
+
+import "context"
+
+// AnonymousActor is used when a request does not identify who made it.
+const AnonymousActor = "anonymous"
+
+// DefaultTenant owns all data of a single-tenant deployment and every request that does not name
+// a tenant.
+const DefaultTenant = "default"
+
+type ctxKey int
+
+const (
+	actorKey ctxKey = iota
+	requestIDKey
+	tenantKey
+)
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns who performs the current request, or AnonymousActor.
+func Actor(ctx context.Context) string {
+	if v, ok := ctx.Value(actorKey).(string); ok && v != "" {
+		return v
+	}
+	return AnonymousActor
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the ID of the current request, or "" outside of a request.
+func RequestID(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDKey).(string)
+	return v
+}
+
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// Tenant returns the tenant whose data the current request may access, or DefaultTenant.
+// Repositories scope every read and write to it.
+func Tenant(ctx context.Context) string {
+	if v, ok := ctx.Value(tenantKey).(string); ok && v != "" {
+		return v
+	}
+	return DefaultTenant
+}
@@ -0,0 +1,169 @@
+// Package seed applies a declarative file of pack sets and pack sizes to the repository.
+//
+// Seeding only ever adds: pack sizes that already exist in a pack set (live or soft-deleted)
+// are left untouched, so applying the same file again changes nothing.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/configfile"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/packmeta"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/tenant"
+)
+
+const (
+	FormatJSON = configfile.FormatJSON
+	FormatYAML = configfile.FormatYAML
+)
+
+// File is the content of a seed file.
+type File struct {
+	PackSets []PackSet `json:"pack_sets"`
+}
+
+type PackSet struct {
+	// Tenant owns the pack set; empty means the default tenant.
+	Tenant string `json:"tenant,omitempty"`
+	Name   string `json:"name"`
+	Packs  []Pack `json:"packs"`
+}
+
+type Pack struct {
+	Size int `json:"size"`
+	models.PackMetadata
+}
+
+// Created is a pack size added by Apply.
+type Created struct {
+	Tenant  string
+	PackSet string
+	models.PackSize
+}
+
+// Result reports what Apply changed.
+type Result struct {
+	Created []Created
+	// Existing counts the pack sizes of the file that were already there.
+	Existing int
+}
+
+// Load reads and validates the seed file at path.
+func Load(path string) (*File, error) {
+	format := configfile.FormatFromPath(path)
+	if format == "" {
+		return nil, fmt.Errorf("seed file must be a .json or .yaml file, provided: %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read seed file: %w", err)
+	}
+	return Parse(data, format)
+}
+
+// Parse decodes and validates a seed file; see configfile.Decode for the supported formats.
+func Parse(data []byte, format string) (*File, error) {
+	var f File
+	if err := configfile.Decode(data, format, &f); err != nil {
+		return nil, fmt.Errorf("invalid seed file: %w", err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("invalid seed file: %w", err)
+	}
+	return &f, nil
+}
+
+// validate checks the file and normalizes the pack metadata.
+func (f *File) validate() error {
+	var errs []error
+	seenSets := make(map[[2]string]bool, len(f.PackSets))
+	for i := range f.PackSets {
+		set := &f.PackSets[i]
+		if set.Tenant != "" && !tenant.ValidID(set.Tenant) {
+			errs = append(errs, fmt.Errorf("pack_sets[%d]: invalid tenant %q", i, set.Tenant))
+			continue
+		}
+		if !repository.ValidPackSetName(set.Name) {
+			errs = append(errs, fmt.Errorf("pack_sets[%d]: invalid pack set name %q", i, set.Name))
+			continue
+		}
+		key := [2]string{set.Tenant, set.Name}
+		if seenSets[key] {
+			errs = append(errs, fmt.Errorf("pack_sets[%d]: duplicate pack set %q", i, set.Name))
+			continue
+		}
+		seenSets[key] = true
+
+		seenSizes := make(map[int]bool, len(set.Packs))
+		for j := range set.Packs {
+			p := &set.Packs[j]
+			if p.Size <= 0 {
+				errs = append(errs, fmt.Errorf("%s packs[%d]: size must be > 0", set.Name, j))
+				continue
+			}
+			if seenSizes[p.Size] {
+				errs = append(errs, fmt.Errorf("%s packs[%d]: duplicate size %d", set.Name, j, p.Size))
+				continue
+			}
+			seenSizes[p.Size] = true
+
+			meta, err := packmeta.Normalize(p.PackMetadata)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s packs[%d]: %w", set.Name, j, err))
+				continue
+			}
+			p.PackMetadata = meta
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Apply creates the pack sizes of f that are missing from repo. Every pack size is created on its
+// own, so a failure part way leaves what was created so far; applying the file again resumes.
+// Pack sets without a tenant belong to the tenant in ctx.
+func Apply(ctx context.Context, repo repository.PackSizesRepository, f *File) (*Result, error) {
+	res := &Result{}
+	parent := ctx
+	for _, set := range f.PackSets {
+		ctx := parent
+		if set.Tenant != "" {
+			ctx = requestinfo.WithTenant(parent, set.Tenant)
+		}
+		owner := requestinfo.Tenant(ctx)
+
+		// Soft-deleted pack sizes count as existing so that a deliberate delete is not undone
+		// on the next start.
+		existing, err := repo.ListAll(ctx, set.Name)
+		if err != nil {
+			return res, fmt.Errorf("list pack sizes of %s: %w", set.Name, err)
+		}
+		have := make(map[int]bool, len(existing))
+		for _, p := range existing {
+			have[p.Size] = true
+		}
+
+		for _, p := range set.Packs {
+			if have[p.Size] {
+				res.Existing++
+				continue
+			}
+			created, err := repo.Create(ctx, set.Name, p.Size, p.PackMetadata, models.EffectivePeriod{})
+			if errors.Is(err, repository.ErrConflict) {
+				// Created concurrently, e.g. by another instance starting up.
+				res.Existing++
+				continue
+			}
+			if err != nil {
+				return res, fmt.Errorf("create pack size %s/%d: %w", set.Name, p.Size, err)
+			}
+			res.Created = append(res.Created, Created{Tenant: owner, PackSet: set.Name, PackSize: *created})
+		}
+	}
+	return res, nil
+}
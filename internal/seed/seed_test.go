@@ -0,0 +1,183 @@
+package seed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+)
+
+const yamlSeed = `
+pack_sets:
+  - name: default
+    packs:
+      - size: 250
+        label: " Small carton "
+        gtin: "4006381333931"
+        dimensions: {length_mm: 300, width_mm: 200, height_mm: 100}
+      - size: 500
+  - name: retail
+    packs:
+      - size: 6
+        supplier_sku: SUP-6
+        weight_grams: 900
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(yamlSeed), FormatYAML)
+	if err != nil {
+		t.Fatalf("parse yaml: %v", err)
+	}
+	want := &File{PackSets: []PackSet{
+		{Name: "default", Packs: []Pack{
+			{Size: 250, PackMetadata: models.PackMetadata{
+				Label:      "Small carton",
+				GTIN:       "4006381333931",
+				Dimensions: &models.PackDimensions{LengthMM: 300, WidthMM: 200, HeightMM: 100},
+			}},
+			{Size: 500},
+		}},
+		{Name: "retail", Packs: []Pack{
+			{Size: 6, PackMetadata: models.PackMetadata{SupplierSKU: "SUP-6", WeightGrams: 900}},
+		}},
+	}}
+	if !reflect.DeepEqual(f, want) {
+		t.Fatalf("yaml = %+v, want %+v", f, want)
+	}
+
+	f, err = Parse([]byte(`{"pack_sets":[{"name":"default","packs":[{"size":250,"label":"Small"}]}]}`), FormatJSON)
+	if err != nil {
+		t.Fatalf("parse json: %v", err)
+	}
+	if len(f.PackSets) != 1 || f.PackSets[0].Packs[0].Label != "Small" {
+		t.Fatalf("json = %+v", f)
+	}
+}
+
+func TestParseRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name, data, wantErr string
+	}{
+		{"unknown field", `{"pack_sets":[{"name":"default","packs":[{"size":250,"lable":"x"}]}]}`, "unknown field"},
+		{"invalid set name", `{"pack_sets":[{"name":"no spaces","packs":[]}]}`, `invalid pack set name "no spaces"`},
+		{"duplicate set", `{"pack_sets":[{"name":"a"},{"name":"a"}]}`, `duplicate pack set "a"`},
+		{"bad size", `{"pack_sets":[{"name":"a","packs":[{"size":0}]}]}`, "a packs[0]: size must be > 0"},
+		{"duplicate size", `{"pack_sets":[{"name":"a","packs":[{"size":5},{"size":5}]}]}`, "a packs[1]: duplicate size 5"},
+		{"bad gtin", `{"pack_sets":[{"name":"a","packs":[{"size":5,"gtin":"4006381333932"}]}]}`, "gtin check digit is invalid"},
+		{"malformed", `{"pack_sets":`, "invalid seed file"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data), FormatJSON)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadByExtension(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "seed.yml")
+	if err := os.WriteFile(path, []byte(yamlSeed), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := Load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "seed.txt")); err == nil {
+		t.Fatalf("expected an error for an unsupported extension")
+	}
+}
+
+func TestApplyIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	orig := repository.PackSizes()
+	t.Cleanup(func() { repository.SetPackSizesRepository(orig) })
+	repository.UseMemoryBackend()
+	repo := repository.PackSizes()
+
+	// Existing rows are left alone, including soft-deleted ones.
+	existing, err := repo.Create(ctx, repository.DefaultPackSet, 250, models.PackMetadata{Label: "Mine"}, models.EffectivePeriod{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	deleted, err := repo.Create(ctx, "retail", 6, models.PackMetadata{}, models.EffectivePeriod{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := repo.Delete(ctx, "retail", deleted.ID, 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	f, err := Parse([]byte(yamlSeed), FormatYAML)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	res, err := Apply(ctx, repo, f)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(res.Created) != 1 || res.Created[0].PackSet != repository.DefaultPackSet || res.Created[0].Size != 500 {
+		t.Fatalf("created = %+v", res.Created)
+	}
+	if res.Existing != 2 {
+		t.Fatalf("existing = %d, want 2", res.Existing)
+	}
+
+	got, err := repo.Get(ctx, repository.DefaultPackSet, existing.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Label != "Mine" || got.Version != existing.Version {
+		t.Fatalf("existing pack size was changed: %+v", got)
+	}
+
+	res, err = Apply(ctx, repo, f)
+	if err != nil {
+		t.Fatalf("second apply: %v", err)
+	}
+	if len(res.Created) != 0 || res.Existing != 3 {
+		t.Fatalf("second apply = %+v", res)
+	}
+}
+
+func TestApplyScopesPackSetsToTheirTenant(t *testing.T) {
+	ctx := context.Background()
+	orig := repository.PackSizes()
+	t.Cleanup(func() { repository.SetPackSizesRepository(orig) })
+	repository.UseMemoryBackend()
+	repo := repository.PackSizes()
+
+	f, err := Parse([]byte(`{"pack_sets":[
+		{"name":"default","packs":[{"size":250}]},
+		{"tenant":"acme","name":"default","packs":[{"size":6},{"size":12}]}]}`), FormatJSON)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	res, err := Apply(ctx, repo, f)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(res.Created) != 3 || res.Created[0].Tenant != requestinfo.DefaultTenant || res.Created[1].Tenant != "acme" {
+		t.Fatalf("created = %+v", res.Created)
+	}
+
+	for tenant, want := range map[string]int{requestinfo.DefaultTenant: 1, "acme": 2} {
+		packs, err := repo.List(requestinfo.WithTenant(ctx, tenant), repository.DefaultPackSet)
+		if err != nil || len(packs) != want {
+			t.Fatalf("%s: packs = %+v, %v", tenant, packs, err)
+		}
+	}
+
+	if _, err := Parse([]byte(`{"pack_sets":[{"tenant":"no spaces","name":"a"}]}`), FormatJSON); err == nil ||
+		!strings.Contains(err.Error(), `invalid tenant "no spaces"`) {
+		t.Fatalf("expected an invalid tenant error, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,7 +13,13 @@ import (
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/config"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/db"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/handlers"
+	httpmw "github.com/NikolaNedicVCS/re-order-packs-calculator/internal/http_server/middleware"
 	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/log"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/repository"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/requestinfo"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/seed"
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/tenant"
 )
 
 type Server struct {
@@ -33,14 +40,55 @@ func (s *Server) Start() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// init sqlite database
-	if err := db.InitSQLite(ctx, s.cfg.DBPath); err != nil {
-		log.Error("failed to init sqlite", "err", err)
+	if len(s.cfg.DefaultPackSizes) > 0 {
+		repository.SetDefaultPackSizes(s.cfg.DefaultPackSizes)
+		log.Info("using configured default pack sizes", "sizes", repository.DefaultPackSizes())
+	}
+
+	repository.SetPackSizePolicy(s.cfg.PackSizePolicy)
+
+	if err := s.loadTenants(); err != nil {
+		log.Error("failed to load tenants file", "path", s.cfg.TenantsFile, "err", err)
 		s.Shutdown(context.Background())
 		return
 	}
 
+	if s.cfg.DBDriver == config.DBDriverMemory {
+		repository.UseMemoryBackend()
+		log.Warn("using in-memory storage; all data is lost on exit")
+	} else {
+		// init database
+		if err := db.Init(ctx, db.Dialect(s.cfg.DBDriver), s.cfg.DBPath, s.cfg.DBDSN); err != nil {
+			log.Error("failed to init database", "driver", s.cfg.DBDriver, "err", err)
+			s.Shutdown(context.Background())
+			return
+		}
+
+		// apply pending schema migrations
+		if err := s.migrate(ctx); err != nil {
+			log.Error("failed to migrate database", "err", err)
+			s.Shutdown(context.Background())
+			return
+		}
+	}
+
+	if s.cfg.SeedFile != "" {
+		if err := s.seed(ctx); err != nil {
+			log.Error("failed to apply seed file", "path", s.cfg.SeedFile, "err", err)
+			s.Shutdown(context.Background())
+			return
+		}
+	}
+
+	// The memory backend needs no cache in front of it.
+	if s.cfg.PackCacheTTL > 0 && s.cfg.DBDriver != config.DBDriverMemory {
+		repository.SetPackSizesRepository(repository.NewCachedPackSizesRepository(repository.PackSizes(), s.cfg.PackCacheTTL))
+	}
+
 	// init http server
+	handlers.SetRequireIfMatch(s.cfg.RequireIfMatch)
+	httpmw.SetIdempotencyKeyTTL(s.cfg.IdempotencyKeyTTL)
+	httpmw.SetAdminToken(s.cfg.AdminToken)
 	handler := http_server.NewHTTPHandler()
 	s.httpServer = http_server.NewHTTPServer(":"+s.cfg.HTTPPort, handler)
 
@@ -55,6 +103,62 @@ func (s *Server) Start() {
 
 }
 
+func (s *Server) migrate(ctx context.Context) error {
+	conn, err := db.DB()
+	if err != nil {
+		return err
+	}
+	applied, err := db.Migrate(ctx, conn)
+	for _, m := range applied {
+		log.Info("applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
+
+// loadTenants applies the tenants file, if configured.
+func (s *Server) loadTenants() error {
+	if s.cfg.TenantsFile == "" {
+		httpmw.SetTenantResolver(tenant.NewResolver(nil))
+		return nil
+	}
+	f, err := tenant.Load(s.cfg.TenantsFile)
+	if err != nil {
+		return err
+	}
+	defaults := f.DefaultPackSizes()
+	for id, sizes := range defaults {
+		if err := s.cfg.PackSizePolicy.CheckSizes(sizes, len(sizes)); err != nil {
+			return fmt.Errorf("default pack sizes of tenant %s violate the pack size policy: %w", id, err)
+		}
+	}
+	repository.SetTenantDefaultPackSizes(defaults)
+	resolver := tenant.NewResolver(f)
+	httpmw.SetTenantResolver(resolver)
+	log.Info("loaded tenants file", "path", s.cfg.TenantsFile, "tenants", len(f.Tenants),
+		"api_keys_required", resolver.RequiresAPIKey())
+	return nil
+}
+
+// seedActor is recorded in the audit log for changes made by the seed file.
+const seedActor = "seed"
+
+func (s *Server) seed(ctx context.Context) error {
+	f, err := seed.Load(s.cfg.SeedFile)
+	if err != nil {
+		return err
+	}
+	res, err := seed.Apply(requestinfo.WithActor(ctx, seedActor), repository.PackSizes(), f)
+	// Apply reports what it created even when it fails part way.
+	for _, c := range res.Created {
+		log.Info("seeded pack size", "tenant", c.Tenant, "pack_set", c.PackSet, "id", c.ID, "size", c.Size)
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("applied seed file", "path", s.cfg.SeedFile, "created", len(res.Created), "existing", res.Existing)
+	return nil
+}
+
 func (s *Server) listenForKillSignal() {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
@@ -96,8 +200,8 @@ func (s *Server) shutdown(ctx context.Context) {
 		}
 	}
 
-	if err := db.CloseSQLite(); err != nil {
-		log.Error("sqlite close failed", "err", err)
+	if err := db.Close(); err != nil {
+		log.Error("database close failed", "err", err)
 		success = false
 	}
 
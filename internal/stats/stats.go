@@ -0,0 +1,213 @@
+// Package stats aggregates the calculation history into time buckets.
+package stats
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+// MaxBuckets caps the number of buckets a single aggregation may produce.
+const MaxBuckets = 1000
+
+var (
+	ErrInvalidBucket  = errors.New("invalid bucket")
+	ErrInvalidRange   = errors.New("invalid range")
+	ErrTooManyBuckets = errors.New("too many buckets")
+)
+
+// Aggregator collects calculations made in [from, to) into buckets.
+type Aggregator struct {
+	from, to time.Time
+	bucket   string
+	buckets  []*accumulator
+	total    *accumulator
+}
+
+type accumulator struct {
+	models.StatsBucket
+	packs     map[int]int64
+	durations []int64
+}
+
+// New returns an aggregator for the range [from, to) split into buckets of the given size.
+func New(from, to time.Time, bucket string) (*Aggregator, error) {
+	from, to = from.UTC(), to.UTC()
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidRange)
+	}
+	if _, err := next(from, bucket); err != nil {
+		return nil, err
+	}
+
+	a := &Aggregator{from: from, to: to, bucket: bucket, total: newAccumulator(from, to)}
+	for start := truncate(from, bucket); start.Before(to); {
+		if len(a.buckets) == MaxBuckets {
+			return nil, fmt.Errorf("%w: at most %d %s buckets", ErrTooManyBuckets, MaxBuckets, bucket)
+		}
+		end, _ := next(start, bucket)
+		a.buckets = append(a.buckets, newAccumulator(start, end))
+		start = end
+	}
+	return a, nil
+}
+
+func newAccumulator(start, end time.Time) *accumulator {
+	return &accumulator{StatsBucket: models.StatsBucket{Start: start, End: end}, packs: make(map[int]int64)}
+}
+
+// Add counts c in its bucket; calculations outside the range are ignored.
+func (a *Aggregator) Add(c models.Calculation) {
+	at := c.CreatedAt.UTC()
+	if at.Before(a.from) || !at.Before(a.to) {
+		return
+	}
+	i, _ := slices.BinarySearchFunc(a.buckets, at, func(b *accumulator, t time.Time) int {
+		switch {
+		case !b.End.After(t):
+			return -1
+		case b.Start.After(t):
+			return 1
+		}
+		return 0
+	})
+	a.buckets[i].add(c)
+	a.total.add(c)
+}
+
+func (acc *accumulator) add(c models.Calculation) {
+	acc.Calculations++
+	acc.Requested += int64(c.Quantity)
+	acc.Shipped += int64(c.Shipped)
+	acc.Overage += int64(c.Overage)
+	for _, p := range c.Packs {
+		acc.packs[p.Size] += int64(p.Count)
+	}
+	acc.durations = append(acc.durations, c.DurationMicros)
+}
+
+// Result returns the aggregated statistics.
+func (a *Aggregator) Result() models.StatsResponse {
+	out := models.StatsResponse{
+		From:    a.from,
+		To:      a.to,
+		Bucket:  a.bucket,
+		Buckets: make([]models.StatsBucket, len(a.buckets)),
+		Total:   a.total.result(),
+	}
+	for i, b := range a.buckets {
+		out.Buckets[i] = b.result()
+	}
+	return out
+}
+
+func (acc *accumulator) result() models.StatsBucket {
+	b := acc.StatsBucket
+	if b.Requested > 0 {
+		b.OveragePercent = math.Round(float64(b.Overage)/float64(b.Requested)*10000) / 100
+	}
+	b.Packs = []models.PackUsage{}
+	for size, count := range acc.packs {
+		b.Packs = append(b.Packs, models.PackUsage{Size: size, Count: count})
+	}
+	slices.SortFunc(b.Packs, func(x, y models.PackUsage) int { return x.Size - y.Size })
+	durations := slices.Clone(acc.durations)
+	slices.Sort(durations)
+	b.LatencyP50Micros = percentile(durations, 50)
+	b.LatencyP95Micros = percentile(durations, 95)
+	return b
+}
+
+// percentile returns the nearest-rank percentile p of sorted values, or 0 without values.
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// truncate returns the start of the bucket t falls in.
+func truncate(t time.Time, bucket string) time.Time {
+	y, m, d := t.Date()
+	switch bucket {
+	case models.StatsBucketHour:
+		return t.Truncate(time.Hour)
+	case models.StatsBucketWeek:
+		// Weekday counts from Sunday; weeks start on Monday.
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case models.StatsBucketMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// next returns the start of the bucket after the one starting at start.
+func next(start time.Time, bucket string) (time.Time, error) {
+	switch bucket {
+	case models.StatsBucketHour:
+		return start.Add(time.Hour), nil
+	case models.StatsBucketDay:
+		return start.AddDate(0, 0, 1), nil
+	case models.StatsBucketWeek:
+		return start.AddDate(0, 0, 7), nil
+	case models.StatsBucketMonth:
+		return start.AddDate(0, 1, 0), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidBucket, bucket)
+	}
+}
+
+// WriteCSV writes one row per bucket followed by a total row. Packs get a column per size used
+// anywhere in the range.
+func WriteCSV(w io.Writer, s models.StatsResponse) error {
+	var sizes []int
+	for _, p := range s.Total.Packs {
+		sizes = append(sizes, p.Size)
+	}
+
+	header := []string{"start", "end", "calculations", "quantity_requested", "quantity_shipped", "overage",
+		"overage_percent", "latency_p50_us", "latency_p95_us"}
+	for _, size := range sizes {
+		header = append(header, "packs_"+strconv.Itoa(size))
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	rows := append(slices.Clone(s.Buckets), s.Total)
+	for i, b := range rows {
+		start := b.Start.Format(time.RFC3339)
+		if i == len(rows)-1 {
+			start = "total"
+		}
+		row := []string{start, b.End.Format(time.RFC3339), strconv.Itoa(b.Calculations),
+			strconv.FormatInt(b.Requested, 10), strconv.FormatInt(b.Shipped, 10), strconv.FormatInt(b.Overage, 10),
+			strconv.FormatFloat(b.OveragePercent, 'f', 2, 64),
+			strconv.FormatInt(b.LatencyP50Micros, 10), strconv.FormatInt(b.LatencyP95Micros, 10)}
+		counts := make(map[int]int64, len(b.Packs))
+		for _, p := range b.Packs {
+			counts[p.Size] = p.Count
+		}
+		for _, size := range sizes {
+			row = append(row, strconv.FormatInt(counts[size], 10))
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("write csv row: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("flush csv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/models"
+)
+
+func TestAggregator(t *testing.T) {
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	agg, err := New(day.Add(6*time.Hour), day.AddDate(0, 0, 2), models.StatsBucketDay)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	calc := func(at time.Time, quantity int, micros int64, packs ...models.PackAllocation) models.Calculation {
+		shipped := 0
+		for _, p := range packs {
+			shipped += p.Size * p.Count
+		}
+		return models.Calculation{CreatedAt: at, Quantity: quantity, Packs: packs, Shipped: shipped,
+			Overage: shipped - quantity, DurationMicros: micros}
+	}
+	agg.Add(calc(day.Add(time.Hour), 1, 1, models.PackAllocation{Size: 250, Count: 1}))
+	agg.Add(calc(day.Add(7*time.Hour), 251, 10, models.PackAllocation{Size: 500, Count: 1}))
+	agg.Add(calc(day.Add(8*time.Hour), 750, 30, models.PackAllocation{Size: 500, Count: 1}, models.PackAllocation{Size: 250, Count: 1}))
+	agg.Add(calc(day.Add(9*time.Hour), 1000, 20, models.PackAllocation{Size: 1000, Count: 1}))
+	agg.Add(calc(day.AddDate(0, 0, 2), 1, 1, models.PackAllocation{Size: 250, Count: 1}))
+
+	got := agg.Result()
+	if len(got.Buckets) != 2 || !got.Buckets[0].Start.Equal(day) || !got.Buckets[1].End.Equal(day.AddDate(0, 0, 2)) {
+		t.Fatalf("buckets = %+v", got.Buckets)
+	}
+	first := got.Buckets[0]
+	want := models.StatsBucket{
+		Start: day, End: day.AddDate(0, 0, 1), Calculations: 3, Requested: 2001, Shipped: 2250, Overage: 249,
+		OveragePercent:   12.44,
+		Packs:            []models.PackUsage{{Size: 250, Count: 1}, {Size: 500, Count: 2}, {Size: 1000, Count: 1}},
+		LatencyP50Micros: 20, LatencyP95Micros: 30,
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Fatalf("first bucket = %+v, want %+v", first, want)
+	}
+	if second := got.Buckets[1]; second.Calculations != 0 || len(second.Packs) != 0 || second.LatencyP95Micros != 0 {
+		t.Fatalf("empty bucket = %+v", second)
+	}
+	if got.Total.Calculations != 3 || !got.Total.Start.Equal(day.Add(6*time.Hour)) {
+		t.Fatalf("total = %+v", got.Total)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, got); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	wantLines := []string{
+		"start,end,calculations,quantity_requested,quantity_shipped,overage,overage_percent,latency_p50_us,latency_p95_us,packs_250,packs_500,packs_1000",
+		"2026-10-14T00:00:00Z,2026-10-15T00:00:00Z,3,2001,2250,249,12.44,20,30,1,2,1",
+		"2026-10-15T00:00:00Z,2026-10-16T00:00:00Z,0,0,0,0,0.00,0,0,0,0,0",
+		"total,2026-10-16T00:00:00Z,3,2001,2250,249,12.44,20,30,1,2,1",
+	}
+	if !reflect.DeepEqual(lines, wantLines) {
+		t.Fatalf("csv =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(wantLines, "\n"))
+	}
+}
+
+func TestBucketBoundaries(t *testing.T) {
+	// 2026-10-14 is a Wednesday.
+	at := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		bucket     string
+		start, end time.Time
+	}{
+		{models.StatsBucketHour, time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC), time.Date(2026, 10, 14, 16, 0, 0, 0, time.UTC)},
+		{models.StatsBucketDay, time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{models.StatsBucketWeek, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{models.StatsBucketMonth, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.bucket, func(t *testing.T) {
+			agg, err := New(at, at.Add(time.Minute), tc.bucket)
+			if err != nil {
+				t.Fatalf("new: %v", err)
+			}
+			b := agg.Result().Buckets
+			if len(b) != 1 || !b[0].Start.Equal(tc.start) || !b[0].End.Equal(tc.end) {
+				t.Fatalf("buckets = %+v", b)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	at := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		from, to time.Time
+		bucket   string
+		want     error
+	}{
+		{"unknown bucket", at, at.Add(time.Hour), "year", ErrInvalidBucket},
+		{"empty range", at, at, models.StatsBucketDay, ErrInvalidRange},
+		{"too many buckets", at, at.AddDate(1, 0, 0), models.StatsBucketHour, ErrTooManyBuckets},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(tc.from, tc.to, tc.bucket); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package tenant loads the tenants file, which lists the tenants a deployment serves together
+// with their API keys and default pack sizes, and resolves the tenant of a request.
+//
+// Without a tenants file every request may name any tenant in the X-Tenant header, which suits a
+// deployment behind a proxy that sets it. With one, only the listed tenants exist, and as soon as
+// any tenant has an API key every request must send one; the key then decides the tenant.
+package tenant
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/NikolaNedicVCS/re-order-packs-calculator/internal/configfile"
+)
+
+// MinAPIKeyLen is the minimum length of an API key.
+const MinAPIKeyLen = 16
+
+var (
+	ErrInvalidTenant     = errors.New("invalid tenant")
+	ErrUnknownTenant     = errors.New("unknown tenant")
+	ErrAPIKeyRequired    = errors.New("API key required")
+	ErrInvalidAPIKey     = errors.New("invalid API key")
+	ErrTenantKeyMismatch = errors.New("API key does not belong to the requested tenant")
+)
+
+var idRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// ValidID reports whether id can be used as a tenant ID. Tenant IDs follow the pack set name rules.
+func ValidID(id string) bool {
+	return idRe.MatchString(id)
+}
+
+// File is the content of a tenants file.
+type File struct {
+	Tenants []Tenant `json:"tenants"`
+}
+
+type Tenant struct {
+	ID      string   `json:"id"`
+	APIKeys []string `json:"api_keys"`
+	// DefaultPackSizes replace the deployment's default pack sizes for this tenant.
+	DefaultPackSizes []int `json:"default_pack_sizes"`
+}
+
+// Load reads and validates the tenants file at path, a .json or .yaml file.
+func Load(path string) (*File, error) {
+	format := configfile.FormatFromPath(path)
+	if format == "" {
+		return nil, fmt.Errorf("tenants file must be a .json or .yaml file, provided: %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tenants file: %w", err)
+	}
+	return Parse(data, format)
+}
+
+// Parse decodes data in the given format (see configfile.Decode) and validates the tenants in it.
+func Parse(data []byte, format string) (*File, error) {
+	var f File
+	if err := configfile.Decode(data, format, &f); err != nil {
+		return nil, fmt.Errorf("invalid tenants file: %w", err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("invalid tenants file: %w", err)
+	}
+	return &f, nil
+}
+
+func (f *File) validate() error {
+	var errs []error
+	if len(f.Tenants) == 0 {
+		errs = append(errs, errors.New("at least one tenant is required"))
+	}
+	seenIDs := make(map[string]bool, len(f.Tenants))
+	seenKeys := make(map[string]bool)
+	for i, t := range f.Tenants {
+		if !ValidID(t.ID) {
+			errs = append(errs, fmt.Errorf("tenants[%d]: invalid tenant id %q", i, t.ID))
+			continue
+		}
+		if seenIDs[t.ID] {
+			errs = append(errs, fmt.Errorf("tenants[%d]: duplicate tenant %q", i, t.ID))
+			continue
+		}
+		seenIDs[t.ID] = true
+
+		for j, key := range t.APIKeys {
+			switch {
+			case len(key) < MinAPIKeyLen || strings.TrimSpace(key) != key:
+				errs = append(errs, fmt.Errorf("%s api_keys[%d]: must be at least %d characters without surrounding spaces", t.ID, j, MinAPIKeyLen))
+			case seenKeys[key]:
+				errs = append(errs, fmt.Errorf("%s api_keys[%d]: key is already used", t.ID, j))
+			default:
+				seenKeys[key] = true
+			}
+		}
+
+		seenSizes := make(map[int]bool, len(t.DefaultPackSizes))
+		for j, s := range t.DefaultPackSizes {
+			switch {
+			case s <= 0:
+				errs = append(errs, fmt.Errorf("%s default_pack_sizes[%d]: size must be > 0", t.ID, j))
+			case seenSizes[s]:
+				errs = append(errs, fmt.Errorf("%s default_pack_sizes[%d]: duplicate size %d", t.ID, j, s))
+			default:
+				seenSizes[s] = true
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DefaultPackSizes returns the default pack sizes of the tenants that configure them.
+func (f *File) DefaultPackSizes() map[string][]int {
+	out := make(map[string][]int)
+	for _, t := range f.Tenants {
+		if len(t.DefaultPackSizes) > 0 {
+			out[t.ID] = t.DefaultPackSizes
+		}
+	}
+	return out
+}
+
+// Resolver decides which tenant a request acts for.
+type Resolver struct {
+	// known is nil when any valid tenant ID is accepted.
+	known map[string]bool
+	// keys maps the SHA-256 of every API key to its tenant, so that a lookup does not compare
+	// secrets byte by byte.
+	keys map[[sha256.Size]byte]string
+}
+
+// NewResolver returns a resolver for the tenants of f; a nil f accepts any valid tenant ID.
+func NewResolver(f *File) *Resolver {
+	r := &Resolver{}
+	if f == nil {
+		return r
+	}
+	r.known = make(map[string]bool, len(f.Tenants))
+	r.keys = make(map[[sha256.Size]byte]string)
+	for _, t := range f.Tenants {
+		r.known[t.ID] = true
+		for _, key := range t.APIKeys {
+			r.keys[sha256.Sum256([]byte(key))] = t.ID
+		}
+	}
+	return r
+}
+
+// RequiresAPIKey reports whether every request must authenticate with an API key.
+func (r *Resolver) RequiresAPIKey() bool {
+	return len(r.keys) > 0
+}
+
+// Resolve returns the tenant of a request that sent apiKey and asked for the tenant requested;
+// both may be empty. An API key always decides the tenant; requesting another one is an error.
+// Without API keys the requested tenant is used, or defaultTenant when none is requested.
+func (r *Resolver) Resolve(apiKey, requested, defaultTenant string) (string, error) {
+	if requested != "" && !ValidID(requested) {
+		return "", fmt.Errorf("%w %q", ErrInvalidTenant, requested)
+	}
+
+	if apiKey != "" && len(r.keys) > 0 {
+		id, ok := r.keys[sha256.Sum256([]byte(apiKey))]
+		if !ok {
+			return "", ErrInvalidAPIKey
+		}
+		if requested != "" && requested != id {
+			return "", ErrTenantKeyMismatch
+		}
+		return id, nil
+	}
+	if r.RequiresAPIKey() {
+		return "", ErrAPIKeyRequired
+	}
+
+	id := requested
+	if id == "" {
+		id = defaultTenant
+	}
+	if r.known != nil && !r.known[id] {
+		return "", fmt.Errorf("%w %q", ErrUnknownTenant, id)
+	}
+	return id, nil
+}
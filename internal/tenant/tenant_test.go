@@ -0,0 +1,93 @@
+package tenant
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const yamlTenants = `
+tenants:
+  - id: default
+    api_keys: [admin-key-0123456789]
+  - id: acme
+    api_keys: [acme-key-0123456789, acme-key-rotated-01]
+    default_pack_sizes: [12, 6, 24]
+  - id: globex
+    api_keys: [globex-key-01234567]
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(yamlTenants), "yaml")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(f.Tenants) != 3 || f.Tenants[1].ID != "acme" || len(f.Tenants[1].APIKeys) != 2 {
+		t.Fatalf("tenants = %+v", f.Tenants)
+	}
+	if got := f.DefaultPackSizes(); !reflect.DeepEqual(got, map[string][]int{"acme": {12, 6, 24}}) {
+		t.Fatalf("default pack sizes = %v", got)
+	}
+}
+
+func TestParseRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name, data, wantErr string
+	}{
+		{"no tenants", `{"tenants":[]}`, "at least one tenant is required"},
+		{"unknown field", `{"tenants":[{"id":"a","apikeys":[]}]}`, "unknown field"},
+		{"invalid id", `{"tenants":[{"id":"no spaces"}]}`, `invalid tenant id "no spaces"`},
+		{"duplicate id", `{"tenants":[{"id":"a"},{"id":"a"}]}`, `duplicate tenant "a"`},
+		{"short key", `{"tenants":[{"id":"a","api_keys":["short"]}]}`, "a api_keys[0]: must be at least 16 characters"},
+		{"shared key", `{"tenants":[{"id":"a","api_keys":["0123456789abcdef"]},{"id":"b","api_keys":["0123456789abcdef"]}]}`, "b api_keys[0]: key is already used"},
+		{"bad size", `{"tenants":[{"id":"a","default_pack_sizes":[0]}]}`, "a default_pack_sizes[0]: size must be > 0"},
+		{"duplicate size", `{"tenants":[{"id":"a","default_pack_sizes":[5,5]}]}`, "a default_pack_sizes[1]: duplicate size 5"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data), "json")
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	f, err := Parse([]byte(yamlTenants), "yaml")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	withKeys := NewResolver(f)
+	withoutKeys := NewResolver(&File{Tenants: []Tenant{{ID: "default"}, {ID: "acme"}}})
+	open := NewResolver(nil)
+
+	tests := []struct {
+		name              string
+		r                 *Resolver
+		apiKey, requested string
+		want              string
+		wantErr           error
+	}{
+		{"key decides", withKeys, "acme-key-rotated-01", "", "acme", nil},
+		{"key and matching tenant", withKeys, "acme-key-0123456789", "acme", "acme", nil},
+		{"key of another tenant", withKeys, "globex-key-01234567", "acme", "", ErrTenantKeyMismatch},
+		{"unknown key", withKeys, "not-a-key-000000000", "", "", ErrInvalidAPIKey},
+		{"key required", withKeys, "", "acme", "", ErrAPIKeyRequired},
+		{"known tenant", withoutKeys, "", "acme", "acme", nil},
+		{"default tenant", withoutKeys, "", "", "default", nil},
+		{"unknown tenant", withoutKeys, "", "globex", "", ErrUnknownTenant},
+		{"any tenant", open, "", "globex", "globex", nil},
+		{"keys are ignored without keys", open, "whatever", "", "default", nil},
+		{"invalid tenant", open, "", "../acme", "", ErrInvalidTenant},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.r.Resolve(tc.apiKey, tc.requested, "default")
+			if !errors.Is(err, tc.wantErr) || got != tc.want {
+				t.Fatalf("Resolve = %q, %v; want %q, %v", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}